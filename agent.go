@@ -0,0 +1,557 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"bufio"
+	"code.google.com/p/tcgl/applog"
+	"github.com/petar/GoLLRB/llrb"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the largest response the Agent will send, the maximum UDP payload over IPv4
+const agentMaxMessage = 65507
+
+// the Agent's default snmpEngineID: no enterprise, and text (RFC 3411 5)
+const agentEngineID = "\x80\x00\x00\x00\x04gsnmpgo"
+
+// Agent is a minimal SNMP v1/v2c/v3 agent that answers requests from a tree
+// of QueryResult, eg one loaded by ReadVeraxResults. It is intended for
+// testing, in place of a real device or the Verax simulator.
+//
+// GET, GETNEXT and GETBULK are answered as in RFC 3416: v2c and v3 requests
+// get noSuchObject, noSuchInstance or endOfMibView exceptions, and v1
+// requests get a noSuchName error. SETs change the value of existing oids if
+// Writable is true; the type of the new value must match the old one.
+//
+// v3 requests are checked by the USM as in RFC 3414, and failures get a
+// Report. The context is ignored: every context has the same results.
+type Agent struct {
+	Community string // v1 and v2c requests with any other community are ignored
+	Writable  bool   // if false, SETs fail with notWritable (noSuchName for v1)
+	// MaxMessage is the largest response sent; larger ones get a tooBig
+	// error. 0 means the maximum UDP payload.
+	MaxMessage int
+	// EngineID is the Agent's snmpEngineID, and Users are the v3 users it
+	// accepts. A request must use its user's SecLevel; the users'
+	// ContextName and ContextEngineID aren't used.
+	EngineID string
+	Users    []UsmParams
+
+	mu      sync.Mutex
+	tree    *llrb.Tree
+	entries []QueryResult // the results in tree, sorted by oid
+
+	keys     map[string]*usmKeys // the keys of Users, by UserName
+	started  time.Time           // for snmpEngineTime
+	usmStats [usmStatsDecryptionErrors + 1]uint32
+
+	conn      *net.UDPConn
+	listener  net.Listener          // for ListenStream
+	streams   map[net.Conn]struct{} // open stream connections, closed by Close
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewAgent returns an Agent that serves results, with the community
+// "public" and no v3 users. Set any other fields, then call Listen.
+//
+// SETs modify results, so it shouldn't be used elsewhere until the Agent
+// is closed.
+func NewAgent(results *llrb.Tree) *Agent {
+	return &Agent{Community: "public", EngineID: agentEngineID, tree: results}
+}
+
+// Listen starts answering requests on the UDP address addr, eg
+// "127.0.0.1:0" for any free port (see Addr).
+func (a *Agent) Listen(addr string) error {
+	if err := a.start("Listen"); err != nil {
+		return err
+	}
+	udp_addr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return &SessionError{Msg: "Listen(): invalid address " + addr, Err: err}
+	}
+	conn, err := net.ListenUDP("udp", udp_addr)
+	if err != nil {
+		return &SessionError{Msg: "Listen(): unable to listen on " + addr, Err: err}
+	}
+	a.conn = conn
+	a.done = make(chan struct{})
+	a.wg.Add(1)
+	go a.serve()
+	return nil
+}
+
+// ListenStream starts answering requests over a stream transport (RFC
+// 3430): network is "tcp" and addr eg "127.0.0.1:0", or network is "unix"
+// and addr the path of the socket.
+func (a *Agent) ListenStream(network, addr string) error {
+	if err := a.start("ListenStream"); err != nil {
+		return err
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return &SessionError{Msg: "ListenStream(): unable to listen on " + addr, Err: err}
+	}
+	a.listener = listener
+	a.streams = make(map[net.Conn]struct{})
+	a.done = make(chan struct{})
+	a.wg.Add(1)
+	go a.accept()
+	return nil
+}
+
+// Addr returns the address the Agent is listening on.
+func (a *Agent) Addr() net.Addr {
+	if a.listener != nil {
+		return a.listener.Addr()
+	}
+	return a.conn.LocalAddr()
+}
+
+// Close stops the Agent, closing any stream connections. It is safe to
+// call Close more than once.
+func (a *Agent) Close() (err error) {
+	if a.conn == nil && a.listener == nil {
+		return nil
+	}
+	a.closeOnce.Do(func() {
+		close(a.done)
+		if a.listener != nil {
+			err = a.listener.Close()
+			a.mu.Lock()
+			for conn := range a.streams {
+				conn.Close()
+			}
+			a.mu.Unlock()
+		} else {
+			err = a.conn.Close()
+		}
+		a.wg.Wait()
+	})
+	return err
+}
+
+// accept accepts stream connections until the Agent is closed.
+func (a *Agent) accept() {
+	defer a.wg.Done()
+
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			select {
+			case <-a.done:
+				return
+			default:
+			}
+			if Debug {
+				applog.Warningf("Agent: accept error: %s", err)
+			}
+			continue
+		}
+		a.mu.Lock()
+		select {
+		case <-a.done: // Close has already closed the other streams
+			a.mu.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		a.streams[conn] = struct{}{}
+		a.mu.Unlock()
+		a.wg.Add(1)
+		go a.serveStream(conn)
+	}
+}
+
+// serve answers requests until the Agent is closed.
+func (a *Agent) serve() {
+	defer a.wg.Done()
+
+	packet := make([]byte, 65535)
+	for {
+		n, source, err := a.conn.ReadFromUDP(packet)
+		if err != nil {
+			select {
+			case <-a.done:
+				return
+			default:
+			}
+			if Debug {
+				applog.Warningf("Agent: read error: %s", err)
+			}
+			continue
+		}
+		msg, err := decodeMessage(packet[:n])
+		if err != nil {
+			if Debug {
+				applog.Warningf("Agent: dropping message from %s: %s", source, err)
+			}
+			continue
+		}
+		response := a.respond(msg)
+		if response == nil {
+			continue
+		}
+		reply, err := encodeMessage(response)
+		if err != nil {
+			if Debug {
+				applog.Warningf("Agent: unable to encode response to %s: %s", source, err)
+			}
+			continue
+		}
+		a.conn.WriteToUDP(reply, source)
+	}
+}
+
+// serveStream answers the requests on a stream connection until it or the
+// Agent is closed.
+func (a *Agent) serveStream(conn net.Conn) {
+	defer a.wg.Done()
+	defer func() {
+		a.mu.Lock()
+		delete(a.streams, conn)
+		a.mu.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		packet, err := readMessage(reader)
+		if err != nil {
+			if Debug && err != io.EOF {
+				applog.Warningf("Agent: read error from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		msg, err := decodeMessage(packet)
+		if err != nil {
+			if Debug {
+				applog.Warningf("Agent: dropping message from %s: %s", conn.RemoteAddr(), err)
+			}
+			continue
+		}
+		response := a.respond(msg)
+		if response == nil {
+			continue
+		}
+		reply, err := encodeMessage(response)
+		if err != nil {
+			if Debug {
+				applog.Warningf("Agent: unable to encode response to %s: %s", conn.RemoteAddr(), err)
+			}
+			continue
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// respond returns the response to msg, or nil if msg should be ignored.
+func (a *Agent) respond(msg *snmpMessage) *snmpMessage {
+	if msg.version != GNET_SNMP_V3 && msg.community != a.Community {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	response := &snmpMessage{
+		version:   msg.version,
+		community: msg.community,
+		pduType:   pduResponse,
+		requestID: msg.requestID,
+	}
+	if msg.version == GNET_SNMP_V3 && !a.usm(msg, response) {
+		if msg.flags&v3Reportable == 0 {
+			return nil
+		}
+		return response
+	}
+	var status PduError
+	var index int
+	switch msg.pduType {
+	case pduGet:
+		response.varbinds, status, index = a.get(msg.version, msg.varbinds)
+	case pduGetNext:
+		response.varbinds, status, index = a.getNext(msg.version, msg.varbinds)
+	case pduGetBulk:
+		if msg.version == GNET_SNMP_V1 {
+			return nil // v1 doesn't have GETBULK
+		}
+		response.varbinds = a.getBulk(response, msg.errorStatus, msg.errorIndex, msg.varbinds)
+	case pduSet:
+		status, index = a.set(msg.version, msg.varbinds)
+	default:
+		return nil
+	}
+	if status != GNET_SNMP_PDU_ERR_NOERROR || msg.pduType == pduSet {
+		// error responses, and SET responses, echo the request
+		response.varbinds = msg.varbinds
+	}
+	response.errorStatus, response.errorIndex = int(status), index
+
+	if packet, err := encodeMessage(response); err != nil || len(packet) > a.maxMessage() {
+		response.varbinds = msg.varbinds
+		response.errorStatus, response.errorIndex = int(GNET_SNMP_PDU_ERR_TOOBIG), 0
+	}
+	return response
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// agentEntries sorts entries by oid.
+type agentEntries []QueryResult
+
+func (e agentEntries) Len() int           { return len(e) }
+func (e agentEntries) Less(i, j int) bool { return e[i].Oid.Compare(e[j].Oid) < 0 }
+func (e agentEntries) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// agentStatus returns v1_status for SNMP v1 requests, and status otherwise
+// (v1 has fewer error-status values, RFC 3584 4.4).
+func agentStatus(version SnmpVersion, status, v1_status PduError) PduError {
+	if version == GNET_SNMP_V1 {
+		return v1_status
+	}
+	return status
+}
+
+// find returns the index of the first entry with an oid greater than or
+// equal to oid.
+func (a *Agent) find(oid OID) int {
+	return sort.Search(len(a.entries), func(i int) bool {
+		return a.entries[i].Oid.Compare(oid) >= 0
+	})
+}
+
+// get answers a GET.
+func (a *Agent) get(version SnmpVersion, varbinds []QueryResult) ([]QueryResult, PduError, int) {
+	var out []QueryResult
+	for i, varbind := range varbinds {
+		oid := varbind.Oid
+		if position := a.lookup(oid); position >= 0 {
+			out = append(out, a.entries[position])
+			continue
+		}
+		if version == GNET_SNMP_V1 {
+			return nil, GNET_SNMP_PDU_ERR_NOSUCHNAME, i + 1
+		}
+		// noSuchInstance if the object exists (eg a scalar's .0 or a
+		// table column), noSuchObject otherwise
+		var value Varbinder = new(VBT_NoSuchObject)
+		if parent := oid.Parent(); len(parent) > 0 {
+			if position := a.find(parent); position < len(a.entries) &&
+				a.entries[position].Oid.IsChildOf(parent) {
+				value = new(VBT_NoSuchInstance)
+			}
+		}
+		out = append(out, QueryResult{Oid: oid, Value: value})
+	}
+	return out, GNET_SNMP_PDU_ERR_NOERROR, 0
+}
+
+// getBulk answers a GETBULK (RFC 3416 4.2.3). Repetitions are left out if
+// they would make response too large.
+func (a *Agent) getBulk(response *snmpMessage, nonrep, maxrep int, varbinds []QueryResult) []QueryResult {
+	if nonrep < 0 {
+		nonrep = 0
+	} else if nonrep > len(varbinds) {
+		nonrep = len(varbinds)
+	}
+	var out []QueryResult
+	var cursors []OID
+	for i, varbind := range varbinds {
+		if i < nonrep {
+			out = append(out, a.next(varbind.Oid))
+		} else {
+			cursors = append(cursors, varbind.Oid)
+		}
+	}
+
+	for row := 0; row < maxrep && len(cursors) > 0; row++ {
+		var repetition []QueryResult
+		var more bool
+		for i, oid := range cursors {
+			next := a.next(oid)
+			repetition = append(repetition, next)
+			if _, ok := next.Value.(*VBT_EndOfMibView); !ok {
+				more = true
+			}
+			cursors[i] = next.Oid
+		}
+		response.varbinds = append(out, repetition...)
+		if packet, err := encodeMessage(response); err != nil || len(packet) > a.maxMessage() {
+			if row == 0 {
+				return response.varbinds // respond() returns tooBig
+			}
+			break
+		}
+		out = response.varbinds
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// getNext answers a GETNEXT.
+func (a *Agent) getNext(version SnmpVersion, varbinds []QueryResult) ([]QueryResult, PduError, int) {
+	var out []QueryResult
+	for i, varbind := range varbinds {
+		next := a.next(varbind.Oid)
+		if _, ok := next.Value.(*VBT_EndOfMibView); ok && version == GNET_SNMP_V1 {
+			return nil, GNET_SNMP_PDU_ERR_NOSUCHNAME, i + 1
+		}
+		out = append(out, next)
+	}
+	return out, GNET_SNMP_PDU_ERR_NOERROR, 0
+}
+
+// lookup returns the index of the entry for oid, or -1.
+func (a *Agent) lookup(oid OID) int {
+	position := a.find(oid)
+	if position < len(a.entries) && a.entries[position].Oid.Compare(oid) == 0 {
+		return position
+	}
+	return -1
+}
+
+// maxMessage returns the largest response the Agent will send.
+func (a *Agent) maxMessage() int {
+	if a.MaxMessage > 0 && a.MaxMessage < agentMaxMessage {
+		return a.MaxMessage
+	}
+	return agentMaxMessage
+}
+
+// next returns the result following oid, or endOfMibView.
+func (a *Agent) next(oid OID) QueryResult {
+	position := a.find(oid)
+	if position < len(a.entries) && a.entries[position].Oid.Compare(oid) == 0 {
+		position++
+	}
+	if position < len(a.entries) {
+		return a.entries[position]
+	}
+	return QueryResult{Oid: oid, Value: new(VBT_EndOfMibView)}
+}
+
+// set answers a SET. Either all of varbinds are set, or none are.
+func (a *Agent) set(version SnmpVersion, varbinds []QueryResult) (PduError, int) {
+	if !a.Writable {
+		return agentStatus(version, GNET_SNMP_PDU_ERR_NOTWRITABLE, GNET_SNMP_PDU_ERR_NOSUCHNAME), 1
+	}
+	positions := make([]int, len(varbinds))
+	for i, varbind := range varbinds {
+		positions[i] = a.lookup(varbind.Oid)
+		if positions[i] < 0 {
+			return agentStatus(version, GNET_SNMP_PDU_ERR_NOCREATION, GNET_SNMP_PDU_ERR_NOSUCHNAME), i + 1
+		}
+		if reflect.TypeOf(varbind.Value) != reflect.TypeOf(a.entries[positions[i]].Value) {
+			return agentStatus(version, GNET_SNMP_PDU_ERR_WRONGTYPE, GNET_SNMP_PDU_ERR_BADVALUE), i + 1
+		}
+	}
+	for i, position := range positions {
+		a.entries[position].Value = varbinds[i].Value
+		a.tree.ReplaceOrInsert(a.entries[position])
+	}
+	return GNET_SNMP_PDU_ERR_NOERROR, 0
+}
+
+// start checks the Agent can listen, and sorts its results for answering
+// requests. method is the caller, for errors.
+func (a *Agent) start(method string) error {
+	if a.conn != nil || a.listener != nil {
+		return &SessionError{Msg: method + "(): agent is already listening"}
+	}
+	if a.tree == nil {
+		return &SessionError{Msg: method + "(): agent has no results"}
+	}
+	keys := make(map[string]*usmKeys)
+	for i := range a.Users {
+		if err := a.Users[i].validate(); err != nil {
+			return &SessionError{Msg: method + "(): invalid v3 user", Err: err}
+		}
+		keys[a.Users[i].UserName] = newUsmKeys(&a.Users[i], a.EngineID)
+	}
+	a.keys = keys
+	a.started = time.Now()
+
+	// built afresh each time, as an earlier Listen may have failed
+	entries := make([]QueryResult, 0, a.tree.Len())
+	ch := a.tree.IterAscend()
+	for {
+		r := <-ch
+		if r == nil {
+			break
+		}
+		entries = append(entries, r.(QueryResult))
+	}
+	sort.Sort(agentEntries(entries))
+	a.entries = entries
+	return nil
+}
+
+// usm checks the v3 request msg as in RFC 3414 3.2, decrypting its
+// scopedPDU if need be, and sets the v3 fields of response. If the check
+// fails, response is made a Report of the usmStats counter of the failure
+// and false is returned.
+func (a *Agent) usm(msg, response *snmpMessage) bool {
+	response.msgID = msg.msgID
+	response.engineID, response.engineBoots = a.EngineID, 1
+	response.engineTime = int(time.Since(a.started) / time.Second)
+	response.userName = msg.userName
+	response.contextEngineID, response.contextName = a.EngineID, msg.contextName
+
+	keys := a.keys[msg.userName]
+	drift := msg.engineTime - response.engineTime
+	var counter uint32
+	switch {
+	case msg.engineID != a.EngineID:
+		counter = usmStatsUnknownEngineIDs
+	case keys == nil:
+		counter = usmStatsUnknownUserNames
+	case msg.flags&(v3Auth|v3Priv) != keys.flags():
+		counter = usmStatsUnsupportedSecLevels
+	case msg.flags&v3Auth != 0 && !keys.verify(msg.packet, msg.digestAt):
+		counter = usmStatsWrongDigests
+	case msg.flags&v3Auth != 0 && (msg.engineBoots != response.engineBoots ||
+		drift < -usmTimeWindow || drift > usmTimeWindow):
+		counter = usmStatsNotInTimeWindows
+		// authenticated, so that the sender can believe the engine time
+		response.flags, response.keys = v3Auth, keys
+	case openMessage(msg, keys) != nil:
+		counter = usmStatsDecryptionErrors
+	default:
+		response.requestID = msg.requestID // only known once decrypted
+		response.flags, response.keys = keys.flags(), keys
+		return true
+	}
+
+	a.usmStats[counter]++
+	response.pduType = pduReport
+	response.varbinds = []QueryResult{{Oid: usmStatsOid.Append(counter, 0), Value: VBT_Counter32(a.usmStats[counter])}}
+	return false
+}
@@ -0,0 +1,228 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var agentResults = []QueryResult{
+	{Oid: MustParseOID("1.3.6.1.2.1.1.1.0"), Value: VBT_OctetString("Linux")},
+	{Oid: MustParseOID("1.3.6.1.2.1.1.5.0"), Value: VBT_OctetString("nms")},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.2.1"), Value: VBT_OctetString("lo")},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.2.2"), Value: VBT_OctetString("eth0")},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.1"), Value: VBT_Counter32(100)},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Value: VBT_Counter32(200)},
+}
+
+// agentRequest is a request to an Agent, and the expected response.
+type agentRequest struct {
+	version  SnmpVersion
+	pduType  byte
+	nonrep   int // or error-status of the response
+	maxrep   int // or error-index of the response
+	oids     []string
+	expected []QueryResult // nil if there should be no response
+	status   PduError
+	index    int
+}
+
+var agentTests = []agentRequest{
+	// GET, with v2c exceptions
+	{GNET_SNMP_V2C, pduGet, 0, 0,
+		[]string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.1.1", "1.3.6.1.2.1.1.99.0"},
+		[]QueryResult{agentResults[0],
+			{Oid: MustParseOID("1.3.6.1.2.1.1.1.1"), Value: new(VBT_NoSuchInstance)},
+			{Oid: MustParseOID("1.3.6.1.2.1.1.99.0"), Value: new(VBT_NoSuchObject)}}, 0, 0},
+	// v1 GET of a missing oid
+	{GNET_SNMP_V1, pduGet, 0, 0,
+		[]string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.99.0"},
+		[]QueryResult{
+			{Oid: MustParseOID("1.3.6.1.2.1.1.1.0"), Value: new(VBT_Null)},
+			{Oid: MustParseOID("1.3.6.1.2.1.1.99.0"), Value: new(VBT_Null)}},
+		GNET_SNMP_PDU_ERR_NOSUCHNAME, 2},
+	// GETNEXT, to endOfMibView
+	{GNET_SNMP_V2C, pduGetNext, 0, 0,
+		[]string{"1.3.6.1.2.1.1", "1.3.6.1.2.1.2.2.1.10.2"},
+		[]QueryResult{agentResults[0],
+			{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Value: new(VBT_EndOfMibView)}}, 0, 0},
+	// v1 GETNEXT past the end
+	{GNET_SNMP_V1, pduGetNext, 0, 0,
+		[]string{"1.3.6.1.2.1.2.2.1.10.2"},
+		[]QueryResult{{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Value: new(VBT_Null)}},
+		GNET_SNMP_PDU_ERR_NOSUCHNAME, 1},
+	// GETBULK with a non-repeater and two columns; stops after the row
+	// where both columns reach endOfMibView
+	{GNET_SNMP_V2C, pduGetBulk, 1, 10,
+		[]string{"1.3.6.1.2.1.1.1", "1.3.6.1.2.1.2.2.1.2", "1.3.6.1.2.1.2.2.1.10"},
+		[]QueryResult{agentResults[0],
+			agentResults[2], agentResults[4],
+			agentResults[3], agentResults[5],
+			agentResults[4], {Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Value: new(VBT_EndOfMibView)},
+			agentResults[5], {Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Value: new(VBT_EndOfMibView)},
+			{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Value: new(VBT_EndOfMibView)},
+			{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Value: new(VBT_EndOfMibView)}}, 0, 0},
+	// v1 doesn't have GETBULK
+	{GNET_SNMP_V1, pduGetBulk, 0, 5, []string{"1.3.6.1.2.1.1"}, nil, 0, 0},
+	// SETs fail unless the agent is Writable
+	{GNET_SNMP_V2C, pduSet, 0, 0, []string{"1.3.6.1.2.1.1.5.0"},
+		[]QueryResult{{Oid: MustParseOID("1.3.6.1.2.1.1.5.0"), Value: new(VBT_Null)}},
+		GNET_SNMP_PDU_ERR_NOTWRITABLE, 1},
+}
+
+// sendAgentRequest sends request to the agent, and returns the response or
+// nil if there isn't one.
+func sendAgentRequest(t *testing.T, conn *net.UDPConn, community string, request agentRequest,
+	values []Varbinder) *snmpMessage {
+	msg := &snmpMessage{version: request.version, community: community, pduType: request.pduType,
+		requestID: 1234, errorStatus: request.nonrep, errorIndex: request.maxrep}
+	for i, oid := range request.oids {
+		var value Varbinder = new(VBT_Null)
+		if values != nil {
+			value = values[i]
+		}
+		msg.varbinds = append(msg.varbinds, QueryResult{Oid: MustParseOID(oid), Value: value})
+	}
+	packet, err := encodeMessage(msg)
+	if err != nil {
+		t.Fatalf("encodeMessage error: %s", err)
+	}
+	if _, err = conn.Write(packet); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil
+	}
+	response, err := decodeMessage(buf[:n])
+	if err != nil {
+		t.Fatalf("decodeMessage error: %s", err)
+	}
+	if response.pduType != pduResponse || response.requestID != msg.requestID ||
+		response.version != msg.version || response.community != community {
+		t.Errorf("bad response header %+v", response)
+	}
+	return response
+}
+
+func TestAgent(t *testing.T) {
+	results := llrb.New(LessOID)
+	for _, result := range agentResults {
+		results.ReplaceOrInsert(result)
+	}
+	agent := NewAgent(results)
+	if err := agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer agent.Close()
+	conn, err := net.DialUDP("udp", nil, agent.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP error: %s", err)
+	}
+	defer conn.Close()
+
+	for i, test := range agentTests {
+		response := sendAgentRequest(t, conn, "public", test, nil)
+		if test.expected == nil {
+			if response != nil {
+				t.Errorf("#%d: expected no response, got %+v", i, response)
+			}
+			continue
+		}
+		if response == nil {
+			t.Errorf("#%d: no response", i)
+			continue
+		}
+		if PduError(response.errorStatus) != test.status || response.errorIndex != test.index {
+			t.Errorf("#%d: expected status %s/%d got %s/%d", i, test.status, test.index,
+				PduError(response.errorStatus), response.errorIndex)
+		}
+		if !reflect.DeepEqual(response.varbinds, test.expected) {
+			t.Errorf("#%d: expected %v got %v", i, test.expected, response.varbinds)
+		}
+	}
+
+	// other communities are ignored
+	if response := sendAgentRequest(t, conn, "private", agentTests[0], nil); response != nil {
+		t.Errorf("expected no response to community private, got %+v", response)
+	}
+
+	// SETs, to a Writable agent serving the same results
+	agent.Close()
+	writable := NewAgent(results)
+	writable.Writable = true
+	if err := writable.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer writable.Close()
+	conn, err = net.DialUDP("udp", nil, writable.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP error: %s", err)
+	}
+	defer conn.Close()
+	set := agentRequest{version: GNET_SNMP_V2C, pduType: pduSet,
+		oids: []string{"1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.2.2.1.10.1"}}
+	setTests := []struct {
+		values []Varbinder
+		status PduError
+		index  int
+	}{
+		{[]Varbinder{VBT_OctetString("gw"), VBT_Integer32(1)}, GNET_SNMP_PDU_ERR_WRONGTYPE, 2},
+		{[]Varbinder{VBT_OctetString("gw"), VBT_Counter32(1)}, GNET_SNMP_PDU_ERR_NOERROR, 0},
+	}
+	for i, test := range setTests {
+		response := sendAgentRequest(t, conn, "public", set, test.values)
+		if response == nil || PduError(response.errorStatus) != test.status || response.errorIndex != test.index {
+			t.Errorf("#%d: SET expected status %s/%d got %+v", i, test.status, test.index, response)
+		}
+	}
+	writable.Close()
+	r := results.Get(QueryResult{Oid: MustParseOID("1.3.6.1.2.1.1.5.0")})
+	if r == nil || r.(QueryResult).Value != VBT_OctetString("gw") {
+		t.Errorf("expected SET to change sysName, got %v", r)
+	}
+
+	missing := []QueryResult{{Oid: MustParseOID("1.3.6.1.2.1.1.99.0"), Value: VBT_Integer32(1)}}
+	if status, index := agent.set(GNET_SNMP_V1, missing); status != GNET_SNMP_PDU_ERR_NOSUCHNAME || index != 1 {
+		t.Errorf("expected v1 SET of a missing oid to fail with noSuchName, got %s/%d", status, index)
+	}
+}
+
+func TestAgentListenRetry(t *testing.T) {
+	results := llrb.New(LessOID)
+	for _, result := range agentResults {
+		results.ReplaceOrInsert(result)
+	}
+	agent := NewAgent(results)
+	if err := agent.Listen("127.0.0.1:bogus"); err == nil {
+		t.Fatalf("expected Listen on an invalid address to fail")
+	}
+	if err := agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer agent.Close()
+	if len(agent.entries) != len(agentResults) {
+		t.Errorf("expected %d entries after a failed Listen, got %d", len(agentResults), len(agent.entries))
+	}
+}
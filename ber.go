@@ -0,0 +1,798 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// ber.go encodes and decodes SNMP v1, v2c and v3 messages (RFC 1157, RFC
+// 3416, RFC 3412) using the Basic Encoding Rules. gsnmp only sends requests
+// and receives their responses, so messages gsnmp can't handle (eg
+// notifications) are done here in Go.
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// BER tags used by SNMP, RFC 2578 and RFC 3416.
+const (
+	berInteger        byte = 0x02
+	berOctetString    byte = 0x04
+	berNull           byte = 0x05
+	berObjectID       byte = 0x06
+	berSequence       byte = 0x30
+	berIPAddress      byte = 0x40
+	berCounter32      byte = 0x41
+	berUnsigned32     byte = 0x42 // aka Gauge32
+	berTimeticks      byte = 0x43
+	berOpaque         byte = 0x44
+	berCounter64      byte = 0x46
+	berNoSuchObject   byte = 0x80
+	berNoSuchInstance byte = 0x81
+	berEndOfMibView   byte = 0x82
+)
+
+// PDU tags, RFC 1157 and RFC 3416.
+const (
+	pduGet      byte = 0xa0
+	pduGetNext  byte = 0xa1
+	pduResponse byte = 0xa2
+	pduSet      byte = 0xa3
+	pduTrapV1   byte = 0xa4
+	pduGetBulk  byte = 0xa5
+	pduInform   byte = 0xa6
+	pduTrapV2   byte = 0xa7
+	pduReport   byte = 0xa8
+)
+
+// v3 msgFlags, RFC 3412 6.4.
+const (
+	v3Auth       byte = 0x01
+	v3Priv       byte = 0x02
+	v3Reportable byte = 0x04
+)
+
+// v3MaxSize is the msgMaxSize of v3 messages, the maximum UDP payload over
+// IPv4.
+const v3MaxSize = 65507
+
+// snmpMessage is a v1, v2c or v3 message.
+type snmpMessage struct {
+	version   SnmpVersion
+	community string // v1 and v2c only
+	pduType   byte
+	requestID int32
+
+	// error-status and error-index; for GETBULK these are non-repeaters
+	// and max-repetitions
+	errorStatus int
+	errorIndex  int
+
+	// v1 Trap-PDU fields
+	enterprise   OID
+	agentAddress string
+	genericTrap  int
+	specificTrap int
+	timestamp    uint32
+
+	varbinds []QueryResult
+
+	// v3 header, USM security parameters (RFC 3414 2.4) and scopedPDU
+	msgID           int32
+	flags           byte
+	engineID        string // the authoritative engine's snmpEngineID
+	engineBoots     int
+	engineTime      int
+	userName        string
+	salt            []byte // msgPrivacyParameters
+	contextEngineID string
+	contextName     string
+
+	// keys authenticate and encrypt a v3 message as it is encoded, as
+	// flags ask
+	keys *usmKeys
+
+	// set by decodeMessage for openMessage: the packet and the offset of
+	// the msgAuthenticationParameters in it, and the scopedPDU if it is
+	// encrypted
+	packet    []byte
+	digestAt  int
+	encrypted []byte
+}
+
+// decodeMessage decodes a BER encoded v1, v2c or v3 message. The scopedPDU
+// of an encrypted v3 message is left for openMessage to decrypt.
+func decodeMessage(packet []byte) (msg *snmpMessage, err error) {
+	body, _, err := berExpect(packet, berSequence, "message")
+	if err != nil {
+		return nil, err
+	}
+
+	msg = new(snmpMessage)
+	var value []byte
+	if value, body, err = berExpect(body, berInteger, "version"); err != nil {
+		return nil, err
+	}
+	version, err := berInt(value)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case int64(GNET_SNMP_V1), int64(GNET_SNMP_V2C):
+	case int64(GNET_SNMP_V3):
+		msg.version = GNET_SNMP_V3
+		if err = msg.decodeV3(packet, body); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	default:
+		return nil, fmt.Errorf("%s: decodeMessage(): unsupported snmp version %d", libname(), version)
+	}
+	msg.version = SnmpVersion(version)
+
+	if value, body, err = berExpect(body, berOctetString, "community"); err != nil {
+		return nil, err
+	}
+	msg.community = string(value)
+	if err = msg.decodeAnyPdu(body); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// decodeAnyPdu decodes a PDU of any type.
+func (msg *snmpMessage) decodeAnyPdu(b []byte) (err error) {
+	tag, body, _, err := berTLV(b)
+	if err != nil {
+		return err
+	}
+	msg.pduType = tag
+	switch tag {
+	case pduTrapV1:
+		return msg.decodeTrapV1(body)
+	case pduGet, pduGetNext, pduResponse, pduSet, pduGetBulk, pduInform, pduTrapV2, pduReport:
+		return msg.decodePdu(body)
+	}
+	return fmt.Errorf("%s: decodeMessage(): unknown pdu type 0x%02x", libname(), tag)
+}
+
+// decodePdu decodes the fields of all PDUs except the v1 Trap-PDU.
+func (msg *snmpMessage) decodePdu(body []byte) (err error) {
+	var value []byte
+	var n int64
+	for i, field := range []string{"request-id", "error-status", "error-index"} {
+		if value, body, err = berExpect(body, berInteger, field); err != nil {
+			return err
+		}
+		if n, err = berInt(value); err != nil {
+			return err
+		}
+		switch i {
+		case 0:
+			msg.requestID = int32(n)
+		case 1:
+			msg.errorStatus = int(n)
+		case 2:
+			msg.errorIndex = int(n)
+		}
+	}
+	msg.varbinds, err = berVarbinds(body)
+	return err
+}
+
+// decodeScopedPdu decodes a v3 scopedPDU, once it has been decrypted.
+func (msg *snmpMessage) decodeScopedPdu(b []byte) (err error) {
+	body, _, err := berExpect(b, berSequence, "scopedPDU") // ignoring any padding
+	if err != nil {
+		return err
+	}
+	var value []byte
+	if value, body, err = berExpect(body, berOctetString, "contextEngineID"); err != nil {
+		return err
+	}
+	msg.contextEngineID = string(value)
+	if value, body, err = berExpect(body, berOctetString, "contextName"); err != nil {
+		return err
+	}
+	msg.contextName = string(value)
+	return msg.decodeAnyPdu(body)
+}
+
+// decodeTrapV1 decodes the fields of a v1 Trap-PDU.
+func (msg *snmpMessage) decodeTrapV1(body []byte) (err error) {
+	var value []byte
+	if value, body, err = berExpect(body, berObjectID, "enterprise"); err != nil {
+		return err
+	}
+	enterprise, err := berOid(value)
+	if err != nil {
+		return err
+	}
+	msg.enterprise = OID(enterprise)
+
+	if value, body, err = berExpect(body, berIPAddress, "agent-addr"); err != nil {
+		return err
+	}
+	if len(value) != 4 {
+		return fmt.Errorf("%s: decodeTrapV1(): agent-addr has %d bytes", libname(), len(value))
+	}
+	msg.agentAddress = net.IP(value).String()
+
+	var n int64
+	if value, body, err = berExpect(body, berInteger, "generic-trap"); err != nil {
+		return err
+	}
+	if n, err = berInt(value); err != nil {
+		return err
+	}
+	msg.genericTrap = int(n)
+	if value, body, err = berExpect(body, berInteger, "specific-trap"); err != nil {
+		return err
+	}
+	if n, err = berInt(value); err != nil {
+		return err
+	}
+	msg.specificTrap = int(n)
+
+	if value, body, err = berExpect(body, berTimeticks, "time-stamp"); err != nil {
+		return err
+	}
+	var ticks uint64
+	if ticks, err = berUint(value, 32); err != nil {
+		return err
+	}
+	msg.timestamp = uint32(ticks)
+
+	msg.varbinds, err = berVarbinds(body)
+	return err
+}
+
+// decodeV3 decodes the rest of the v3 message packet, body: the header, the
+// USM security parameters, and the scopedPDU unless it is encrypted.
+func (msg *snmpMessage) decodeV3(packet, body []byte) (err error) {
+	header, body, err := berExpect(body, berSequence, "msgGlobalData")
+	if err != nil {
+		return err
+	}
+	var value []byte
+	var n int64
+	for _, field := range []string{"msgID", "msgMaxSize", "msgFlags", "msgSecurityModel"} {
+		tag := berInteger
+		if field == "msgFlags" {
+			tag = berOctetString
+		}
+		if value, header, err = berExpect(header, tag, field); err != nil {
+			return err
+		}
+		switch field {
+		case "msgID":
+			n, err = berInt(value)
+			msg.msgID = int32(n)
+		case "msgFlags":
+			if len(value) != 1 || value[0]&(v3Auth|v3Priv) == v3Priv {
+				return fmt.Errorf("%s: decodeMessage(): invalid msgFlags % x", libname(), value)
+			}
+			msg.flags = value[0]
+		case "msgSecurityModel":
+			if n, err = berInt(value); err == nil && n != int64(GNET_SNMP_SECMODEL_SNMPV3) {
+				return fmt.Errorf("%s: decodeMessage(): unsupported security model %d", libname(), n)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var params []byte
+	if value, body, err = berExpect(body, berOctetString, "msgSecurityParameters"); err != nil {
+		return err
+	}
+	if params, _, err = berExpect(value, berSequence, "UsmSecurityParameters"); err != nil {
+		return err
+	}
+	for _, field := range []string{"msgAuthoritativeEngineID", "msgAuthoritativeEngineBoots",
+		"msgAuthoritativeEngineTime", "msgUserName", "msgAuthenticationParameters", "msgPrivacyParameters"} {
+		tag := berOctetString
+		if field == "msgAuthoritativeEngineBoots" || field == "msgAuthoritativeEngineTime" {
+			tag = berInteger
+		}
+		if value, params, err = berExpect(params, tag, field); err != nil {
+			return err
+		}
+		switch field {
+		case "msgAuthoritativeEngineID":
+			msg.engineID = string(value)
+		case "msgAuthoritativeEngineBoots":
+			n, err = berInt(value)
+			msg.engineBoots = int(n)
+		case "msgAuthoritativeEngineTime":
+			n, err = berInt(value)
+			msg.engineTime = int(n)
+		case "msgUserName":
+			msg.userName = string(value)
+		case "msgAuthenticationParameters":
+			// value shares packet's backing array, so the difference in
+			// capacity is its offset
+			msg.packet, msg.digestAt = packet, cap(packet)-cap(value)
+		case "msgPrivacyParameters":
+			msg.salt = value
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if msg.flags&v3Priv != 0 {
+		msg.encrypted, _, err = berExpect(body, berOctetString, "encryptedPDU")
+		return err
+	}
+	return msg.decodeScopedPdu(body)
+}
+
+// encodeMessage BER encodes msg.
+func encodeMessage(msg *snmpMessage) ([]byte, error) {
+	pdu, err := encodePdu(msg)
+	if err != nil {
+		return nil, err
+	}
+	if msg.version == GNET_SNMP_V3 {
+		return encodeV3(msg, pdu)
+	}
+
+	var body []byte
+	body = berAppendTLV(body, berInteger, berIntBytes(int64(msg.version)))
+	body = berAppendTLV(body, berOctetString, []byte(msg.community))
+	body = append(body, pdu...)
+	return berAppendTLV(nil, berSequence, body), nil
+}
+
+// encodePdu BER encodes the PDU of msg.
+func encodePdu(msg *snmpMessage) ([]byte, error) {
+	varbinds, err := berEncodeVarbinds(msg.varbinds)
+	if err != nil {
+		return nil, err
+	}
+
+	var pdu []byte
+	if msg.pduType == pduTrapV1 {
+		oid, err := berOidBytes(msg.enterprise)
+		if err != nil {
+			return nil, err
+		}
+		agent := net.ParseIP(msg.agentAddress).To4()
+		if agent == nil {
+			return nil, fmt.Errorf("%s: encodeMessage(): invalid agent address %q", libname(), msg.agentAddress)
+		}
+		pdu = berAppendTLV(pdu, berObjectID, oid)
+		pdu = berAppendTLV(pdu, berIPAddress, agent)
+		pdu = berAppendTLV(pdu, berInteger, berIntBytes(int64(msg.genericTrap)))
+		pdu = berAppendTLV(pdu, berInteger, berIntBytes(int64(msg.specificTrap)))
+		pdu = berAppendTLV(pdu, berTimeticks, berUintBytes(uint64(msg.timestamp)))
+	} else {
+		pdu = berAppendTLV(pdu, berInteger, berIntBytes(int64(msg.requestID)))
+		pdu = berAppendTLV(pdu, berInteger, berIntBytes(int64(msg.errorStatus)))
+		pdu = berAppendTLV(pdu, berInteger, berIntBytes(int64(msg.errorIndex)))
+	}
+	pdu = berAppendTLV(pdu, berSequence, varbinds)
+	return berAppendTLV(nil, msg.pduType, pdu), nil
+}
+
+// encodeV3 BER encodes the v3 message msg, whose PDU is pdu. The scopedPDU
+// is encrypted and the message authenticated with msg.keys, as msg.flags
+// ask.
+func encodeV3(msg *snmpMessage, pdu []byte) (packet []byte, err error) {
+	var scoped []byte
+	scoped = berAppendTLV(scoped, berOctetString, []byte(msg.contextEngineID))
+	scoped = berAppendTLV(scoped, berOctetString, []byte(msg.contextName))
+	scoped = berAppendTLV(nil, berSequence, append(scoped, pdu...))
+
+	data := scoped
+	if msg.flags&v3Priv != 0 {
+		var encrypted []byte
+		if encrypted, msg.salt, err = msg.keys.encrypt(scoped, msg.engineBoots, msg.engineTime); err != nil {
+			return nil, err
+		}
+		data = berAppendTLV(nil, berOctetString, encrypted)
+	}
+
+	var header []byte
+	header = berAppendTLV(header, berInteger, berIntBytes(int64(msg.msgID)))
+	header = berAppendTLV(header, berInteger, berIntBytes(v3MaxSize))
+	header = berAppendTLV(header, berOctetString, []byte{msg.flags})
+	header = berAppendTLV(header, berInteger, berIntBytes(int64(GNET_SNMP_SECMODEL_SNMPV3)))
+
+	var digest []byte
+	if msg.flags&v3Auth != 0 {
+		digest = make([]byte, usmDigestLength) // filled in below
+	}
+	salt := berAppendTLV(nil, berOctetString, msg.salt)
+	var params []byte
+	params = berAppendTLV(params, berOctetString, []byte(msg.engineID))
+	params = berAppendTLV(params, berInteger, berIntBytes(int64(msg.engineBoots)))
+	params = berAppendTLV(params, berInteger, berIntBytes(int64(msg.engineTime)))
+	params = berAppendTLV(params, berOctetString, []byte(msg.userName))
+	params = berAppendTLV(params, berOctetString, digest)
+	params = append(params, salt...)
+
+	var body []byte
+	body = berAppendTLV(body, berInteger, berIntBytes(int64(GNET_SNMP_V3)))
+	body = berAppendTLV(body, berSequence, header)
+	body = berAppendTLV(body, berOctetString, berAppendTLV(nil, berSequence, params))
+	body = append(body, data...)
+	packet = berAppendTLV(nil, berSequence, body)
+
+	if msg.flags&v3Auth != 0 {
+		// the digest is followed by the privacy parameters and the data
+		msg.keys.sign(packet, len(packet)-len(data)-len(salt)-usmDigestLength)
+	}
+	return packet, nil
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// berAppendTLV appends tag, the BER length of value, and value to b.
+func berAppendTLV(b []byte, tag byte, value []byte) []byte {
+	b = append(b, tag)
+	length := len(value)
+	switch {
+	case length < 0x80:
+		b = append(b, byte(length))
+	case length <= 0xff:
+		b = append(b, 0x81, byte(length))
+	case length <= 0xffff:
+		b = append(b, 0x82, byte(length>>8), byte(length))
+	default:
+		b = append(b, 0x84, byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+	}
+	return append(b, value...)
+}
+
+// berEncodeValue returns the BER tag and contents for value. Both the value
+// types and the pointer types that convertResults() returns for exceptions
+// (eg *VBT_NoSuchObject) are accepted.
+func berEncodeValue(value Varbinder) (tag byte, contents []byte, err error) {
+	switch v := value.(type) {
+	case VBT_Null, *VBT_Null, nil:
+		return berNull, nil, nil
+	case VBT_NoSuchObject, *VBT_NoSuchObject:
+		return berNoSuchObject, nil, nil
+	case VBT_NoSuchInstance, *VBT_NoSuchInstance:
+		return berNoSuchInstance, nil, nil
+	case VBT_EndOfMibView, *VBT_EndOfMibView:
+		return berEndOfMibView, nil, nil
+	case VBT_Integer32:
+		return berInteger, berIntBytes(int64(v)), nil
+	case VBT_Unsigned32:
+		return berUnsigned32, berUintBytes(uint64(v)), nil
+	case VBT_Counter32:
+		return berCounter32, berUintBytes(uint64(v)), nil
+	case VBT_Timeticks:
+		return berTimeticks, berUintBytes(uint64(v)), nil
+	case VBT_Counter64:
+		return berCounter64, berUintBytes(uint64(v)), nil
+	case VBT_OctetString:
+		return berOctetString, []byte(v), nil
+	case VBT_Opaque:
+		return berOpaque, []byte(v), nil
+	case VBT_IPAddress:
+		ip := net.ParseIP(string(v)).To4()
+		if ip == nil {
+			return 0, nil, fmt.Errorf("%s: berEncodeValue(): invalid ip address %s", libname(), v)
+		}
+		return berIPAddress, ip, nil
+	case VBT_ObjectID:
+		oid, err := parseOid(string(v))
+		if err != nil {
+			return 0, nil, err
+		}
+		contents, err = berOidBytes(oid)
+		return berObjectID, contents, err
+	}
+	return 0, nil, fmt.Errorf("%s: berEncodeValue(): unsupported type %T", libname(), value)
+}
+
+// berEncodeVarbinds returns the contents of a VarBindList.
+func berEncodeVarbinds(varbinds []QueryResult) (result []byte, err error) {
+	for _, varbind := range varbinds {
+		name, err := berOidBytes(varbind.Oid)
+		if err != nil {
+			return nil, err
+		}
+		tag, contents, err := berEncodeValue(varbind.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s (oid %s)", err, varbind.Oid)
+		}
+		var vb []byte
+		vb = berAppendTLV(vb, berObjectID, name)
+		vb = berAppendTLV(vb, tag, contents)
+		result = berAppendTLV(result, berSequence, vb)
+	}
+	return result, nil
+}
+
+// berExpect splits the first tag-length-value off b, and checks the tag is
+// want. what names the field for the error message.
+func berExpect(b []byte, want byte, what string) (value, rest []byte, err error) {
+	tag, value, rest, err := berTLV(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag != want {
+		return nil, nil, fmt.Errorf("%s: berExpect(): %s has tag 0x%02x, expected 0x%02x",
+			libname(), what, tag, want)
+	}
+	return value, rest, nil
+}
+
+// berInt decodes a two's complement INTEGER of up to 64 bits.
+func berInt(value []byte) (result int64, err error) {
+	if len(value) == 0 || len(value) > 8 {
+		return 0, fmt.Errorf("%s: berInt(): invalid integer length %d", libname(), len(value))
+	}
+	result = int64(int8(value[0])) // sign extend
+	for _, b := range value[1:] {
+		result = result<<8 | int64(b)
+	}
+	return result, nil
+}
+
+// berIntBytes returns the shortest two's complement encoding of n.
+func berIntBytes(n int64) []byte {
+	result := []byte{byte(n)}
+	for (n > 0x7f || n < -0x80) && len(result) < 8 {
+		n >>= 8
+		result = append([]byte{byte(n)}, result...)
+	}
+	return result
+}
+
+// berOid decodes the contents of an OBJECT IDENTIFIER.
+func berOid(value []byte) (result []uint32, err error) {
+	if len(value) == 0 {
+		return nil, fmt.Errorf("%s: berOid(): empty oid", libname())
+	}
+	var sub uint64
+	for i, b := range value {
+		sub = sub<<7 | uint64(b&0x7f)
+		if sub > 0xffffffff {
+			return nil, fmt.Errorf("%s: berOid(): sub-identifier overflow", libname())
+		}
+		if b&0x80 != 0 {
+			if i == len(value)-1 {
+				return nil, fmt.Errorf("%s: berOid(): truncated oid", libname())
+			}
+			continue
+		}
+		if result == nil {
+			// the first sub-identifier holds the first two arcs
+			switch {
+			case sub < 40:
+				result = append(result, 0, uint32(sub))
+			case sub < 80:
+				result = append(result, 1, uint32(sub-40))
+			default:
+				result = append(result, 2, uint32(sub-80))
+			}
+		} else {
+			result = append(result, uint32(sub))
+		}
+		sub = 0
+	}
+	return result, nil
+}
+
+// berOidBytes returns the contents of an OBJECT IDENTIFIER for oid.
+func berOidBytes(oid []uint32) (result []byte, err error) {
+	if len(oid) < 2 || oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("%s: berOidBytes(): invalid oid %s", libname(), oidString(oid))
+	}
+	subs := make([]uint64, 0, len(oid)-1)
+	subs = append(subs, uint64(oid[0])*40+uint64(oid[1]))
+	for _, sub := range oid[2:] {
+		subs = append(subs, uint64(sub))
+	}
+	for _, sub := range subs {
+		encoded := []byte{byte(sub & 0x7f)}
+		for sub >>= 7; sub > 0; sub >>= 7 {
+			encoded = append([]byte{byte(sub&0x7f) | 0x80}, encoded...)
+		}
+		result = append(result, encoded...)
+	}
+	return result, nil
+}
+
+// berTLV splits the first tag-length-value off b.
+func berTLV(b []byte) (tag byte, value, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, fmt.Errorf("%s: berTLV(): truncated message", libname())
+	}
+	tag = b[0]
+	length := int(b[1])
+	b = b[2:]
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(b) < n {
+			return 0, nil, nil, fmt.Errorf("%s: berTLV(): invalid length", libname())
+		}
+		length = 0
+		for _, c := range b[:n] {
+			length = length<<8 | int(c)
+		}
+		b = b[n:]
+	}
+	if length < 0 || length > len(b) {
+		return 0, nil, nil, fmt.Errorf("%s: berTLV(): truncated message", libname())
+	}
+	return tag, b[:length], b[length:], nil
+}
+
+// berUint decodes an unsigned INTEGER (eg Counter32) of up to bits bits.
+func berUint(value []byte, bits uint) (result uint64, err error) {
+	if len(value) == 0 || len(value) > 9 || (len(value) == 9 && value[0] != 0) {
+		return 0, fmt.Errorf("%s: berUint(): invalid integer length %d", libname(), len(value))
+	}
+	for _, b := range value {
+		result = result<<8 | uint64(b)
+	}
+	if bits < 64 && result>>bits != 0 {
+		return 0, fmt.Errorf("%s: berUint(): %d overflows %d bits", libname(), result, bits)
+	}
+	return result, nil
+}
+
+// berUintBytes returns the shortest encoding of n as a non-negative INTEGER.
+func berUintBytes(n uint64) []byte {
+	result := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		result = append([]byte{byte(n)}, result...)
+	}
+	if result[0]&0x80 != 0 {
+		result = append([]byte{0}, result...)
+	}
+	return result
+}
+
+// berValue converts a BER value to the same Varbinder types as
+// convertResults().
+func berValue(tag byte, value []byte) (Varbinder, error) {
+	switch tag {
+	case berNull:
+		return new(VBT_Null), nil
+	case berNoSuchObject:
+		return new(VBT_NoSuchObject), nil
+	case berNoSuchInstance:
+		return new(VBT_NoSuchInstance), nil
+	case berEndOfMibView:
+		return new(VBT_EndOfMibView), nil
+	case berOctetString:
+		return VBT_OctetString(value), nil
+	case berOpaque:
+		return VBT_Opaque(value), nil
+	case berIPAddress:
+		if len(value) != net.IPv4len && len(value) != net.IPv6len { // same as union_ui8v_ipaddress
+			return VBT_IPAddress(""), nil
+		}
+		return VBT_IPAddress(net.IP(value).String()), nil
+	case berObjectID:
+		oid, err := berOid(value)
+		if err != nil {
+			return nil, err
+		}
+		return VBT_ObjectID("." + oidString(oid)), nil
+	case berInteger:
+		n, err := berInt(value)
+		if err != nil {
+			return nil, err
+		}
+		if n < -1<<31 || n > 1<<31-1 {
+			return nil, fmt.Errorf("%s: berValue(): %d overflows Integer32", libname(), n)
+		}
+		return VBT_Integer32(n), nil
+	case berUnsigned32, berCounter32, berTimeticks, berCounter64:
+		bits := uint(32)
+		if tag == berCounter64 {
+			bits = 64
+		}
+		n, err := berUint(value, bits)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case berUnsigned32:
+			return VBT_Unsigned32(n), nil
+		case berCounter32:
+			return VBT_Counter32(n), nil
+		case berTimeticks:
+			return VBT_Timeticks(n), nil
+		}
+		return VBT_Counter64(n), nil
+	}
+	return nil, fmt.Errorf("%s: berValue(): unknown type 0x%02x", libname(), tag)
+}
+
+// berVarbinds decodes the contents of a VarBindList.
+func berVarbinds(b []byte) (results []QueryResult, err error) {
+	list, _, err := berExpect(b, berSequence, "variable-bindings")
+	if err != nil {
+		return nil, err
+	}
+	for len(list) > 0 {
+		var vb, name, value []byte
+		if vb, list, err = berExpect(list, berSequence, "varbind"); err != nil {
+			return nil, err
+		}
+		if name, vb, err = berExpect(vb, berObjectID, "varbind name"); err != nil {
+			return nil, err
+		}
+		oid, err := berOid(name)
+		if err != nil {
+			return nil, err
+		}
+		tag, value, _, err := berTLV(vb)
+		if err != nil {
+			return nil, err
+		}
+		result := QueryResult{Oid: OID(oid)}
+		if result.Value, err = berValue(tag, value); err != nil {
+			return nil, fmt.Errorf("%s (oid %s)", err, result.Oid)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// hexString formats b as a hex string, eg 00 25 89 27 56 1B
+func hexString(b []byte) string {
+	hex := make([]string, len(b))
+	for i, c := range b {
+		hex[i] = fmt.Sprintf("%02X", c)
+	}
+	return strings.Join(hex, " ")
+}
+
+// octetString formats b as text if all bytes are printable, otherwise as a
+// hex string.
+func octetString(b []byte) string {
+	for _, c := range b {
+		if !strconv.IsPrint(rune(c)) {
+			return hexString(b)
+		}
+	}
+	return string(b)
+}
+
+// oidString formats oid in dotted form, without a leading dot.
+func oidString(oid []uint32) string {
+	subs := make([]string, len(oid))
+	for i, sub := range oid {
+		subs[i] = strconv.FormatUint(uint64(sub), 10)
+	}
+	return strings.Join(subs, ".")
+}
+
+// parseHexString parses a hex string made by hexString().
+func parseHexString(s string) (result []byte, err error) {
+	for _, field := range strings.Fields(s) {
+		n, err := strconv.ParseUint(field, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parseHexString(): invalid hex string %q", libname(), s)
+		}
+		result = append(result, byte(n))
+	}
+	return result, nil
+}
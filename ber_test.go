@@ -0,0 +1,190 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"encoding/hex"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+)
+
+var berValueTests = []struct {
+	value Varbinder
+	ber   string // hex of tag, length and contents
+}{
+	{VBT_Integer32(0), "020100"},
+	{VBT_Integer32(127), "02017f"},
+	{VBT_Integer32(128), "02020080"},
+	{VBT_Integer32(-1), "0201ff"},
+	{VBT_Integer32(-129), "0202ff7f"},
+	{VBT_Integer32(-2147483648), "020480000000"},
+	{VBT_Unsigned32(4294967295), "420500ffffffff"},
+	{VBT_Counter32(256), "41020100"},
+	{VBT_Timeticks(4381200), "430342da10"},
+	{VBT_Counter64(18446744073709551615), "460900ffffffffffffffff"},
+	{VBT_OctetString("Linux"), "04054c696e7578"},
+	{VBT_IPAddress("192.168.1.10"), "4004c0a8010a"},
+	{VBT_ObjectID(".1.3.6.1.4.1.2021.250.10"), "060a2b060104018f65817a0a"},
+	{VBT_Opaque("\x9f\x78\x04"), "44039f7804"},
+	{new(VBT_Null), "0500"},
+	{new(VBT_NoSuchObject), "8000"},
+	{new(VBT_NoSuchInstance), "8100"},
+	{new(VBT_EndOfMibView), "8200"},
+}
+
+func TestBerValue(t *testing.T) {
+	for i, test := range berValueTests {
+		tag, contents, err := berEncodeValue(test.value)
+		if err != nil {
+			t.Errorf("#%d: berEncodeValue(%#v) error: %s", i, test.value, err)
+			continue
+		}
+		encoded := hex.EncodeToString(berAppendTLV(nil, tag, contents))
+		if encoded != test.ber {
+			t.Errorf("#%d: berEncodeValue(%#v) expected %s got %s", i, test.value, test.ber, encoded)
+		}
+
+		decoded, err := berValue(tag, contents)
+		if err != nil {
+			t.Errorf("#%d: berValue(%s) error: %s", i, encoded, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, test.value) {
+			t.Errorf("#%d: berValue(%s) expected %#v got %#v", i, encoded, test.value, decoded)
+		}
+	}
+}
+
+var berOidTests = []struct {
+	oid string
+	ber string
+}{
+	{"1.3.6.1.2.1.1.1.0", "2b06010201010100"},
+	{"1.3.6.1.4.1.2021.250.10", "2b060104018f65817a0a"},
+	{"0.0", "00"},
+	{"2.999.3", "883703"},
+	{"1.3.4294967295", "2b8fffffff7f"},
+}
+
+func TestBerOid(t *testing.T) {
+	for i, test := range berOidTests {
+		oid, _ := parseOid(test.oid)
+		encoded, err := berOidBytes(oid)
+		if err != nil {
+			t.Errorf("#%d: berOidBytes(%s) error: %s", i, test.oid, err)
+			continue
+		}
+		if hex.EncodeToString(encoded) != test.ber {
+			t.Errorf("#%d: berOidBytes(%s) expected %s got %x", i, test.oid, test.ber, encoded)
+		}
+		decoded, err := berOid(encoded)
+		if err != nil || oidString(decoded) != test.oid {
+			t.Errorf("#%d: berOid(%s) expected %s got %s (%v)", i, test.ber, test.oid, oidString(decoded), err)
+		}
+	}
+}
+
+// a v2c get-response for sysDescr.0 and sysUpTime.0
+var berResponse = "303d02010104067075626c6963a2300202077f0201000201003024301106082b060102010101" +
+	"0004054c696e7578300f06082b06010201010300430342da10"
+
+var berMessageErrors = []string{
+	"",
+	"30",
+	"3003020101",               // truncated
+	"300602010304007000",       // v3 (unsupported)
+	"3082ffff020101",           // length past end
+	"300b020101040170a9020100", // unknown pdu
+}
+
+func TestDecodeMessage(t *testing.T) {
+	msg := &snmpMessage{
+		version:   GNET_SNMP_V2C,
+		community: "public",
+		pduType:   pduResponse,
+		requestID: 1919,
+		varbinds: []QueryResult{
+			{Oid: MustParseOID("1.3.6.1.2.1.1.1.0"), Value: VBT_OctetString("Linux")},
+			{Oid: MustParseOID("1.3.6.1.2.1.1.3.0"), Value: VBT_Timeticks(4381200)},
+		},
+	}
+	packet, err := encodeMessage(msg)
+	if err != nil {
+		t.Fatalf("encodeMessage error: %s", err)
+	}
+	if hex.EncodeToString(packet) != berResponse {
+		t.Errorf("encodeMessage expected %s got %x", berResponse, packet)
+	}
+	decoded, err := decodeMessage(packet)
+	if err != nil {
+		t.Fatalf("decodeMessage error: %s", err)
+	}
+	if !reflect.DeepEqual(decoded, msg) {
+		t.Errorf("decodeMessage expected %#v got %#v", msg, decoded)
+	}
+
+	for i, test := range berMessageErrors {
+		packet, _ := hex.DecodeString(test)
+		if _, err := decodeMessage(packet); err == nil {
+			t.Errorf("#%d: decodeMessage(%s) expected error", i, test)
+		}
+	}
+}
+
+func TestBerOctetString(t *testing.T) {
+	// the octets are kept as they are; only String() shows unprintable
+	// octets as a hex string
+	octets := []byte{0x00, 0x25, 0x89, 0x27, 0x56, 0x1b}
+	value, _ := berValue(berOctetString, octets)
+	if !reflect.DeepEqual(value.Bytes(), octets) {
+		t.Errorf("expected octets % x, got % x", octets, value.Bytes())
+	}
+	if value.String() != "00 25 89 27 56 1B" {
+		t.Errorf("expected hex string, got %q", value.String())
+	}
+
+	value, _ = berValue(berOpaque, []byte{0x9f, 0x78, 0x04})
+	if !reflect.DeepEqual(value.Bytes(), []byte{0x9f, 0x78, 0x04}) || value.String() != "9F 78 04" {
+		t.Errorf("expected opaque 9F 78 04, got %q", value.String())
+	}
+}
+
+func TestBerIPv6Address(t *testing.T) {
+	// an IpAddress is 4 bytes, but some agents send ipv6 addresses in one
+	ip := net.ParseIP("2001:db8::1")
+	value, _ := berValue(berIPAddress, []byte(ip))
+	if value != VBT_IPAddress("2001:db8::1") {
+		t.Errorf("expected 2001:db8::1, got %#v", value)
+	}
+	if !reflect.DeepEqual(value.Bytes(), []byte(ip)) {
+		t.Errorf("expected bytes % x, got % x", []byte(ip), value.Bytes())
+	}
+	if value.Integer().Cmp(new(big.Int).SetBytes(ip)) != 0 {
+		t.Errorf("expected integer %s, got %s", new(big.Int).SetBytes(ip), value.Integer())
+	}
+	if value, _ = berValue(berIPAddress, []byte{1, 2, 3}); value != VBT_IPAddress("") {
+		t.Errorf("expected empty address for 3 bytes, got %#v", value)
+	}
+
+	// but only ipv4 addresses can be sent
+	if _, _, err := berEncodeValue(VBT_IPAddress("2001:db8::1")); err == nil {
+		t.Errorf("berEncodeValue() of an ipv6 address expected error")
+	}
+}
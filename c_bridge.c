@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 #include "c_bridge.h"
 
 #include <gsnmp/ber.h>
@@ -10,6 +13,7 @@
 #include <gsnmp/utils.h>
 #include <gsnmp/gsnmp.h>
 #include <stdlib.h>
+#include <string.h>
 
 // get_err_label is a wrapper for gnet_snmp_enum_get_label()
 // gchar const *
@@ -23,3 +27,91 @@ vbl_delete(GList *list) {
 	g_list_foreach(list, (GFunc) gnet_snmp_varbind_delete, NULL);
 	g_list_free(list);
 }
+
+// vbl_append_oid appends a NULL varbind for oid to a var bind list, for
+// building GETNEXT and GETBULK requests. The oid is copied.
+GList *
+vbl_append_oid(GList *vbl, guint32 *oid, gsize oid_len) {
+	return vbl_append_value(vbl, oid, oid_len, GNET_SNMP_VARBIND_TYPE_NULL, NULL, 0);
+}
+
+// vbl_append_value appends a varbind of type with value to a var bind list,
+// for building SET requests. The oid and value are copied; value points to
+// a gint32, guint32 or guint64 for the scalar types, or to a vector of
+// value_len bytes (value_len guint32's for GNET_SNMP_VARBIND_TYPE_OBJECTID).
+GList *
+vbl_append_value(GList *vbl, guint32 *oid, gsize oid_len,
+		GNetSnmpVarBindType type, gpointer value, gsize value_len) {
+	GNetSnmpVarBind *vb;
+
+	vb = gnet_snmp_varbind_new(oid, oid_len, type, value, value_len);
+	return g_list_append(vbl, vb);
+}
+
+// set_gstring calls one of the gnet_snmp_set_* functions that take a GString
+// (which they copy), eg gnet_snmp_set_sec_name
+static void
+set_gstring(GNetSnmp *s, void (*setter)(GNetSnmp *, GString *), gchar *value, gsize len) {
+	GString *gstring;
+
+	gstring = g_string_new_len(value, len);
+	setter(s, gstring);
+	g_string_free(gstring, TRUE);
+}
+
+// session_new creates a session with community as the security name,
+// without going through a uri. The transport is set separately, with
+// session_set_address() or session_set_path().
+GNetSnmp *
+session_new(gchar *community) {
+	GNetSnmp *s;
+
+	s = gnet_snmp_new();
+	set_gstring(s, gnet_snmp_set_sec_name, community, strlen(community));
+	return s;
+}
+
+// session_set_address sets the transport of a session to ip:port using
+// UDP, or TCP if tcp is TRUE, over ipv6 if ip is an ipv6 address. ip must
+// be an address; host names are resolved in Go. Returns FALSE if ip isn't
+// an address.
+gboolean
+session_set_address(GNetSnmp *s, gchar *ip, gint port, gboolean tcp) {
+	GInetAddr *inetaddr;
+	GNetSnmpTAddress *taddress;
+	GNetSnmpTDomain tdomain;
+
+	inetaddr = gnet_inetaddr_new(ip, port);
+	if (inetaddr == NULL) {
+		return FALSE;
+	}
+	if (gnet_inetaddr_is_ipv6(inetaddr)) {
+		tdomain = tcp ? GNET_SNMP_TDOMAIN_TCP_IPV6 : GNET_SNMP_TDOMAIN_UDP_IPV6;
+	} else {
+		tdomain = tcp ? GNET_SNMP_TDOMAIN_TCP_IPV4 : GNET_SNMP_TDOMAIN_UDP_IPV4;
+	}
+	taddress = gnet_snmp_taddress_new_inet(tdomain, inetaddr);
+	gnet_inetaddr_delete(inetaddr);
+
+	gnet_snmp_set_transport(s, taddress);
+	gnet_snmp_taddress_delete(taddress);
+	return TRUE;
+}
+
+// session_set_path sets the transport of a session to the Unix-domain
+// socket at path. Returns FALSE if path is empty.
+gboolean
+session_set_path(GNetSnmp *s, gchar *path) {
+	GNetSnmpTAddress *taddress;
+
+	if (path == NULL || *path == '\0') {
+		return FALSE;
+	}
+	taddress = gnet_snmp_taddress_new_path(GNET_SNMP_TDOMAIN_LOCAL, path);
+	if (taddress == NULL) {
+		return FALSE;
+	}
+	gnet_snmp_set_transport(s, taddress);
+	gnet_snmp_taddress_delete(taddress);
+	return TRUE;
+}
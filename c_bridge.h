@@ -15,4 +15,20 @@
 void
 vbl_delete(GList *list);
 
+GList *
+vbl_append_oid(GList *vbl, guint32 *oid, gsize oid_len);
+
+GList *
+vbl_append_value(GList *vbl, guint32 *oid, gsize oid_len,
+		GNetSnmpVarBindType type, gpointer value, gsize value_len);
+
+GNetSnmp *
+session_new(gchar *community);
+
+gboolean
+session_set_address(GNetSnmp *s, gchar *ip, gint port, gboolean tcp);
+
+gboolean
+session_set_path(GNetSnmp *s, gchar *path);
+
 #endif //__C_BRIDGE_H__
@@ -0,0 +1,439 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// client.go is the SNMP client written in Go: requests are encoded by ber.go
+// and sent over UDP, TCP or a Unix-domain socket, and SNMP v3 requests are
+// authenticated and encrypted by usm.go. It does all the queries of the pure
+// Go backend, and the v3 queries of the gsnmp backend, as gsnmp 0.3.0 can't
+// authenticate or encrypt.
+
+import (
+	"bufio"
+	"code.google.com/p/tcgl/applog"
+	"context"
+	"fmt"
+	"github.com/petar/GoLLRB/llrb"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// goClient sends requests to a single agent.
+type goClient struct {
+	addr      net.Addr
+	conn      net.Conn      // nil after a stream times out, until it is redialled
+	reader    *bufio.Reader // reads conn, for the stream transports
+	community string
+	params    *QueryParams
+	requestID int32
+
+	// SNMP v3: the user's keys, and the agent's engine as found by
+	// discover; engineTime was its snmpEngineTime at synced
+	keys        *usmKeys
+	engineID    string
+	engineBoots int
+	engineTime  int
+	synced      time.Time
+}
+
+// goQuery does the work of Query() with a goClient.
+func goQuery(ctx context.Context, params *QueryParams) (results *llrb.Tree, status *QueryStatus, err error) {
+	uri, err := validateUri(params.Uri, MAX_URI_COUNT)
+	if err != nil {
+		return nil, nil, err
+	}
+	if Debug {
+		applog.Warningf("number of incoming uris: %d", len(uri.oids))
+	}
+	if len(uri.oids) == 0 {
+		return nil, nil, &UriError{Uri: params.Uri, Pos: -1, Msg: "no oids in uri"}
+	}
+
+	addr, err := queryAddr(ctx, params, uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := dialClient(addr, uri.community, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.close()
+
+	varbinds, status, err := client.query(ctx, uri.oids, uri.uritype, true)
+	if status != nil {
+		status.Addr = addr
+	}
+	if ctx.Err() != nil {
+		// return whatever was collected before the cancel or deadline
+		return resultsTree(params, varbinds), status, ctx.Err()
+	}
+	if err != nil {
+		return nil, status, err
+	}
+	return resultsTree(params, varbinds), status, nil
+}
+
+// goSet does the work of Set() with a goClient.
+func goSet(params *QueryParams, varbinds []QueryResult) (results *llrb.Tree, err error) {
+	uri, err := parseSnmpUri(params.Uri)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := queryAddr(context.Background(), params, uri)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dialClient(addr, uri.community, params)
+	if err != nil {
+		return nil, err
+	}
+	defer client.close()
+	return client.set(varbinds)
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// close closes the client's socket.
+func (c *goClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// connect dials the client's agent. For TCP the connection is made here,
+// waiting as long as a request would for a response.
+func (c *goClient) connect() error {
+	timeout := time.Duration(c.params.Timeout*(c.params.Retries+1)) * time.Millisecond
+	conn, err := net.DialTimeout(c.addr.Network(), c.addr.String(), timeout)
+	if err != nil {
+		return &SessionError{Msg: "unable to reach " + c.addr.String(), Err: err}
+	}
+	c.conn = conn
+	if _, ok := c.addr.(*net.UDPAddr); !ok {
+		c.reader = bufio.NewReader(conn)
+	}
+	return nil
+}
+
+// decode decodes packet, returning an error unless it is the response to
+// msg. For SNMP v3 a Report is also a response, and the packet is
+// authenticated and decrypted with the user's keys.
+func (c *goClient) decode(msg *snmpMessage, packet []byte) (*snmpMessage, error) {
+	response, err := decodeMessage(packet)
+	if err != nil {
+		return nil, err
+	}
+	if msg.version != GNET_SNMP_V3 {
+		if response.version != msg.version || response.pduType != pduResponse || response.requestID != msg.requestID {
+			return nil, fmt.Errorf("%s: decode(): not the response to request %d", libname(), msg.requestID)
+		}
+		return response, nil
+	}
+	if response.version != GNET_SNMP_V3 || response.msgID != msg.msgID {
+		return nil, fmt.Errorf("%s: decode(): not the response to message %d", libname(), msg.msgID)
+	}
+	if err = openMessage(response, c.keys); err != nil {
+		return nil, err
+	}
+	switch {
+	case response.pduType == pduReport:
+	case response.pduType != pduResponse:
+		return nil, fmt.Errorf("%s: decode(): pdu type 0x%02x isn't a response", libname(), response.pduType)
+	case c.keys != nil && response.flags&(v3Auth|v3Priv) != c.keys.flags():
+		return nil, fmt.Errorf("%s: decode(): response isn't at the user's security level", libname())
+	}
+	return response, nil
+}
+
+// dialClient returns a client for the agent at addr, a *net.UDPAddr,
+// *net.TCPAddr or *net.UnixAddr.
+func dialClient(addr net.Addr, community string, params *QueryParams) (*goClient, error) {
+	switch params.Version {
+	case GNET_SNMP_V1, GNET_SNMP_V2C:
+	case GNET_SNMP_V3:
+		if err := params.Usm.validate(); err != nil {
+			return nil, &SessionError{Msg: "invalid v3 credentials", Err: err}
+		}
+	default:
+		return nil, &SessionError{Msg: "unsupported snmp version " + params.Version.String()}
+	}
+	client := &goClient{addr: addr, community: community, params: params, requestID: rand.Int31()}
+	if err := client.connect(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// discover finds the agent's snmpEngineID, boots and time by sending an
+// empty unauthenticated request (RFC 3414 4), and localizes the user's keys
+// to the engine ID.
+func (c *goClient) discover(ctx context.Context) *QueryStatus {
+	response, status := c.exchange(ctx, &snmpMessage{pduType: pduGet})
+	if status != nil {
+		return status
+	}
+	if response.engineID == "" || usmReport(response) != usmStatsUnknownEngineIDs {
+		return &QueryStatus{Message: "snmp v3 engine discovery failed"}
+	}
+	c.engineID = response.engineID
+	c.sync(response)
+	c.keys = newUsmKeys(c.params.Usm, c.engineID)
+	return nil
+}
+
+// exchange sends msg, filling in its version, ids and community or v3
+// fields, and waits for the response. If there is no response, or it can't
+// be sent, the status says why.
+func (c *goClient) exchange(ctx context.Context, msg *snmpMessage) (*snmpMessage, *QueryStatus) {
+	c.requestID++
+	msg.version, msg.requestID = c.params.Version, c.requestID
+	if msg.version == GNET_SNMP_V3 {
+		c.secure(msg)
+	} else {
+		msg.community = c.community
+	}
+	packet, err := encodeMessage(msg)
+	if err != nil {
+		return nil, &QueryStatus{Error: GNET_SNMP_PDU_ERR_INTERNAL, Message: err.Error()}
+	}
+	if c.reader != nil {
+		return c.streamRequest(ctx, msg, packet)
+	}
+
+	buf := make([]byte, 65535)
+	for attempt := 0; attempt <= c.params.Retries && ctx.Err() == nil; attempt++ {
+		if _, err = c.conn.Write(packet); err != nil {
+			return nil, &QueryStatus{Error: GNET_SNMP_PDU_ERR_INTERNAL, Message: err.Error()}
+		}
+		timeout := time.Duration(contextTimeout(ctx, c.params.Timeout)) * time.Millisecond
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+		for {
+			length, err := c.conn.Read(buf)
+			if err != nil {
+				break // timed out, or eg icmp port unreachable; retry
+			}
+			response, err := c.decode(msg, buf[:length])
+			if err != nil {
+				if Debug {
+					applog.Warningf("exchange(): discarding packet from %s: %v", c.conn.RemoteAddr(), err)
+				}
+				continue
+			}
+			return response, nil
+		}
+	}
+	return nil, &QueryStatus{Error: GNET_SNMP_PDU_ERR_NORESPONSE}
+}
+
+// nullVarbinds returns a varbind with a NULL value for each of oids, for
+// building GET, GETNEXT and GETBULK requests.
+func nullVarbinds(oids [][]uint32) []QueryResult {
+	varbinds := make([]QueryResult, len(oids))
+	for i, oid := range oids {
+		varbinds[i] = QueryResult{Oid: OID(oid), Value: new(VBT_Null)}
+	}
+	return varbinds
+}
+
+// query does a GET or GETNEXT of oids, or walks them. Walks use GETBULK
+// requests if bulk is true, except for SNMP v1 which uses GETNEXTs.
+//
+// As with the gsnmp backend, errors are only returned when there are no
+// results at all; status describes why the query stopped.
+func (c *goClient) query(ctx context.Context, oids [][]uint32, uritype UriType,
+	bulk bool) (varbinds []QueryResult, status *QueryStatus, err error) {
+	if Debug {
+		applog.Debugf("Starting a %s", uritype)
+	}
+	switch uritype {
+	case GNET_SNMP_URI_GET:
+		return c.request(ctx, pduGet, nullVarbinds(oids), 0, 0)
+	case GNET_SNMP_URI_NEXT:
+		return c.request(ctx, pduGetNext, nullVarbinds(oids), 0, 0)
+	case GNET_SNMP_URI_WALK:
+		bulk = bulk && c.params.Version != GNET_SNMP_V1
+		fetch := func(request [][]uint32, nonrep, maxrep int) ([]QueryResult, *QueryStatus, error) {
+			if bulk {
+				return c.request(ctx, pduGetBulk, nullVarbinds(request), nonrep, maxrep)
+			}
+			return c.request(ctx, pduGetNext, nullVarbinds(request), 0, 0)
+		}
+		varbinds, status, err = walkOids(ctx, oids, bulk, c.params.Nonrep, c.params.Maxrep, fetch)
+		if len(varbinds) > 0 {
+			err = nil
+		}
+		return varbinds, status, err
+	}
+	return nil, nil, &UriError{Uri: c.params.Uri, Pos: -1, Msg: "query(): unknown uritype"}
+}
+
+// request sends a request of type pdu_type and waits for the response,
+// retrying params.Retries times every params.Timeout milliseconds (reduced
+// to fit ctx's deadline). nonrep and maxrep are only used by GETBULK.
+//
+// An error response from the agent echoes the request; its varbinds are
+// returned along with the error, as gsnmp does. For SNMP v3, the agent's engine is discovered by the
+// first request, and a request outside its time window is resent once with
+// the time it reports.
+func (c *goClient) request(ctx context.Context, pdu_type byte, varbinds []QueryResult,
+	nonrep, maxrep int) ([]QueryResult, *QueryStatus, error) {
+	if c.params.Version == GNET_SNMP_V3 && c.keys == nil {
+		if status := c.discover(ctx); status != nil {
+			return nil, status, status.err(c.params)
+		}
+	}
+	msg := &snmpMessage{
+		pduType:     pdu_type,
+		errorStatus: nonrep,
+		errorIndex:  maxrep,
+		varbinds:    varbinds,
+	}
+	response, status := c.exchange(ctx, msg)
+	if status == nil && usmReport(response) == usmStatsNotInTimeWindows {
+		c.sync(response)
+		response, status = c.exchange(ctx, msg)
+	}
+	if status == nil {
+		status = &QueryStatus{Error: PduError(response.errorStatus), Index: response.errorIndex}
+		if counter := usmReport(response); counter > 0 {
+			status = &QueryStatus{Message: "agent reported " + usmStatsNames[counter]}
+		}
+	}
+	if !status.Ok() {
+		if response != nil && status.Error != GNET_SNMP_PDU_ERR_NOERROR {
+			return response.varbinds, status, status.err(c.params)
+		}
+		return nil, status, status.err(c.params)
+	}
+	return response.varbinds, status, nil
+}
+
+// secure fills in the v3 fields of msg: the user, the agent's engine and
+// its estimated time, the context, and the flags for the user's security
+// level. Before discover, the engine is empty and msg is unauthenticated.
+func (c *goClient) secure(msg *snmpMessage) {
+	msg.msgID = msg.requestID
+	msg.flags = v3Reportable
+	msg.engineID = c.engineID
+	msg.contextEngineID, msg.contextName = c.engineID, ""
+	if c.keys == nil {
+		return
+	}
+	msg.engineBoots = c.engineBoots
+	msg.engineTime = c.engineTime + int(time.Since(c.synced)/time.Second)
+	msg.userName = c.params.Usm.UserName
+	msg.flags |= c.keys.flags()
+	msg.keys = c.keys
+	msg.contextName = c.params.Usm.ContextName
+	if c.params.Usm.ContextEngineID != "" {
+		msg.contextEngineID = c.params.Usm.ContextEngineID
+	}
+}
+
+// set does an SNMP SET of varbinds. If the agent rejects it, the varbinds of
+// its response are returned with the error, as with the gsnmp backend.
+func (c *goClient) set(varbinds []QueryResult) (results *llrb.Tree, err error) {
+	response, _, err := c.request(context.Background(), pduSet, varbinds, 0, 0)
+	if response == nil {
+		return nil, err
+	}
+	return resultsTree(c.params, response), err
+}
+
+// streamExchange writes packet and reads messages until the response to msg
+// arrives, discarding any others.
+func (c *goClient) streamExchange(ctx context.Context, msg *snmpMessage, packet []byte) (*snmpMessage, error) {
+	if _, err := c.conn.Write(packet); err != nil {
+		return nil, err
+	}
+	timeout := contextTimeout(ctx, c.params.Timeout*(c.params.Retries+1))
+	c.conn.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Millisecond))
+	for {
+		packet, err := readMessage(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		response, err := c.decode(msg, packet)
+		if err != nil {
+			if Debug {
+				applog.Warningf("streamExchange(): discarding message from %s: %v", c.addr, err)
+			}
+			continue
+		}
+		return response, nil
+	}
+}
+
+// streamRequest sends packet, the encoding of msg, over a stream transport
+// and waits for the response. Nothing is resent as the transport is
+// reliable; instead the wait is as long as all the retries of a datagram
+// would be (reduced to fit ctx's deadline).
+//
+// The connection is closed if no response arrives, as the rest of a late
+// response would be mistaken for the next; the next request redials. If an
+// open connection turns out to be broken (eg the agent restarted), it is
+// redialled and packet resent once.
+func (c *goClient) streamRequest(ctx context.Context, msg *snmpMessage, packet []byte) (*snmpMessage, *QueryStatus) {
+	for {
+		redialled := c.conn == nil
+		if redialled {
+			if err := c.connect(); err != nil {
+				return nil, &QueryStatus{Error: GNET_SNMP_PDU_ERR_INTERNAL, Message: err.Error()}
+			}
+		}
+		response, err := c.streamExchange(ctx, msg, packet)
+		if err == nil {
+			return response, nil
+		}
+
+		c.conn.Close()
+		c.conn, c.reader = nil, nil
+		if net_err, ok := err.(net.Error); ok && net_err.Timeout() {
+			return nil, &QueryStatus{Error: GNET_SNMP_PDU_ERR_NORESPONSE}
+		} else if !redialled && ctx.Err() == nil {
+			if Debug {
+				applog.Warningf("streamRequest(): redialling %s: %s", c.addr, err)
+			}
+			continue
+		}
+		return nil, &QueryStatus{Error: GNET_SNMP_PDU_ERR_INTERNAL, Message: err.Error()}
+	}
+}
+
+// sync sets the agent's engine boots and time from a v3 message it sent.
+func (c *goClient) sync(msg *snmpMessage) {
+	c.engineBoots, c.engineTime, c.synced = msg.engineBoots, msg.engineTime, time.Now()
+}
+
+// usmReport returns the usmStats counter a v3 Report is of, or 0 if msg
+// isn't a USM Report.
+func usmReport(msg *snmpMessage) uint32 {
+	if msg.pduType != pduReport || len(msg.varbinds) == 0 {
+		return 0
+	}
+	oid := msg.varbinds[0].Oid
+	if len(oid) != len(usmStatsOid)+2 || !oid.HasPrefix(usmStatsOid) {
+		return 0
+	}
+	counter := oid[len(usmStatsOid)]
+	if counter == 0 || int(counter) >= len(usmStatsNames) {
+		return 0
+	}
+	return counter
+}
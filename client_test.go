@@ -0,0 +1,113 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// tests for the Go client, used by both backends for SNMP v3
+
+import (
+	"errors"
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+	"testing"
+)
+
+var v3Tests = []struct {
+	usm    UsmParams
+	report string // the usmStats counter the agent should report, if any
+}{
+	{v3TestUsers[0], ""},
+	{v3TestUsers[1], ""},
+	{v3TestUsers[2], ""},
+	{v3TestUsers[3], ""},
+	{UsmParams{UserName: "nobody", SecLevel: GNET_SNMP_SECLEVEL_NANP}, "usmStatsUnknownUserNames"},
+	{UsmParams{UserName: "sha", SecLevel: GNET_SNMP_SECLEVEL_NANP}, "usmStatsUnsupportedSecLevels"},
+	{UsmParams{UserName: "sha", SecLevel: GNET_SNMP_SECLEVEL_ANP, AuthProtocol: USM_AUTH_SHA,
+		AuthPassword: "wrongpassword"}, "usmStatsWrongDigests"},
+	{UsmParams{UserName: "sha", SecLevel: GNET_SNMP_SECLEVEL_ANP, AuthProtocol: USM_AUTH_MD5,
+		AuthPassword: "maplesyrup"}, "usmStatsWrongDigests"},
+	{UsmParams{UserName: "aes", SecLevel: GNET_SNMP_SECLEVEL_AP, AuthProtocol: USM_AUTH_SHA, AuthPassword: "maplesyrup",
+		PrivProtocol: USM_PRIV_AES, PrivPassword: "wrongpassword"}, "usmStatsDecryptionErrors"},
+}
+
+func TestV3(t *testing.T) {
+	results := llrb.New(LessOID)
+	for _, result := range agentResults {
+		results.ReplaceOrInsert(result)
+	}
+	agent := NewAgent(results)
+	agent.Users = v3TestUsers
+	if err := agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer agent.Close()
+
+	uri := "snmp://" + agent.Addr().String() + "//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.5.0)"
+	for i, test := range v3Tests {
+		params := NewDefaultParams(uri)
+		params.Version = GNET_SNMP_V3
+		params.Retries = 0
+		usm := test.usm
+		params.Usm = &usm
+		results, err := Query(params)
+		if test.report != "" {
+			var session_err *SessionError
+			if !errors.As(err, &session_err) || session_err.Msg != "agent reported "+test.report {
+				t.Errorf("#%d: expected a %s report, got %v", i, test.report, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: %s: Query error: %s", i, test.usm.UserName, err)
+			continue
+		}
+		if results.Len() != 2 {
+			t.Errorf("#%d: %s: expected 2 results, got %d", i, test.usm.UserName, results.Len())
+		}
+		r := results.Get(QueryResult{Oid: MustParseOID("1.3.6.1.2.1.1.1.0")})
+		if r == nil || r.(QueryResult).Value != VBT_OctetString("Linux") {
+			t.Errorf("#%d: %s: expected sysDescr Linux, got %v", i, test.usm.UserName, r)
+		}
+	}
+
+	// a walk, with GETBULKs, over a session whose idea of the agent's time
+	// has drifted out of the time window, so that it has to resync
+	session := NewSession("127.0.0.1", "", GNET_SNMP_V3)
+	session.Port = agent.Addr().(*net.UDPAddr).Port
+	session.Usm = &v3TestUsers[2]
+	if err := session.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer session.Close()
+	if _, err := session.Get([]string{"1.3.6.1.2.1.1.1.0"}); err != nil {
+		t.Fatalf("session Get error: %s", err)
+	}
+	session.conn.client.engineTime -= 2 * usmTimeWindow
+	walked, err := session.BulkWalk([]string{"1.3.6.1.2.1.2.2.1"})
+	if err != nil {
+		t.Fatalf("session BulkWalk error: %s", err)
+	}
+	if walked.Len() != 4 {
+		t.Errorf("expected the walk to return 4 results, got %d", walked.Len())
+	}
+	agent.mu.Lock()
+	reports := agent.usmStats[usmStatsNotInTimeWindows]
+	agent.mu.Unlock()
+	if reports != 1 {
+		t.Errorf("expected 1 usmStatsNotInTimeWindows report, got %d", reports)
+	}
+}
@@ -0,0 +1,33 @@
+// Command gsnmpset does SNMP SETs, like net-snmp's snmpset.
+//
+//    gsnmpset [options] agent oid type value ...
+//
+// Run gsnmpset -h for the options.
+package main
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"os"
+
+	"github.com/soniah/gsnmpgo/cmd/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(cli.SET, os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,573 @@
+// Package cli is the code shared by the gsnmpgo command-line tools in cmd/:
+// parsing net-snmp style options and agents, building a gsnmpgo.Request,
+// and printing the results.
+package cli
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/soniah/gsnmpgo"
+)
+
+// Command is one of the tools.
+type Command int
+
+const (
+	GET      Command = iota // gsnmpget
+	GETNEXT                 // gsnmpgetnext
+	WALK                    // gsnmpwalk, using GETNEXT
+	BULKWALK                // gsnmpbulkwalk, using GETBULK
+	SET                     // gsnmpset
+	TABLE                   // gsnmptable
+)
+
+// String returns the name of the tool, eg "gsnmpget".
+func (c Command) String() string {
+	switch c {
+	case GET:
+		return "gsnmpget"
+	case GETNEXT:
+		return "gsnmpgetnext"
+	case WALK:
+		return "gsnmpwalk"
+	case BULKWALK:
+		return "gsnmpbulkwalk"
+	case SET:
+		return "gsnmpset"
+	case TABLE:
+		return "gsnmptable"
+	}
+	return "UNKNOWN Command"
+}
+
+// the subtree walked when no oid is given, mib-2 (as net-snmp's snmpwalk)
+const defaultWalk = "1.3.6.1.2.1"
+
+// the options that take a value, either joined (-v2c) or separate (-v 2c)
+const valueOptions = "vctrCOmulaAxXnE"
+
+// errUsage is returned by parseArgs for -h.
+var errUsage = errors.New("usage")
+
+// options are the parsed command line.
+type options struct {
+	req    *gsnmpgo.Request
+	usm    gsnmpgo.UsmParams
+	output string   // the -O letters
+	mibs   []string // files or directories to load MIBs from
+	index  bool     // -Ci, show the index of table rows
+	sep    string   // -Cf, the separator of table columns
+	args   []string // the agent, then oids (or oid type value for SET)
+}
+
+// Main runs the tool cmd with args (without the program name), writing
+// results to stdout and errors to stderr. It returns the exit status: 0 on
+// success, 1 if the query failed and 2 for usage errors.
+func Main(cmd Command, args []string, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(cmd, args)
+	if err == errUsage {
+		usage(cmd, stdout)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n(run %s -h for usage)\n", cmd, err, cmd)
+		return 2
+	}
+	if len(opts.mibs) > 0 {
+		mib, err := gsnmpgo.LoadMIBs(opts.mibs...)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", cmd, err)
+			return 1
+		}
+		gsnmpgo.Mib = mib
+	}
+	if err = opts.request(cmd); err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n(run %s -h for usage)\n", cmd, err, cmd)
+		return 2
+	}
+
+	results, err := gsnmpgo.QueryRequest(opts.req)
+	if cmd == SET && err != nil {
+		results = nil // the agent's response to a rejected SET just echoes it
+	}
+	if results != nil {
+		if cmd == TABLE {
+			oid, _ := parseName(opts.req.Oids[0])
+			printTable(stdout, oid, gsnmpgo.NewTable(oid, results), opts)
+		} else {
+			printResults(stdout, results, opts.output)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", cmd, err)
+		return 1
+	}
+	return 0
+}
+
+// parseArgs parses the options at the start of args; the rest are left in
+// the args of the result. Like net-snmp, option values can be joined to
+// the option (-v2c, -Cr50) or follow it (-v 2c, -C r50).
+func parseArgs(cmd Command, args []string) (opts *options, err error) {
+	opts = &options{req: gsnmpgo.NewRequest("", "public", gsnmpgo.GNET_SNMP_V2C, cmd.operation())}
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			args = args[1:]
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		args = args[1:]
+		letter, value := arg[1], arg[2:]
+		if strings.IndexByte(valueOptions, letter) >= 0 && value == "" {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("option -%c needs a value", letter)
+			}
+			value, args = args[0], args[1:]
+		}
+		if err = opts.set(cmd, letter, value); err != nil {
+			return nil, err
+		}
+	}
+	if opts.req.Version == gsnmpgo.GNET_SNMP_V3 {
+		opts.req.Usm = &opts.usm
+	}
+	opts.args = args
+	return opts, nil
+}
+
+// request completes opts.req from the agent and oids in opts.args. It is
+// called after any MIBs are loaded, so that names can be used.
+func (opts *options) request(cmd Command) (err error) {
+	if len(opts.args) == 0 {
+		return errors.New("no agent")
+	}
+	if err = opts.setAgent(opts.args[0]); err != nil {
+		return err
+	}
+	args := opts.args[1:]
+
+	switch cmd {
+	case SET:
+		if len(opts.req.Oids) > 0 {
+			return errors.New("values to set are given as oid type value, not in the uri")
+		}
+		if len(args) == 0 || len(args)%3 != 0 {
+			return errors.New("expected oid type value for each value to set")
+		}
+		for i := 0; i < len(args); i += 3 {
+			varbind, err := parseVarbind(args[i], args[i+1], args[i+2])
+			if err != nil {
+				return err
+			}
+			opts.req.Varbinds = append(opts.req.Varbinds, varbind)
+		}
+		return nil
+	case TABLE:
+		opts.req.Oids = append(opts.req.Oids, args...)
+		if len(opts.req.Oids) != 1 {
+			return errors.New("expected one table oid")
+		}
+		_, err = parseName(opts.req.Oids[0])
+		return err
+	}
+	opts.req.Oids = append(opts.req.Oids, args...)
+	if len(opts.req.Oids) == 0 {
+		if cmd == GET || cmd == GETNEXT {
+			return errors.New("no oids")
+		}
+		opts.req.Oids = []string{defaultWalk}
+	}
+	return nil
+}
+
+// set sets the option letter to value.
+func (opts *options) set(cmd Command, letter byte, value string) (err error) {
+	req := opts.req
+	switch letter {
+	case 'h':
+		return errUsage
+	case 'v':
+		switch value {
+		case "1":
+			req.Version = gsnmpgo.GNET_SNMP_V1
+		case "2c":
+			req.Version = gsnmpgo.GNET_SNMP_V2C
+		case "3":
+			req.Version = gsnmpgo.GNET_SNMP_V3
+		default:
+			return fmt.Errorf("invalid version %q, expected 1, 2c or 3", value)
+		}
+	case 'c':
+		req.Community = value
+	case 't':
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds <= 0 || seconds > math.MaxInt32/1000 {
+			return fmt.Errorf("invalid timeout %q", value)
+		}
+		req.Timeout = int(seconds * 1000)
+	case 'r':
+		if req.Retries, err = strconv.Atoi(value); err != nil || req.Retries < 0 {
+			return fmt.Errorf("invalid retries %q", value)
+		}
+	case 'C':
+		return opts.setCommandOption(cmd, value)
+	case 'O':
+		for _, c := range value {
+			if !strings.ContainsRune("nqvD", c) {
+				return fmt.Errorf("unknown output option -O%c", c)
+			}
+		}
+		opts.output += value
+	case 'm':
+		opts.mibs = append(opts.mibs, filepath.SplitList(value)...)
+	case 'u':
+		opts.usm.UserName = value
+	case 'l':
+		switch strings.ToLower(value) {
+		case "noauthnopriv", "nanp":
+			opts.usm.SecLevel = gsnmpgo.GNET_SNMP_SECLEVEL_NANP
+		case "authnopriv", "anp":
+			opts.usm.SecLevel = gsnmpgo.GNET_SNMP_SECLEVEL_ANP
+		case "authpriv", "ap":
+			opts.usm.SecLevel = gsnmpgo.GNET_SNMP_SECLEVEL_AP
+		default:
+			return fmt.Errorf("invalid security level %q", value)
+		}
+	case 'a':
+		switch strings.ToUpper(value) {
+		case "MD5":
+			opts.usm.AuthProtocol = gsnmpgo.USM_AUTH_MD5
+		case "SHA":
+			opts.usm.AuthProtocol = gsnmpgo.USM_AUTH_SHA
+		default:
+			return fmt.Errorf("invalid authentication protocol %q, expected MD5 or SHA", value)
+		}
+	case 'A':
+		opts.usm.AuthPassword = value
+	case 'x':
+		switch strings.ToUpper(value) {
+		case "DES":
+			opts.usm.PrivProtocol = gsnmpgo.USM_PRIV_DES
+		case "AES":
+			opts.usm.PrivProtocol = gsnmpgo.USM_PRIV_AES
+		default:
+			return fmt.Errorf("invalid privacy protocol %q, expected DES or AES", value)
+		}
+	case 'X':
+		opts.usm.PrivPassword = value
+	case 'n':
+		opts.usm.ContextName = value
+	case 'E':
+		engine_id, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid context engine id %q, expected hex", value)
+		}
+		opts.usm.ContextEngineID = string(engine_id)
+	default:
+		return fmt.Errorf("unknown option -%c", letter)
+	}
+	return nil
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// operation returns the Request operation done by a command.
+func (c Command) operation() gsnmpgo.Operation {
+	switch c {
+	case GETNEXT:
+		return gsnmpgo.OP_GETNEXT
+	case WALK:
+		return gsnmpgo.OP_WALK
+	case BULKWALK, TABLE:
+		return gsnmpgo.OP_BULKWALK
+	case SET:
+		return gsnmpgo.OP_SET
+	}
+	return gsnmpgo.OP_GET
+}
+
+// parseName parses an oid, or a name if MIBs are loaded.
+func parseName(s string) (gsnmpgo.OID, error) {
+	if gsnmpgo.Mib != nil {
+		return gsnmpgo.Mib.ParseName(s)
+	}
+	return gsnmpgo.ParseOID(s)
+}
+
+// parseValue parses a value to set, with a net-snmp type letter:
+//
+//    i INTEGER, u Gauge32 (Unsigned32), c Counter32, C Counter64,
+//    t TimeTicks, a IpAddress, o OBJECT IDENTIFIER, s STRING,
+//    x hex STRING (eg "00 1a 2b" or "001a2b"), d decimal STRING
+//    (eg "10.0.0.1" or "10 0 0 1"), n NULL
+func parseValue(type_letter, value string) (gsnmpgo.Varbinder, error) {
+	invalid := fmt.Errorf("invalid value %q for type %s", value, type_letter)
+	switch type_letter {
+	case "i":
+		n, err := strconv.ParseInt(value, 0, 32)
+		if err != nil {
+			return nil, invalid
+		}
+		return gsnmpgo.VBT_Integer32(n), nil
+	case "u", "c", "t":
+		n, err := strconv.ParseUint(value, 0, 32)
+		if err != nil {
+			return nil, invalid
+		}
+		switch type_letter {
+		case "u":
+			return gsnmpgo.VBT_Unsigned32(n), nil
+		case "c":
+			return gsnmpgo.VBT_Counter32(n), nil
+		}
+		return gsnmpgo.VBT_Timeticks(n), nil
+	case "C":
+		n, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return gsnmpgo.VBT_Counter64(n), nil
+	case "a":
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return nil, invalid
+		}
+		return gsnmpgo.VBT_IPAddress(ip.To4().String()), nil
+	case "o":
+		oid, err := parseName(value)
+		if err != nil {
+			return nil, invalid
+		}
+		return gsnmpgo.VBT_ObjectID("." + oid.String()), nil
+	case "s":
+		return gsnmpgo.VBT_OctetString(value), nil
+	case "x":
+		octets, err := hex.DecodeString(strings.NewReplacer(" ", "", ":", "").Replace(value))
+		if err != nil {
+			return nil, invalid
+		}
+		return gsnmpgo.VBT_OctetString(octets), nil
+	case "d":
+		var octets []byte
+		for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == '.' || r == ' ' }) {
+			n, err := strconv.ParseUint(field, 10, 8)
+			if err != nil {
+				return nil, invalid
+			}
+			octets = append(octets, byte(n))
+		}
+		return gsnmpgo.VBT_OctetString(octets), nil
+	case "n":
+		return gsnmpgo.VBT_Null{}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q, expected one of i u c C t a o s x d n", type_letter)
+}
+
+// parseVarbind parses an oid type value triple of gsnmpset.
+func parseVarbind(oid, type_letter, value string) (varbind gsnmpgo.QueryResult, err error) {
+	if varbind.Oid, err = parseName(oid); err != nil {
+		return varbind, err
+	}
+	varbind.Value, err = parseValue(type_letter, value)
+	return varbind, err
+}
+
+// setAgent sets the agent of the request. agent is either an RFC 4088 uri
+// (snmp://, snmp+tcp:// or snmp+unix://), whose oids are queried along with
+// any others given, or a net-snmp style address:
+//
+//    [transport:]host[:port]
+//
+// where transport is udp (the default), tcp, udp6, tcp6 or unix; for unix
+// the host is the path of the socket. An ipv6 host is given in brackets,
+// eg udp6:[::1]:161.
+func (opts *options) setAgent(agent string) error {
+	req := opts.req
+	if i := strings.Index(agent, "://"); i >= 0 {
+		parsed, err := gsnmpgo.ParseRequest(agent)
+		if err != nil {
+			return err
+		}
+		req.Host, req.Port, req.Transport = parsed.Host, parsed.Port, parsed.Transport
+		authority := agent[i+len("://"):]
+		if end := strings.Index(authority, "/"); end >= 0 {
+			authority = authority[:end]
+		}
+		if strings.Contains(authority, "@") {
+			req.Community = parsed.Community
+		}
+		if parsed.Usm != nil {
+			// a v3 context in the uri, unless given by -n and -E
+			if opts.usm.ContextName == "" {
+				opts.usm.ContextName = parsed.Usm.ContextName
+			}
+			if opts.usm.ContextEngineID == "" {
+				opts.usm.ContextEngineID = parsed.Usm.ContextEngineID
+			}
+		}
+		req.Oids = parsed.Oids
+		return nil
+	}
+
+	if i := strings.Index(agent, ":"); i > 0 {
+		switch transport := strings.ToLower(agent[:i]); transport {
+		case "udp", "tcp", "udp6", "tcp6":
+			agent = agent[i+1:]
+			if strings.HasPrefix(transport, "tcp") {
+				req.Transport = gsnmpgo.TRANSPORT_TCP
+			}
+			if strings.HasSuffix(transport, "6") {
+				req.Resolver = &gsnmpgo.Resolver{Prefer: gsnmpgo.ONLY_IPV6}
+			}
+		case "unix":
+			if agent[i+1:] == "" {
+				return errors.New("no socket path in agent")
+			}
+			req.Host, req.Transport = agent[i+1:], gsnmpgo.TRANSPORT_UNIX
+			return nil
+		}
+	}
+
+	host, port := agent, ""
+	if strings.HasPrefix(agent, "[") {
+		end := strings.Index(agent, "]")
+		if end < 0 {
+			return fmt.Errorf("invalid agent %q, no closing ]", agent)
+		}
+		host, port = agent[1:end], agent[end+1:]
+		if port != "" {
+			if port[0] != ':' {
+				return fmt.Errorf("invalid agent %q", agent)
+			}
+			port = port[1:]
+		}
+	} else if strings.Count(agent, ":") == 1 {
+		i := strings.Index(agent, ":")
+		host, port = agent[:i], agent[i+1:]
+	}
+	if host == "" {
+		return fmt.Errorf("invalid agent %q, no host", agent)
+	}
+	req.Host = host
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		req.Port = n
+	}
+	return nil
+}
+
+// setCommandOption sets a -C option: -Cr<n> and -Cn<n>, the max-repetitions
+// and non-repeaters of GETBULKs, and for tables -Ci (show the index) and
+// -Cf<separator>.
+func (opts *options) setCommandOption(cmd Command, value string) error {
+	bulk := cmd == BULKWALK || cmd == TABLE
+	switch {
+	case value == "":
+		return errors.New("option -C needs a value")
+	case bulk && (value[0] == 'r' || value[0] == 'n'):
+		n, err := strconv.Atoi(value[1:])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid option -C%s", value)
+		}
+		if value[0] == 'r' {
+			opts.req.Maxrep = n
+		} else {
+			opts.req.Nonrep = n
+		}
+	case cmd == TABLE && value == "i":
+		opts.index = true
+	case cmd == TABLE && value[0] == 'f' && len(value) > 1:
+		opts.sep = value[1:]
+	default:
+		return fmt.Errorf("unknown option -C%s for %s", value, cmd)
+	}
+	return nil
+}
+
+// usage prints the usage of cmd.
+func usage(cmd Command, w io.Writer) {
+	args := "oid ..."
+	switch cmd {
+	case WALK, BULKWALK:
+		args = "[oid ...]"
+	case SET:
+		args = "oid type value ..."
+	case TABLE:
+		args = "table-oid"
+	}
+	fmt.Fprintf(w, "usage: %s [options] agent %s\n\n", cmd, args)
+	fmt.Fprint(w, `agent is an snmp uri (snmp://public@host:161//oids, or snmp+tcp://,
+snmp+unix://), or [transport:]host[:port] where transport is udp, tcp,
+udp6, tcp6 or unix (with a socket path as the host).
+
+options:
+  -h                show this help
+  -v 1|2c|3         snmp version (default 2c)
+  -c community      community (default public); a uri's own community wins
+  -t seconds        timeout of each request (default 0.2)
+  -r retries        number of retries (default 3)
+  -O nqvD           output: n numeric oids, q no type or '=', v values only,
+                    D in the format of gsnmpgo.Dump
+  -m files          MIB files or directories to load, ':' separated; names
+                    can then be used for oids
+  -u user           v3 user name
+  -l level          v3 security level: noAuthNoPriv, authNoPriv or authPriv
+  -a MD5|SHA        v3 authentication protocol
+  -A password       v3 authentication password
+  -x DES|AES        v3 privacy protocol
+  -X password       v3 privacy password
+  -n context        v3 context name
+  -E engine-id      v3 context engine id, in hex
+`)
+	switch cmd {
+	case BULKWALK:
+		fmt.Fprint(w, `  -Cr<n>            max-repetitions of each GETBULK (default 100)
+  -Cn<n>            non-repeaters of each GETBULK (default 0)
+`)
+	case TABLE:
+		fmt.Fprint(w, `  -Cr<n>            max-repetitions of each GETBULK (default 100)
+  -Ci               show the index of each row
+  -Cf<separator>    separate columns with separator instead of aligning them
+
+table-oid is the table, eg ifTable; with -m it may be the table's entry, eg
+ifEntry.
+`)
+	case SET:
+		fmt.Fprint(w, `
+type is i (INTEGER), u (Gauge32), c (Counter32), C (Counter64), t (TimeTicks),
+a (IpAddress), o (OBJECT IDENTIFIER), s (STRING), x (hex STRING),
+d (decimal STRING) or n (NULL).
+`)
+	}
+}
@@ -0,0 +1,411 @@
+package cli
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/petar/GoLLRB/llrb"
+	"github.com/soniah/gsnmpgo"
+)
+
+// the snapshot of a device, in net-snmp's output format
+const snapshot = "../../../testing/snapshot/os-linux-std.txt"
+
+var parseArgsTests = []struct {
+	cmd  Command
+	args string
+	ok   bool
+	// the fields expected, if ok
+	version   gsnmpgo.SnmpVersion
+	community string
+	timeout   int
+	retries   int
+	maxrep    int
+	rest      string
+}{
+	{GET, "host 1.3.6", true, gsnmpgo.GNET_SNMP_V2C, "public", 200, 3, 100, "host 1.3.6"},
+	{GET, "-v1 -c private -t 1.5 -r0 host 1.3.6", true, gsnmpgo.GNET_SNMP_V1, "private", 1500, 0, 100, "host 1.3.6"},
+	{GET, "-v 2c -cprivate -t1 host", true, gsnmpgo.GNET_SNMP_V2C, "private", 1000, 3, 100, "host"},
+	{BULKWALK, "-Cr10 -Cn1 host", true, gsnmpgo.GNET_SNMP_V2C, "public", 200, 3, 10, "host"},
+	{TABLE, "-C r5 -Ci -Cf, host ifTable", true, gsnmpgo.GNET_SNMP_V2C, "public", 200, 3, 5, "host ifTable"},
+	{GET, "-On -Oq host -1", true, gsnmpgo.GNET_SNMP_V2C, "public", 200, 3, 100, "host -1"},
+	{GET, "-- -host", true, gsnmpgo.GNET_SNMP_V2C, "public", 200, 3, 100, "-host"},
+	{GET, "-v 2 host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-t 0 host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-t x host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-r -1 host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-Cr10 host", false, 0, "", 0, 0, 0, ""},
+	{BULKWALK, "-Cx host", false, 0, "", 0, 0, 0, ""},
+	{BULKWALK, "-Ci host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-Ox host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-z host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-l bogus host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-a SHA256 host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-E zz host", false, 0, "", 0, 0, 0, ""},
+	{GET, "-c", false, 0, "", 0, 0, 0, ""},
+}
+
+func TestParseArgs(t *testing.T) {
+	for i, test := range parseArgsTests {
+		opts, err := parseArgs(test.cmd, strings.Fields(test.args))
+		if !test.ok {
+			if err == nil {
+				t.Errorf("#%d: parseArgs(%s) expected an error", i, test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: parseArgs(%s) error: %s", i, test.args, err)
+			continue
+		}
+		req := opts.req
+		if req.Version != test.version || req.Community != test.community || req.Timeout != test.timeout ||
+			req.Retries != test.retries || req.Maxrep != test.maxrep || strings.Join(opts.args, " ") != test.rest {
+			t.Errorf("#%d: parseArgs(%s) got %+v, args %q", i, test.args, req, opts.args)
+		}
+	}
+
+	opts, err := parseArgs(GET, strings.Fields("-v3 -u noc -l authPriv -a sha -A secret1 -x AES -X secret2 -n vlan1 -E 0x8000 host"))
+	if err != nil {
+		t.Fatalf("parseArgs v3 error: %s", err)
+	}
+	expected := &gsnmpgo.UsmParams{UserName: "noc", SecLevel: gsnmpgo.GNET_SNMP_SECLEVEL_AP,
+		AuthProtocol: gsnmpgo.USM_AUTH_SHA, AuthPassword: "secret1", PrivProtocol: gsnmpgo.USM_PRIV_AES,
+		PrivPassword: "secret2", ContextName: "vlan1", ContextEngineID: "\x80\x00"}
+	if !reflect.DeepEqual(opts.req.Usm, expected) {
+		t.Errorf("parseArgs v3 expected %+v got %+v", expected, opts.req.Usm)
+	}
+
+	if _, err := parseArgs(GET, []string{"-h"}); err != errUsage {
+		t.Errorf("parseArgs(-h) expected errUsage, got %v", err)
+	}
+}
+
+var setAgentTests = []struct {
+	agent     string
+	ok        bool
+	host      string
+	port      int
+	transport gsnmpgo.Transport
+	community string
+	oids      []string
+}{
+	{"192.168.1.10", true, "192.168.1.10", 161, gsnmpgo.TRANSPORT_UDP, "c", nil},
+	{"host.example.com:1161", true, "host.example.com", 1161, gsnmpgo.TRANSPORT_UDP, "c", nil},
+	{"udp:host:1161", true, "host", 1161, gsnmpgo.TRANSPORT_UDP, "c", nil},
+	{"TCP:host", true, "host", 161, gsnmpgo.TRANSPORT_TCP, "c", nil},
+	{"tcp6:[::1]:1161", true, "::1", 1161, gsnmpgo.TRANSPORT_TCP, "c", nil},
+	{"[2001:db8::1]", true, "2001:db8::1", 161, gsnmpgo.TRANSPORT_UDP, "c", nil},
+	{"2001:db8::1", true, "2001:db8::1", 161, gsnmpgo.TRANSPORT_UDP, "c", nil},
+	{"unix:/var/agentx/master", true, "/var/agentx/master", 161, gsnmpgo.TRANSPORT_UNIX, "c", nil},
+	{"snmp://192.168.1.10", true, "192.168.1.10", 161, gsnmpgo.TRANSPORT_UDP, "c", nil},
+	{"snmp://private@192.168.1.10:1161//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.3.0)", true,
+		"192.168.1.10", 1161, gsnmpgo.TRANSPORT_UDP, "private", []string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.3.0"}},
+	{"snmp+unix://p%40ss@%2Ftmp%2Fagent.sock//1.3.6.1.2.1.1.1.0", true,
+		"/tmp/agent.sock", 161, gsnmpgo.TRANSPORT_UNIX, "p@ss", []string{"1.3.6.1.2.1.1.1.0"}},
+	{"snmp://host//1.3.x", false, "", 0, 0, "", nil},
+	{"host:0", false, "", 0, 0, "", nil},
+	{"host:x", false, "", 0, 0, "", nil},
+	{"[::1", false, "", 0, 0, "", nil},
+	{"[::1]x", false, "", 0, 0, "", nil},
+	{":161", false, "", 0, 0, "", nil},
+	{"unix:", false, "", 0, 0, "", nil},
+}
+
+func TestSetAgent(t *testing.T) {
+	for i, test := range setAgentTests {
+		opts := &options{req: gsnmpgo.NewRequest("", "c", gsnmpgo.GNET_SNMP_V2C, gsnmpgo.OP_GET)}
+		err := opts.setAgent(test.agent)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("#%d: setAgent(%s) expected an error", i, test.agent)
+			}
+			continue
+		}
+		req := opts.req
+		if err != nil {
+			t.Errorf("#%d: setAgent(%s) error: %s", i, test.agent, err)
+		} else if req.Host != test.host || req.Port != test.port || req.Transport != test.transport ||
+			req.Community != test.community || !reflect.DeepEqual(req.Oids, test.oids) {
+			t.Errorf("#%d: setAgent(%s) got %+v", i, test.agent, req)
+		}
+	}
+
+	opts := &options{req: gsnmpgo.NewRequest("", "c", gsnmpgo.GNET_SNMP_V2C, gsnmpgo.OP_GET)}
+	if err := opts.setAgent("udp6:host"); err != nil || opts.req.Resolver == nil ||
+		opts.req.Resolver.Prefer != gsnmpgo.ONLY_IPV6 {
+		t.Errorf("setAgent(udp6:host) expected an ONLY_IPV6 Resolver, got %+v (%v)", opts.req.Resolver, err)
+	}
+
+	// a uri's context, unless given by -n
+	for _, context := range []string{"", "other"} {
+		opts, err := parseArgs(GET, []string{"-v3", "-u", "admin", "-n", context, "snmp://admin@host/vlan2;engine/1.3.6"})
+		if err == nil {
+			err = opts.setAgent(opts.args[0])
+		}
+		expected := context
+		if expected == "" {
+			expected = "vlan2"
+		}
+		if err != nil || opts.req.Usm.ContextName != expected || opts.req.Usm.ContextEngineID != "engine" {
+			t.Errorf("setAgent() with -n %q expected context %s;engine, got %+v (%v)", context, expected, opts.req.Usm, err)
+		}
+	}
+}
+
+var parseValueTests = []struct {
+	value_type string
+	value      string
+	expected   gsnmpgo.Varbinder // nil for an error
+}{
+	{"i", "-5", gsnmpgo.VBT_Integer32(-5)},
+	{"i", "2147483648", nil},
+	{"u", "4294967295", gsnmpgo.VBT_Unsigned32(4294967295)},
+	{"u", "-1", nil},
+	{"c", "7", gsnmpgo.VBT_Counter32(7)},
+	{"C", "18446744073709551615", gsnmpgo.VBT_Counter64(18446744073709551615)},
+	{"t", "4381200", gsnmpgo.VBT_Timeticks(4381200)},
+	{"a", "10.0.0.1", gsnmpgo.VBT_IPAddress("10.0.0.1")},
+	{"a", "::1", nil},
+	{"a", "10.0.0", nil},
+	{"o", ".1.3.6.1.4.1.8072.3.2.10", gsnmpgo.VBT_ObjectID(".1.3.6.1.4.1.8072.3.2.10")},
+	{"o", "1.3.x", nil},
+	{"s", "Server Room", gsnmpgo.VBT_OctetString("Server Room")},
+	{"x", "00 25 89 27 56 1b", gsnmpgo.VBT_OctetString("\x00\x25\x89\x27\x56\x1b")},
+	{"x", "00:25:89", gsnmpgo.VBT_OctetString("\x00\x25\x89")},
+	{"x", "0g", nil},
+	{"d", "10.0.0.1", gsnmpgo.VBT_OctetString("\x0a\x00\x00\x01")},
+	{"d", "256", nil},
+	{"n", "", gsnmpgo.VBT_Null{}},
+	{"b", "0", nil},
+}
+
+func TestParseValue(t *testing.T) {
+	for i, test := range parseValueTests {
+		value, err := parseValue(test.value_type, test.value)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("#%d: parseValue(%s, %s) expected an error, got %v", i, test.value_type, test.value, value)
+			}
+		} else if err != nil || !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("#%d: parseValue(%s, %s) expected %#v got %#v (%v)", i, test.value_type, test.value, test.expected, value, err)
+		}
+	}
+}
+
+// snapshotAgent returns an agent serving the snapshot, and its address.
+func snapshotAgent(t *testing.T) (agent *gsnmpgo.Agent, addr string) {
+	results, err := gsnmpgo.ReadVeraxResults(snapshot)
+	if err != nil {
+		t.Fatalf("ReadVeraxResults error: %s", err)
+	}
+	agent = gsnmpgo.NewAgent(results)
+	agent.Writable = true
+	if err = agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	return agent, agent.Addr().String()
+}
+
+// run runs cmd with args, returning the exit status and output.
+func run(cmd Command, args ...string) (status int, stdout, stderr string) {
+	var out, errs bytes.Buffer
+	status = Main(cmd, args, &out, &errs)
+	return status, out.String(), errs.String()
+}
+
+func TestMainSnapshot(t *testing.T) {
+	agent, addr := snapshotAgent(t)
+	defer agent.Close()
+
+	// walking the whole agent gives back the snapshot; BITS need MIBs
+	contents, err := ioutil.ReadFile(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []string
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		if !strings.Contains(line, "= BITS: ") {
+			expected = append(expected, line)
+		}
+	}
+	for _, cmd := range []Command{WALK, BULKWALK} {
+		status, stdout, stderr := run(cmd, "-On", addr, ".1.3")
+		if status != 0 {
+			t.Fatalf("%s exit status %d: %s", cmd, status, stderr)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+			if !strings.Contains(line, ".1.3.6.1.2.1.88.") {
+				got = append(got, line)
+			}
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s output differs from the snapshot:\n%s", cmd, strings.Join(got, "\n"))
+		}
+	}
+}
+
+var mainTests = []struct {
+	cmd    Command
+	args   string // "AGENT" is replaced by the agent's address
+	status int
+	stdout string
+}{
+	{GET, "AGENT 1.3.6.1.2.1.1.5.0 .1.3.6.1.2.1.1.3.0", 0,
+		".1.3.6.1.2.1.1.3.0 = Timeticks: (4381200) 12:10:12.00\n.1.3.6.1.2.1.1.5.0 = STRING: \"nms\"\n"},
+	{GET, "-Oq AGENT 1.3.6.1.2.1.1.5.0", 0, ".1.3.6.1.2.1.1.5.0 \"nms\"\n"},
+	{GET, "-Ov AGENT 1.3.6.1.2.1.1.7.0", 0, "INTEGER: 72\n"},
+	{GET, "-Oqv AGENT 1.3.6.1.2.1.1.7.0", 0, "72\n"},
+	{GET, "AGENT 1.3.6.1.2.1.1.99.0", 0, ".1.3.6.1.2.1.1.99.0 = No Such Object available on this agent at this OID\n"},
+	{GET, "AGENT 1.3.6.1.2.1.1.1.1", 0, ".1.3.6.1.2.1.1.1.1 = No Such Instance currently exists at this OID\n"},
+	{GET, "-OD AGENT 1.3.6.1.2.1.1.7.0", 0,
+		"Dump:\noid, type: 1.3.6.1.2.1.1.7.0, gsnmpgo.VBT_Integer32\nINTEGER: 72\nSTRING : 72\n\n"},
+	{GETNEXT, "AGENT 1.3.6.1.2.1.4.20.1.1", 0, ".1.3.6.1.2.1.4.20.1.1.10.0.0.1 = IpAddress: 10.0.0.1\n"},
+	{GET, "snmp://public@AGENT//(1.3.6.1.2.1.1.5.0,1.3.6.1.2.1.1.6.0)", 0,
+		".1.3.6.1.2.1.1.5.0 = STRING: \"nms\"\n.1.3.6.1.2.1.1.6.0 = STRING: \"Server Room\"\n"},
+	{BULKWALK, "-Cr2 AGENT 1.3.6.1.2.1.2.2.1.6", 0,
+		".1.3.6.1.2.1.2.2.1.6.1 = STRING: \"\"\n.1.3.6.1.2.1.2.2.1.6.2 = Hex-STRING: 00 25 89 27 56 1B\n" +
+			".1.3.6.1.2.1.2.2.1.6.3 = Hex-STRING: 00 25 89 27 56 1C\n"},
+	{TABLE, "-Ci AGENT 1.3.6.1.2.1.4.20", 0,
+		"SNMP table: .1.3.6.1.2.1.4.20\n\n     index         1 2\n  10.0.0.1  10.0.0.1 2\n 127.0.0.1 127.0.0.1 1\n"},
+	{TABLE, "-Cf, AGENT 1.3.6.1.2.1.2.2", 0,
+		"SNMP table: .1.3.6.1.2.1.2.2\n\n1,2,3,4,5,6,8,10\n1,lo,24,16436,10000000,,1,1911702\n" +
+			"2,eth0,6,1500,1000000000,00 25 89 27 56 1B,1,4294967295\n3,eth1,6,1500,4294967295,00 25 89 27 56 1C,2,0\n"},
+	{TABLE, "AGENT 1.3.6.1.2.1.99", 0, ".1.3.6.1.2.1.99: No entries\n"},
+	{SET, "AGENT 1.3.6.1.2.1.1.6.0 s Basement 1.3.6.1.2.1.1.7.0 i 64", 0,
+		".1.3.6.1.2.1.1.6.0 = STRING: \"Basement\"\n.1.3.6.1.2.1.1.7.0 = INTEGER: 64\n"},
+	{GET, "-Oqv AGENT 1.3.6.1.2.1.1.6.0", 0, "\"Basement\"\n"},
+	{SET, "AGENT 1.3.6.1.2.1.1.6.0 i 1", 1, ""},
+	{SET, "AGENT 1.3.6.1.2.1.1.6.0 s", 2, ""},
+	{SET, "snmp://AGENT//1.3.6.1.2.1.1.6.0 1.3.6.1.2.1.1.6.0 s x", 2, ""},
+	{GET, "AGENT", 2, ""},
+	{GET, "", 2, ""},
+	{TABLE, "AGENT 1.3.6 1.3.7", 2, ""},
+	{GET, "-c private -t 0.05 -r 0 AGENT 1.3.6.1.2.1.1.5.0", 1, ""},
+	{GET, "-m /nonexistent AGENT 1.3.6.1.2.1.1.5.0", 1, ""},
+}
+
+func TestMainCommands(t *testing.T) {
+	agent, addr := snapshotAgent(t)
+	defer agent.Close()
+
+	for i, test := range mainTests {
+		args := strings.Fields(strings.Replace(test.args, "AGENT", addr, -1))
+		status, stdout, stderr := run(test.cmd, args...)
+		if status != test.status {
+			t.Errorf("#%d: %s %s expected exit status %d got %d: %s", i, test.cmd, test.args, test.status, status, stderr)
+			continue
+		}
+		if stdout != test.stdout {
+			t.Errorf("#%d: %s %s expected output:\n%s\ngot:\n%s", i, test.cmd, test.args, test.stdout, stdout)
+		}
+		if (status == 0) != (stderr == "") {
+			t.Errorf("#%d: %s %s exit status %d with errors %q", i, test.cmd, test.args, status, stderr)
+		}
+	}
+
+	status, stdout, _ := run(SET, "-h")
+	if status != 0 || !strings.HasPrefix(stdout, "usage: gsnmpset [options] agent oid type value") {
+		t.Errorf("gsnmpset -h expected usage, got %d %q", status, stdout)
+	}
+}
+
+func TestMainMibs(t *testing.T) {
+	agent, addr := snapshotAgent(t)
+	defer agent.Close()
+	defer func() { gsnmpgo.Mib = nil }()
+
+	tests := []struct {
+		cmd    Command
+		args   string
+		stdout string
+	}{
+		{GET, "AGENT sysDescr.0 SNMPv2-MIB::sysObjectID.0",
+			"SNMPv2-MIB::sysDescr.0 = STRING: \"Linux nms 3.2.0-35-generic #55-Ubuntu SMP Wed Dec 5 17:42:16 UTC 2012 x86_64\"\n" +
+				"SNMPv2-MIB::sysObjectID.0 = OID: RFC1155-SMI::enterprises.8072.3.2.10\n"},
+		{GET, "-On AGENT ifOperStatus.3", ".1.3.6.1.2.1.2.2.1.8.3 = INTEGER: down(2)\n"},
+		{GETNEXT, "AGENT ifPhysAddress.1", "IF-MIB::ifPhysAddress.2 = STRING: 00:25:89:27:56:1b\n"},
+		{GET, "AGENT mteTriggerTest.6.95.115.110.109.112.100.1.116",
+			"DISMAN-EVENT-MIB::mteTriggerTest.6.95.115.110.109.112.100.1.116 = BITS: C0 existence(0) boolean(1)\n"},
+		{TABLE, "AGENT ipAddrTable", "SNMP table: RFC1213-MIB::ipAddrTable\n\n ipAdEntAddr ipAdEntIfIndex\n    10.0.0.1              2\n   127.0.0.1              1\n"},
+		{SET, "AGENT sysLocation.0 s Attic", "SNMPv2-MIB::sysLocation.0 = STRING: \"Attic\"\n"},
+	}
+	for i, test := range tests {
+		args := append([]string{"-m", "../../../testing/mibs"}, strings.Fields(strings.Replace(test.args, "AGENT", addr, -1))...)
+		status, stdout, stderr := run(test.cmd, args...)
+		if status != 0 {
+			t.Errorf("#%d: %s %s exit status %d: %s", i, test.cmd, test.args, status, stderr)
+		} else if stdout != test.stdout {
+			t.Errorf("#%d: %s %s expected output:\n%s\ngot:\n%s", i, test.cmd, test.args, test.stdout, stdout)
+		}
+	}
+}
+
+func TestMainTableEntry(t *testing.T) {
+	defer func() { gsnmpgo.Mib = nil }()
+
+	// ipNetToMediaTable is indexed by ifIndex and IpAddress
+	results := llrb.New(gsnmpgo.LessOID)
+	for _, cell := range []struct {
+		oid   string
+		value gsnmpgo.Varbinder
+	}{
+		{"1.3.6.1.2.1.4.22.1.1.2.10.0.0.1", gsnmpgo.VBT_Integer32(2)},
+		{"1.3.6.1.2.1.4.22.1.1.2.10.0.0.254", gsnmpgo.VBT_Integer32(2)},
+		{"1.3.6.1.2.1.4.22.1.1.10.192.168.1.1", gsnmpgo.VBT_Integer32(10)},
+		{"1.3.6.1.2.1.4.22.1.3.2.10.0.0.1", gsnmpgo.VBT_IPAddress("10.0.0.1")},
+		{"1.3.6.1.2.1.4.22.1.3.2.10.0.0.254", gsnmpgo.VBT_IPAddress("10.0.0.254")},
+		{"1.3.6.1.2.1.4.22.1.3.10.192.168.1.1", gsnmpgo.VBT_IPAddress("192.168.1.1")},
+	} {
+		results.ReplaceOrInsert(gsnmpgo.QueryResult{Oid: gsnmpgo.MustParseOID(cell.oid), Value: cell.value})
+	}
+	agent := gsnmpgo.NewAgent(results)
+	if err := agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer agent.Close()
+	addr := agent.Addr().String()
+
+	rows := "2.10.0.0.1,2,10.0.0.1\n2.10.0.0.254,2,10.0.0.254\n10.192.168.1.1,10,192.168.1.1\n"
+	tests := []struct {
+		args   string
+		stdout string
+	}{
+		{"AGENT ipNetToMediaEntry",
+			"SNMP table: RFC1213-MIB::ipNetToMediaEntry\n\nindex,ipNetToMediaIfIndex,ipNetToMediaNetAddress\n" + rows},
+		{"AGENT RFC1213-MIB::ipNetToMediaTable",
+			"SNMP table: RFC1213-MIB::ipNetToMediaTable\n\nindex,ipNetToMediaIfIndex,ipNetToMediaNetAddress\n" + rows},
+		{"-On AGENT 1.3.6.1.2.1.4.22.1",
+			"SNMP table: .1.3.6.1.2.1.4.22.1\n\nindex,1,3\n" + rows},
+	}
+	for i, test := range tests {
+		args := append([]string{"-m", "../../../testing/mibs", "-Ci", "-Cf,"}, strings.Fields(strings.Replace(test.args, "AGENT", addr, -1))...)
+		status, stdout, stderr := run(TABLE, args...)
+		if status != 0 {
+			t.Errorf("#%d: %s %s exit status %d: %s", i, TABLE, test.args, status, stderr)
+		} else if stdout != test.stdout {
+			t.Errorf("#%d: %s %s expected output:\n%s\ngot:\n%s", i, TABLE, test.args, test.stdout, stdout)
+		}
+	}
+}
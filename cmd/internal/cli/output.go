@@ -0,0 +1,233 @@
+package cli
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// output.go prints results the way net-snmp's tools do, eg
+//
+//    SNMPv2-MIB::sysUpTime.0 = Timeticks: (4381200) 12:10:12.00
+//    .1.3.6.1.2.1.2.2.1.2.1 = STRING: "lo"
+//
+// so that the output of the two can be diffed, or in the format of
+// gsnmpgo.Dump.
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/petar/GoLLRB/llrb"
+	"github.com/soniah/gsnmpgo"
+)
+
+// printResults prints results, formatted as given by the -O letters in
+// output.
+func printResults(w io.Writer, results *llrb.Tree, output string) {
+	if strings.Contains(output, "D") {
+		gsnmpgo.Fdump(w, results)
+		return
+	}
+	numeric := strings.Contains(output, "n")
+	quick := strings.Contains(output, "q")
+	values := strings.Contains(output, "v")
+
+	ch := results.IterAscend()
+	for {
+		r := <-ch
+		if r == nil {
+			break
+		}
+		result := r.(gsnmpgo.QueryResult)
+		value_type, value := formatValue(result.Oid, result.Value, numeric)
+		if value_type == "STRING" && value == string(result.Value.Bytes()) {
+			value = `"` + value + `"` // quoted unless formatted by a DISPLAY-HINT
+		}
+		if value_type != "" && !quick {
+			value = value_type + ": " + value
+		}
+		switch {
+		case values:
+			fmt.Fprintln(w, value)
+		case quick:
+			fmt.Fprintln(w, oidName(result.Oid, numeric), value)
+		default:
+			fmt.Fprintln(w, oidName(result.Oid, numeric), "=", value)
+		}
+	}
+}
+
+// printTable prints a table like net-snmp's snmptable: the columns
+// aligned under a header of their names (or numbers, without MIBs), or
+// separated by opts.sep. Missing values in a sparse table are shown as '?'.
+func printTable(w io.Writer, oid gsnmpgo.OID, table *gsnmpgo.Table, opts *options) {
+	numeric := strings.Contains(opts.output, "n")
+	name := oidName(oid, numeric)
+	if len(table.Rows) == 0 {
+		fmt.Fprintf(w, "%s: No entries\n", name)
+		return
+	}
+	fmt.Fprintf(w, "SNMP table: %s\n\n", name)
+
+	var header []string
+	if opts.index {
+		header = append(header, "index")
+	}
+	for _, column := range table.Columns {
+		header = append(header, columnName(table.Entry.Append(column), column, numeric))
+	}
+	lines := [][]string{header}
+	for _, row := range table.Rows {
+		var line []string
+		if opts.index {
+			line = append(line, row.Index.String())
+		}
+		for _, column := range table.Columns {
+			value, ok := row.Values[column]
+			if !ok {
+				line = append(line, "?")
+				continue
+			}
+			_, text := formatValue(table.Entry.Append(column).Append(row.Index...), value, numeric)
+			line = append(line, text)
+		}
+		lines = append(lines, line)
+	}
+
+	if opts.sep != "" {
+		for _, line := range lines {
+			fmt.Fprintln(w, strings.Join(line, opts.sep))
+		}
+		return
+	}
+	widths := make([]int, len(header))
+	for _, line := range lines {
+		for i, field := range line {
+			if len(field) > widths[i] {
+				widths[i] = len(field)
+			}
+		}
+	}
+	for _, line := range lines {
+		for i, field := range line {
+			fmt.Fprintf(w, " %*s", widths[i], field)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// columnName returns the name of a table column, eg ifDescr, or its number
+// if the column isn't in a MIB.
+func columnName(oid gsnmpgo.OID, column uint32, numeric bool) string {
+	if gsnmpgo.Mib != nil && !numeric {
+		if node, index := gsnmpgo.Mib.Node(oid); node != nil && len(index) == 0 {
+			return node.Name
+		}
+	}
+	return strconv.FormatUint(uint64(column), 10)
+}
+
+// formatTimeticks formats ticks like net-snmp, eg "(4381200) 12:10:12.00"
+// or "(586731977) 67 days, 21:48:39.77".
+func formatTimeticks(ticks uint32) string {
+	centiseconds := ticks % 100
+	seconds := ticks / 100
+	days := seconds / 86400
+	seconds %= 86400
+	text := fmt.Sprintf("%d:%02d:%02d.%02d", seconds/3600, seconds/60%60, seconds%60, centiseconds)
+	switch days {
+	case 0:
+	case 1:
+		text = "1 day, " + text
+	default:
+		text = fmt.Sprintf("%d days, %s", days, text)
+	}
+	return fmt.Sprintf("(%d) %s", ticks, text)
+}
+
+// formatValue returns the net-snmp name of the type of value (empty for
+// NULL and the exceptions) and the value as text, using the MIBs if any are
+// loaded.
+func formatValue(oid gsnmpgo.OID, value gsnmpgo.Varbinder, numeric bool) (value_type, text string) {
+	text = value.String()
+	if gsnmpgo.Mib != nil {
+		text = gsnmpgo.Mib.Format(oid, value)
+	}
+	switch v := value.(type) {
+	case gsnmpgo.VBT_Integer32:
+		return "INTEGER", text
+	case gsnmpgo.VBT_OctetString:
+		if gsnmpgo.Mib != nil && text != v.String() {
+			if node, _ := gsnmpgo.Mib.Node(oid); node != nil && node.Syntax == "BITS" {
+				return "BITS", fmt.Sprintf("% X %s", []byte(v), text) // eg "C0 existence(0) boolean(1)"
+			}
+		}
+		if text == v.String() && !isPrintable(v.Bytes()) {
+			return "Hex-STRING", text
+		}
+		return "STRING", text
+	case gsnmpgo.VBT_ObjectID:
+		if parsed, err := gsnmpgo.ParseOID(string(v)); err == nil {
+			text = oidName(parsed, numeric)
+		}
+		return "OID", text
+	case gsnmpgo.VBT_IPAddress:
+		return "IpAddress", text
+	case gsnmpgo.VBT_Unsigned32:
+		return "Gauge32", text
+	case gsnmpgo.VBT_Counter32:
+		return "Counter32", text
+	case gsnmpgo.VBT_Counter64:
+		return "Counter64", text
+	case gsnmpgo.VBT_Timeticks:
+		return "Timeticks", formatTimeticks(uint32(v))
+	case gsnmpgo.VBT_Opaque:
+		return "OPAQUE", text
+	case gsnmpgo.VBT_Null, *gsnmpgo.VBT_Null:
+		return "", "NULL"
+	case gsnmpgo.VBT_NoSuchObject, *gsnmpgo.VBT_NoSuchObject:
+		return "", "No Such Object available on this agent at this OID"
+	case gsnmpgo.VBT_NoSuchInstance, *gsnmpgo.VBT_NoSuchInstance:
+		return "", "No Such Instance currently exists at this OID"
+	case gsnmpgo.VBT_EndOfMibView, *gsnmpgo.VBT_EndOfMibView:
+		return "", "No more variables left in this MIB View (It is past the end of the MIB tree)"
+	}
+	return "", text
+}
+
+// isPrintable returns true if all of b is printable text.
+func isPrintable(b []byte) bool {
+	for _, c := range b {
+		if !strconv.IsPrint(rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// oidName returns oid as a name if MIBs are loaded and numeric is false,
+// otherwise in numeric form with a leading dot as net-snmp shows it.
+func oidName(oid gsnmpgo.OID, numeric bool) string {
+	if gsnmpgo.Mib != nil && !numeric {
+		if name := gsnmpgo.Mib.Name(oid); name != oid.String() {
+			return name
+		}
+	}
+	return "." + oid.String()
+}
@@ -0,0 +1,72 @@
+package cli
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"testing"
+
+	"github.com/soniah/gsnmpgo"
+)
+
+func TestFormatTimeticks(t *testing.T) {
+	tests := []struct {
+		ticks    uint32
+		expected string
+	}{
+		{0, "(0) 0:00:00.00"},
+		{4381200, "(4381200) 12:10:12.00"},
+		{8640000, "(8640000) 1 day, 0:00:00.00"},
+		{21913544, "(21913544) 2 days, 12:52:15.44"},
+		{586731977, "(586731977) 67 days, 21:48:39.77"},
+	}
+	for i, test := range tests {
+		if got := formatTimeticks(test.ticks); got != test.expected {
+			t.Errorf("#%d: formatTimeticks(%d) expected %s got %s", i, test.ticks, test.expected, got)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value      gsnmpgo.Varbinder
+		value_type string
+		text       string
+	}{
+		{gsnmpgo.VBT_Integer32(-1), "INTEGER", "-1"},
+		{gsnmpgo.VBT_OctetString("eth0"), "STRING", "eth0"},
+		{gsnmpgo.VBT_OctetString("\x00\x25"), "Hex-STRING", "00 25"},
+		{gsnmpgo.VBT_ObjectID(".1.3.6.1.4.1.8072.3.2.10"), "OID", ".1.3.6.1.4.1.8072.3.2.10"},
+		{gsnmpgo.VBT_IPAddress("10.0.0.1"), "IpAddress", "10.0.0.1"},
+		{gsnmpgo.VBT_Unsigned32(4294967295), "Gauge32", "4294967295"},
+		{gsnmpgo.VBT_Counter32(7), "Counter32", "7"},
+		{gsnmpgo.VBT_Counter64(18446744073709551615), "Counter64", "18446744073709551615"},
+		{gsnmpgo.VBT_Timeticks(100), "Timeticks", "(100) 0:00:01.00"},
+		{gsnmpgo.VBT_Opaque("\x9f\x78\x04"), "OPAQUE", "9F 78 04"},
+		{gsnmpgo.VBT_Null{}, "", "NULL"},
+		{new(gsnmpgo.VBT_NoSuchObject), "", "No Such Object available on this agent at this OID"},
+		{gsnmpgo.VBT_NoSuchInstance{}, "", "No Such Instance currently exists at this OID"},
+		{new(gsnmpgo.VBT_EndOfMibView), "", "No more variables left in this MIB View (It is past the end of the MIB tree)"},
+	}
+	oid := gsnmpgo.MustParseOID("1.3.6.1.2.1.1.1.0")
+	for i, test := range tests {
+		value_type, text := formatValue(oid, test.value, false)
+		if value_type != test.value_type || text != test.text {
+			t.Errorf("#%d: formatValue(%#v) expected %s: %s got %s: %s", i, test.value, test.value_type, test.text, value_type, text)
+		}
+	}
+}
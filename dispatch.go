@@ -0,0 +1,117 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// dispatch.go contains the dispatcher that all calls into gsnmp go through.
+//
+// gsnmp runs a glib main loop for each sync_* call, and glib aborts with
+// "main loop already active in another thread" if calls are made from
+// whichever OS thread a goroutine happens to be on. Instead, C calls are
+// sent over a channel to a single worker goroutine that is locked to its own
+// OS thread (runtime.LockOSThread). The sync_* calls iterate the global
+// default GMainContext, so only one can run at a time: queries into gsnmp
+// are serialized.
+
+/*
+#cgo pkg-config: glib-2.0 gsnmp
+#include "c_bridge.h"
+*/
+import "C"
+
+import (
+	"code.google.com/p/tcgl/applog"
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Workers is kept for compatibility, and is ignored: gsnmp's sync_* calls
+// all iterate the global default GMainContext, so the calls into gsnmp are
+// run one at a time on a single dispatcher goroutine, whatever its value.
+var Workers = 1
+
+// a function to be run by a dispatcher worker
+type dispatchRequest struct {
+	fn        func()
+	recovered interface{} // value of a panic in fn, re-raised in the caller
+	done      chan bool
+}
+
+var (
+	dispatchOnce  sync.Once
+	dispatchQueue chan *dispatchRequest
+)
+
+// dispatch runs fn on the dispatcher worker, and waits for it to
+// finish. It can be called from any goroutine.
+func dispatch(fn func()) {
+	dispatchContext(context.Background(), fn)
+}
+
+// dispatchContext is like dispatch, but gives up waiting for the worker
+// if ctx is done, returning ctx.Err() without running fn. Once fn has
+// started it runs to completion; fn itself should check ctx.
+func dispatchContext(ctx context.Context, fn func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dispatchOnce.Do(startDispatcher)
+	request := &dispatchRequest{fn: fn, done: make(chan bool)}
+	select {
+	case dispatchQueue <- request:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-request.done
+	if request.recovered != nil {
+		panic(request.recovered)
+	}
+	return nil
+}
+
+// dispatchWorker runs requests from the dispatch queue on a locked OS thread.
+// The worker runs for the life of the program.
+func dispatchWorker() {
+	runtime.LockOSThread()
+	if Debug {
+		applog.Debugf("dispatcher worker started")
+	}
+
+	for request := range dispatchQueue {
+		runRequest(request)
+	}
+}
+
+// runRequest runs a single request, passing any panic back to the caller
+// rather than killing the worker.
+func runRequest(request *dispatchRequest) {
+	defer func() {
+		request.recovered = recover()
+		close(request.done)
+	}()
+	request.fn()
+}
+
+// startDispatcher starts the dispatcher worker.
+func startDispatcher() {
+	dispatchQueue = make(chan *dispatchRequest)
+	go dispatchWorker()
+}
@@ -0,0 +1,288 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// display.go formats values: octets as hex, MAC addresses or UTF-8 text,
+// and any value using its definition in a MIB: enumerated INTEGERs,
+// DISPLAY-HINTs (RFC 2579 3.1) and BITS.
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Format returns value, the value of oid, as text using the definition of
+// oid in the MIB:
+//
+//    INTEGERs with named numbers are shown as name(number), eg "up(1)"
+//    values with a DISPLAY-HINT are formatted by it, eg a MacAddress is shown
+//      as "00:25:89:27:56:1b" and a DateAndTime as "2010-12-5,10:31:44.0,+11:0"
+//    InetAddresses are shown as ipv4 or ipv6 addresses
+//    BITS are shown as the bits that are set, eg "boolean(1) threshold(2)"
+//
+// Anything else, including values that don't fit their DISPLAY-HINT, is
+// shown as value.String().
+func (m *MIB) Format(oid OID, value Varbinder) string {
+	node, _ := m.Node(oid)
+	if node == nil || node.Syntax == "" {
+		return value.String()
+	}
+	syntax, hint, enums, convention := m.typeOf(node)
+
+	switch v := value.(type) {
+	case VBT_Integer32, VBT_Unsigned32, VBT_Counter32, VBT_Counter64:
+		n := value.Integer()
+		if name, ok := enums[int(n.Int64())]; ok && n.IsInt64() {
+			return fmt.Sprintf("%s(%s)", name, n)
+		}
+		if hint != "" {
+			if s, err := displayHintInteger(hint, n); err == nil {
+				return s
+			}
+		}
+	case VBT_OctetString:
+		data := v.Bytes()
+		switch {
+		case syntax == "BITS":
+			return displayBits(data, enums)
+		case hint != "":
+			if s, err := displayHint(hint, data); err == nil {
+				return s
+			}
+		case convention == "InetAddress":
+			return displayInetAddress(data)
+		}
+	}
+	return value.String()
+}
+
+// FormatHex returns data as a hex string, eg "00 25 89 27 56 1B", the way
+// VBT_Opaque values and unprintable VBT_OctetString values are shown.
+func FormatHex(data []byte) string {
+	return hexString(data)
+}
+
+// FormatMAC returns data as a MAC address, eg "00:25:89:27:56:1b".
+func FormatMAC(data []byte) string {
+	return net.HardwareAddr(data).String()
+}
+
+// FormatUTF8 returns data as UTF-8 text, eg a description in a language
+// other than English. Invalid UTF-8 sequences are replaced by U+FFFD.
+func FormatUTF8(data []byte) string {
+	return strings.ToValidUTF8(string(data), "\uFFFD")
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// displayBits returns the bits set in data (a BITS value), named by enums,
+// eg "boolean(1) threshold(2)". Bit 0 is the most significant bit of the
+// first byte.
+func displayBits(data []byte, enums map[int]string) string {
+	var bits []string
+	for i := 0; i < len(data)*8; i++ {
+		if data[i/8]&(0x80>>uint(i%8)) == 0 {
+			continue
+		}
+		if name, ok := enums[i]; ok {
+			bits = append(bits, fmt.Sprintf("%s(%d)", name, i))
+		} else {
+			bits = append(bits, strconv.Itoa(i))
+		}
+	}
+	return strings.Join(bits, " ")
+}
+
+// displayHint formats data with an OCTET STRING DISPLAY-HINT, eg "1x:" for
+// a MacAddress or "255a" for a DisplayString.
+func displayHint(hint string, data []byte) (string, error) {
+	specs, err := parseDisplayHint(hint)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	for pos, i := 0, 0; pos < len(data); i++ {
+		// the last specification is repeated until the data runs out
+		spec := specs[len(specs)-1]
+		if i < len(specs) {
+			spec = specs[i]
+		}
+		count := 1
+		if spec.repeat {
+			count = int(data[pos])
+			pos++
+		}
+		for c := 0; c < count && pos < len(data); c++ {
+			end := pos + spec.length
+			if end > len(data) {
+				end = len(data)
+			}
+			chunk := data[pos:end]
+			pos = end
+			switch spec.format {
+			case 'a', 't':
+				out.Write(chunk)
+			case 'd':
+				out.WriteString(new(big.Int).SetBytes(chunk).String())
+			case 'o':
+				out.WriteString(new(big.Int).SetBytes(chunk).Text(8))
+			case 'x':
+				out.WriteString(fmt.Sprintf("%0*x", 2*len(chunk), new(big.Int).SetBytes(chunk)))
+			}
+			last := spec.repeat && c == count-1 && spec.terminator != 0
+			if pos < len(data) && spec.separator != 0 && !last {
+				out.WriteByte(spec.separator)
+			}
+		}
+		if spec.repeat && spec.terminator != 0 && pos < len(data) {
+			out.WriteByte(spec.terminator)
+		}
+	}
+	return out.String(), nil
+}
+
+// displayHintInteger formats n with an INTEGER DISPLAY-HINT, eg "x" or "d-2"
+// (two decimal places).
+func displayHintInteger(hint string, n *big.Int) (string, error) {
+	switch {
+	case hint == "d":
+		return n.String(), nil
+	case hint == "x":
+		return n.Text(16), nil
+	case hint == "o":
+		return n.Text(8), nil
+	case hint == "b":
+		return n.Text(2), nil
+	case strings.HasPrefix(hint, "d-"):
+		places, err := strconv.Atoi(hint[2:])
+		if err != nil || places < 0 {
+			break
+		}
+		digits := new(big.Int).Abs(n).String()
+		for len(digits) <= places {
+			digits = "0" + digits
+		}
+		s := digits
+		if places > 0 {
+			s = digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+		}
+		if n.Sign() < 0 {
+			s = "-" + s
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("%s: displayHintInteger(): invalid display hint %q", libname(), hint)
+}
+
+// displayHintSpec is one specification of an OCTET STRING DISPLAY-HINT, eg
+// "1x:" or "*1d." (RFC 2579 3.1).
+type displayHintSpec struct {
+	repeat     bool // the first byte of the data is a repeat count
+	length     int  // the number of bytes used by each application
+	format     byte // a (ascii), t (utf-8), d (decimal), o (octal) or x (hex)
+	separator  byte // shown after each application, if not 0
+	terminator byte // shown after the repeated applications, if not 0
+}
+
+// displayInetAddress formats an InetAddress (RFC 4001) by its length, as
+// the InetAddressType that goes with it isn't known here: ipv4 and ipv6
+// addresses, with a zone index if there is one, or otherwise a dns name.
+func displayInetAddress(data []byte) string {
+	switch len(data) {
+	case net.IPv4len, net.IPv6len:
+		return net.IP(data).String()
+	case net.IPv4len + 4, net.IPv6len + 4:
+		ip := net.IP(data[:len(data)-4]).String()
+		zone := new(big.Int).SetBytes(data[len(data)-4:])
+		return ip + "%" + zone.String()
+	}
+	return string(data)
+}
+
+// parseDisplayHint splits an OCTET STRING DISPLAY-HINT into its
+// specifications.
+func parseDisplayHint(hint string) (specs []displayHintSpec, err error) {
+	invalid := fmt.Errorf("%s: parseDisplayHint(): invalid display hint %q", libname(), hint)
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	for i := 0; i < len(hint); {
+		var spec displayHintSpec
+		if hint[i] == '*' {
+			spec.repeat = true
+			i++
+		}
+		start := i
+		for i < len(hint) && isDigit(hint[i]) {
+			i++
+		}
+		if i == start || i == len(hint) {
+			return nil, invalid
+		}
+		spec.length, _ = strconv.Atoi(hint[start:i])
+		spec.format = hint[i]
+		i++
+		if spec.length < 1 || !strings.ContainsRune("adotx", rune(spec.format)) {
+			return nil, invalid
+		}
+		if i < len(hint) && !isDigit(hint[i]) && hint[i] != '*' {
+			spec.separator = hint[i]
+			i++
+		}
+		if spec.repeat && i < len(hint) && !isDigit(hint[i]) && hint[i] != '*' {
+			spec.terminator = hint[i]
+			i++
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, invalid
+	}
+	return specs, nil
+}
+
+// typeOf returns the underlying syntax of node (following its textual
+// convention), its DISPLAY-HINT, its named numbers and the name of its
+// textual convention, if any.
+func (m *MIB) typeOf(node *MibNode) (syntax, hint string, enums map[int]string, convention string) {
+	syntax, enums = node.Syntax, node.Enums
+	// textual conventions shouldn't be defined in terms of other textual
+	// conventions, but some are
+	for depth := 0; depth < 4; depth++ {
+		mib_type := m.types[node.Module][syntax]
+		if mib_type == nil {
+			mib_type = m.Type(syntax)
+		}
+		if mib_type == nil {
+			break
+		}
+		if convention == "" {
+			convention = mib_type.Name
+		}
+		if hint == "" {
+			hint = mib_type.DisplayHint
+		}
+		if enums == nil {
+			enums = mib_type.Enums
+		}
+		syntax = mib_type.Syntax
+	}
+	return syntax, hint, enums, convention
+}
@@ -0,0 +1,217 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+var displayHintTests = []struct {
+	hint string
+	data []byte
+	out  string
+}{
+	{"1x:", []byte{0x00, 0x25, 0x89, 0x27, 0x56, 0x1b}, "00:25:89:27:56:1b"},
+	{"255a", []byte("eth0"), "eth0"},
+	{"1d.1d.1d.1d", []byte{10, 0, 0, 1}, "10.0.0.1"},
+	{"2x:2x:2x:2x:2x:2x:2x:2x", []byte{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0x02, 0x25, 0x89, 0xff, 0xfe, 0x27, 0x56, 0x1b},
+		"fe80:0000:0000:0000:0225:89ff:fe27:561b"},
+	{"2d-1d-1d,1d:1d:1d.1d,1a1d:1d", []byte{0x07, 0xda, 0x0c, 0x05, 0x0a, 0x1f, 0x2c, 0x00},
+		"2010-12-5,10:31:44.0"},
+	{"2d-1d-1d,1d:1d:1d.1d,1a1d:1d", []byte{0x07, 0xda, 0x0c, 0x05, 0x0a, 0x1f, 0x2c, 0x00, 0x2b, 0x0b, 0x00},
+		"2010-12-5,10:31:44.0,+11:0"},
+	{"1o", []byte{8, 9}, "1011"},
+	{"*1x:/1d", []byte{2, 0xab, 0xcd, 7}, "ab:cd/7"},
+	{"1x:", nil, ""},
+}
+
+func TestDisplayHint(t *testing.T) {
+	for i, test := range displayHintTests {
+		out, err := displayHint(test.hint, test.data)
+		if err != nil {
+			t.Errorf("#%d: displayHint(%q) error: %s", i, test.hint, err)
+			continue
+		}
+		if out != test.out {
+			t.Errorf("#%d: displayHint(%q) expected %q got %q", i, test.hint, test.out, out)
+		}
+	}
+
+	for i, bad := range []string{"", "x", "1", "0x", "1q", "*x"} {
+		if _, err := displayHint(bad, []byte{1}); err == nil {
+			t.Errorf("#%d: displayHint(%q) expected error", i, bad)
+		}
+	}
+}
+
+var displayHintIntegerTests = []struct {
+	hint string
+	n    int64
+	out  string
+}{
+	{"d", 1234, "1234"},
+	{"x", 255, "ff"},
+	{"o", 8, "10"},
+	{"b", 5, "101"},
+	{"d-2", 1234, "12.34"},
+	{"d-2", 5, "0.05"},
+	{"d-1", -15, "-1.5"},
+	{"d-0", 7, "7"},
+}
+
+func TestDisplayHintInteger(t *testing.T) {
+	for i, test := range displayHintIntegerTests {
+		out, err := displayHintInteger(test.hint, big.NewInt(test.n))
+		if err != nil {
+			t.Errorf("#%d: displayHintInteger(%q) error: %s", i, test.hint, err)
+			continue
+		}
+		if out != test.out {
+			t.Errorf("#%d: displayHintInteger(%q, %d) expected %q got %q", i, test.hint, test.n, test.out, out)
+		}
+	}
+
+	for i, bad := range []string{"", "a", "d-", "d-x", "1x:"} {
+		if _, err := displayHintInteger(bad, big.NewInt(1)); err == nil {
+			t.Errorf("#%d: displayHintInteger(%q) expected error", i, bad)
+		}
+	}
+}
+
+func TestDisplayBits(t *testing.T) {
+	enums := map[int]string{0: "existence", 1: "boolean", 2: "threshold"}
+	tests := []struct {
+		data []byte
+		out  string
+	}{
+		{[]byte{0x40}, "boolean(1)"},
+		{[]byte{0xe0}, "existence(0) boolean(1) threshold(2)"},
+		{[]byte{0x20, 0x01}, "threshold(2) 15"},
+		{[]byte{0x00}, ""},
+	}
+	for i, test := range tests {
+		if out := displayBits(test.data, enums); out != test.out {
+			t.Errorf("#%d: displayBits(% x) expected %q got %q", i, test.data, test.out, out)
+		}
+	}
+}
+
+func TestDisplayInetAddress(t *testing.T) {
+	tests := []struct {
+		data []byte
+		out  string
+	}{
+		{[]byte{10, 0, 0, 1}, "10.0.0.1"},
+		{[]byte{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, "fe80::1"},
+		{[]byte{10, 0, 0, 1, 0, 0, 0, 3}, "10.0.0.1%3"},
+		{[]byte("www.example.com"), "www.example.com"},
+	}
+	for i, test := range tests {
+		if out := displayInetAddress(test.data); out != test.out {
+			t.Errorf("#%d: displayInetAddress(% x) expected %q got %q", i, test.data, test.out, out)
+		}
+	}
+}
+
+func TestFormatOctets(t *testing.T) {
+	tests := []struct {
+		data []byte
+		hex  string
+		mac  string
+		utf8 string
+	}{
+		{[]byte{0x00, 0x25, 0x89, 0x27, 0x56, 0x1b}, "00 25 89 27 56 1B", "00:25:89:27:56:1b", "\x00%\uFFFD'V\x1b"},
+		{[]byte("Zürich"), "5A C3 BC 72 69 63 68", "5a:c3:bc:72:69:63:68", "Zürich"},
+		{[]byte{'a', 0xff}, "61 FF", "61:ff", "a\uFFFD"},
+		{nil, "", "", ""},
+	}
+	for i, test := range tests {
+		if hex := FormatHex(test.data); hex != test.hex {
+			t.Errorf("#%d: FormatHex(% x) expected %q got %q", i, test.data, test.hex, hex)
+		}
+		if mac := FormatMAC(test.data); mac != test.mac {
+			t.Errorf("#%d: FormatMAC(% x) expected %q got %q", i, test.data, test.mac, mac)
+		}
+		if utf8 := FormatUTF8(test.data); utf8 != test.utf8 {
+			t.Errorf("#%d: FormatUTF8(% x) expected %q got %q", i, test.data, test.utf8, utf8)
+		}
+	}
+}
+
+var mibFormatTests = []struct {
+	oid   string
+	value Varbinder
+	out   string
+}{
+	{"IF-MIB::ifOperStatus.1", VBT_Integer32(1), "up(1)"},
+	{"IF-MIB::ifOperStatus.1", VBT_Integer32(99), "99"},
+	{"IF-MIB::ifPhysAddress.2", VBT_OctetString("\x00\x25\x89\x27\x56\x1b"), "00:25:89:27:56:1b"},
+	{"IF-MIB::ifDescr.1", VBT_OctetString("lo"), "lo"},
+	{"IF-MIB::ifIndex.2", VBT_Integer32(2), "2"},
+	{"IF-MIB::ifPromiscuousMode.2", VBT_Integer32(2), "false(2)"},
+	{"HOST-RESOURCES-MIB::hrSystemDate.0", VBT_OctetString("\x07\xda\x0c\x05\x0a\x1f\x2c\x00\x2b\x0b\x00"), "2010-12-5,10:31:44.0,+11:0"},
+	{"HOST-RESOURCES-MIB::hrMemorySize.0", VBT_Integer32(2055924), "2055924"},
+	{"DISMAN-EVENT-MIB::mteTriggerTest.6.95.115.110.109.112.100.1.116", VBT_OctetString("\xc0"), "existence(0) boolean(1)"},
+	{"IP-FORWARD-MIB::inetCidrRouteNextHop.1", VBT_OctetString("\x0a\x00\x00\x01"), "10.0.0.1"},
+	{"IP-FORWARD-MIB::inetCidrRouteNextHopType.1", VBT_Integer32(2), "ipv6(2)"},
+	{"1.3.99.1", VBT_Integer32(1), "1"},
+}
+
+func TestMibFormat(t *testing.T) {
+	mib, err := LoadMIBs("testing/mibs")
+	if err != nil {
+		t.Fatalf("LoadMIBs error: %s", err)
+	}
+	for i, test := range mibFormatTests {
+		oid, err := mib.ParseName(test.oid)
+		if err != nil {
+			t.Errorf("#%d: ParseName(%s) error: %s", i, test.oid, err)
+			continue
+		}
+		if out := mib.Format(oid, test.value); out != test.out {
+			t.Errorf("#%d: Format(%s, %s) expected %q got %q", i, test.oid, test.value, test.out, out)
+		}
+	}
+}
+
+func TestVeraxBits(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []byte
+	}{
+		{"C0 existence(0) boolean(1)", []byte{0xc0}},
+		{"40 boolean(1)", []byte{0x40}},
+		{"00 ", []byte{0x00}},
+		{"20 01 2 15", []byte{0x20, 0x01}},
+	}
+	for i, test := range tests {
+		out, err := veraxBits(test.in)
+		if err != nil {
+			t.Errorf("#%d: veraxBits(%q) error: %s", i, test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(out, test.out) {
+			t.Errorf("#%d: veraxBits(%q) expected % x got % x", i, test.in, test.out, out)
+		}
+	}
+	if _, err := veraxBits("C0 boolean(1)"); err == nil {
+		t.Errorf("veraxBits(%q) expected error", "C0 boolean(1)")
+	}
+}
@@ -5,21 +5,16 @@ gsnmpgo is pre 1.0, therefore API's may change, and tests are minimal.
 
 ISSUES
 
-Snmp walks aren't being done as "GETBULK" when using snmp v2c, rather repeated
-getnexts are being done ie like snmp v1. The gsnmp C library doesn't implement
-GETBULK directly, my intention is to write the C code to do it.
-
-Threading: either gsnmp isn't totally thread safe, or I'm making errors with my
-C calls from Go (more likely). I'm getting aborts with this message:
+Threading: gsnmp runs a glib main loop for each query, and aborts with this
+message if queries are made from different OS threads:
 
     GLib-WARNING **: g_main_context_prepare(): main loop already active in another thread
 
-My current workaround is to force Query() calls to be synchronous:
-
-    var mu sync.Mutex
-    mu.Lock()
-    _, _ = gsnmpgo.Query(params)
-    mu.Unlock()
+Hence gsnmpgo makes all of its calls into gsnmp from a dispatcher goroutine
+that is locked to its own OS thread (runtime.LockOSThread). Query() can be
+called from any goroutine without any external locking, but the queries are
+serialized: gsnmp's main loops all run in glib's global default context, so
+only one query can be in progress at a time. gsnmpgo.Workers is ignored.
 
 INSTALLATION
 
@@ -60,6 +55,17 @@ Builds ok with these C libs (Ubuntu versions shown):
     libgsnmp0-dev                                  0.3.0-1.1
     libgnet-dev                                    2.0.8-2.1
 
+PURE GO BACKEND
+
+gsnmpgo can also be built without gsnmp or glib, using a backend written in
+Go. It is used with the purego build tag, or when cgo is disabled:
+
+    go install -tags purego github.com/soniah/gsnmpgo
+    CGO_ENABLED=0 go install github.com/soniah/gsnmpgo
+
+The API is the same. Queries don't use the dispatcher goroutine, so they run
+concurrently.
+
 SUMMARY
 
 (most of this code is in examples/example.go)
@@ -93,6 +99,190 @@ as doing an snmp get you can also do a snmp getnext or snmp walk:
     // WALK - notice the star at the end
     // uri := `snmp://public@192.168.1.10//1.3.6.1.2.1.*`
 
+An ipv6 host goes in brackets (snmp://public@[::1]:161//1.3.6.1.2.1.1.1.0),
+and characters like '@' and '/' in the community can be percent-encoded
+(%40, %2F). Uris are parsed in Go, for both backends, before a query is
+sent; a malformed uri gives a *UriError whose Pos
+is the byte offset of the problem, eg 24 for the 'x' in
+snmp://host//(1.3.6,1.3.x).
+
+Walks using snmp v2c are done with GETBULK requests; QueryParams.Nonrep and
+QueryParams.Maxrep control the number of non-repeaters and the number of rows
+retrieved per request. Each oid is walked until it leaves its subtree. Walks
+using snmp v1 fall back to a series of GETNEXTs.
+
+Use QueryContext() to be able to cancel a query, or give it a deadline. Walks
+are stopped between requests, and the results collected so far are returned
+along with ctx.Err():
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+    results, err := gsnmpgo.QueryContext(ctx, params)
+    if err == context.DeadlineExceeded {
+        // results holds the part of the walk that was done
+    }
+
+REQUESTS
+
+Instead of a uri, a query can be described by a Request, which isn't parsed,
+so communities can contain any characters (eg '@' or '/'), and which can also
+express SETs and v3 queries:
+
+    req := gsnmpgo.NewRequest("192.168.1.10", "p@ss/word", gsnmpgo.GNET_SNMP_V2C,
+        gsnmpgo.OP_BULKWALK, "1.3.6.1.2.1.2.2")
+    results, err := gsnmpgo.QueryRequest(req)
+
+The operations are OP_GET (any number of oids, see GetMany), OP_GETNEXT,
+OP_WALK, OP_BULKWALK and OP_SET (of req.Varbinds). ParseRequest() converts an
+existing uri to a Request, and Request.Uri() converts back, percent-encoding
+the community. A uri with a context (snmp://user@host/context;engine-id/oids)
+becomes a v3 Request, whose Usm has the user and context.
+
+SESSIONS
+
+Query() parses the uri and sets up a new gsnmp session every time. When
+polling one device many times, use a Session instead; the host is resolved and
+the session configured once, in Open():
+
+    s := gsnmpgo.NewSession("192.168.1.10", "public", gsnmpgo.GNET_SNMP_V2C)
+    if err := s.Open(); err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+    defer s.Close()
+
+    results, err := s.Get([]string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.5.0"})
+    results, err = s.BulkWalk([]string{"1.3.6.1.2.1.2.2"})
+
+A Session has Get, GetMany, GetNext, Walk, BulkWalk and Set methods. Requests
+on one Session are done one at a time; use a Session per device for parallel
+polling.
+
+HOST NAMES AND IPV6
+
+Agents can be queried over ipv4 or ipv6, by address or by name. Names are
+resolved in Go by a Resolver, which decides whether ipv4 or ipv6 addresses are
+used, and how long addresses are cached:
+
+    resolver := &gsnmpgo.Resolver{Prefer: gsnmpgo.PREFER_IPV6, TTL: 5 * time.Minute}
+    params := gsnmpgo.NewDefaultParams(`snmp://public@router.example.com//1.3.6.1.2.1.1.1.0`)
+    params.Resolver = resolver
+    results, status, err := gsnmpgo.QueryWithStatus(params)
+    fmt.Println("sent to", status.Addr) // eg [2001:db8::1]:161
+
+The preferences are PREFER_SYSTEM (the order of the system resolver),
+PREFER_IPV4, PREFER_IPV6, ONLY_IPV4 and ONLY_IPV6. A Resolver can be shared,
+and set on a Session, Request or poller Target too; without one,
+DefaultResolver is used, which uses the system's order and doesn't cache.
+The address used is in QueryStatus.Addr, Session.Addr() and PollResult.Addr.
+
+IpAddress values are 4 bytes, but the 16 byte ipv6 addresses some agents
+send are decoded too, eg VBT_IPAddress("2001:db8::1").
+
+TRANSPORTS
+
+Requests are sent over UDP by default. TCP (RFC 3430) and Unix-domain stream
+sockets are selected by the uri's scheme, or by QueryParams.Transport for a
+plain snmp:// uri. For a Unix-domain socket the host is the socket's path,
+percent-encoded:
+
+    uri := `snmp+tcp://public@192.168.1.10//1.3.6.1.2.1.1.1.0`
+    uri := `snmp+unix://public@%2Fvar%2Fagentx%2Fmaster//1.3.6.1.2.1.1.1.0`
+
+    params := gsnmpgo.NewDefaultParams(`snmp://public@192.168.1.10//1.3.6.1.2.1.1.1.0`)
+    params.Transport = gsnmpgo.TRANSPORT_TCP
+
+Sessions, Requests and poller Targets have a Transport field too; with
+TRANSPORT_UNIX, Host is the socket's path. QueryStatus.Addr is a
+*net.UDPAddr, *net.TCPAddr or *net.UnixAddr.
+
+With the pure Go backend, a Session keeps its connection open between
+requests, and redials if the agent has closed it. Nothing is resent over a
+stream; instead a request waits Timeout * (Retries + 1) for its response.
+
+SNMP V3
+
+For snmp v3, set Version to GNET_SNMP_V3 and supply the user's credentials in
+QueryParams.Usm. The security level decides which credentials are used:
+
+    params := gsnmpgo.NewDefaultParams(`snmp://192.168.1.10//(1.3.6.1.2.1.1.1.0)`)
+    params.Version = gsnmpgo.GNET_SNMP_V3
+    params.Usm = &gsnmpgo.UsmParams{
+        UserName:     "noc",
+        SecLevel:     gsnmpgo.GNET_SNMP_SECLEVEL_AP, // authPriv
+        AuthProtocol: gsnmpgo.USM_AUTH_SHA,
+        AuthPassword: "maplesyrup",
+        PrivProtocol: gsnmpgo.USM_PRIV_AES,
+        PrivPassword: "maplesyrup",
+    }
+
+GNET_SNMP_SECLEVEL_NANP (noAuthNoPriv) only needs a UserName, and
+GNET_SNMP_SECLEVEL_ANP (authNoPriv) doesn't need the Priv fields.
+ContextName and ContextEngineID can also be set, eg to query a vlan context.
+
+gsnmp 0.3.0 can't authenticate or encrypt, so v3 queries are done in Go with
+either backend, without going through gsnmp: the agent's engine ID is
+discovered (RFC 3414 4), messages are authenticated with HMAC-MD5-96 or
+HMAC-SHA-96, and encrypted with DES or AES-128 (RFC 3826).
+
+SETS
+
+Set() does an snmp set; the target and community are taken from the uri, and
+the values to set are passed as QueryResults:
+
+    uri := `snmp://private@192.168.1.10`
+    params := gsnmpgo.NewDefaultParams(uri)
+    varbinds := []gsnmpgo.QueryResult{
+        {Oid: gsnmpgo.MustParseOID("1.3.6.1.2.1.1.4.0"), Value: gsnmpgo.VBT_OctetString("noc@example.com")},
+        {Oid: gsnmpgo.MustParseOID("1.3.6.1.2.1.2.2.1.7.3"), Value: gsnmpgo.VBT_Integer32(2)}, // ifAdminStatus down
+    }
+    results, err := gsnmpgo.Set(params, varbinds)
+
+If the agent rejects the set, err will be a *gsnmpgo.AgentError containing the
+agent's error-status (a PduError) and error-index.
+
+NOTIFICATIONS
+
+A Listener receives v1 traps, v2c traps and v2c informs on a UDP port, and
+delivers them on a channel. Informs are acknowledged by the Listener:
+
+    l, err := gsnmpgo.NewListener(":162")
+    if err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+    defer l.Close()
+    for n := range l.C {
+        fmt.Printf("%s from %s (%s): %s\n", n.TrapOid, n.Source, n.Community, n.Uptime)
+        for _, vb := range n.Varbinds {
+            fmt.Printf("  %s: %s\n", vb.Oid, vb.Value)
+        }
+    }
+
+For v1 traps, TrapOid is made from the enterprise, generic-trap and
+specific-trap fields (RFC 3584), which are also available in the Enterprise,
+AgentAddress, GenericTrap and SpecificTrap fields. TrapOid and Enterprise are
+OIDs, eg n.TrapOid.HasPrefix(enterprise) checks a trap's origin. Varbinds use
+the same types as query results. v3 notifications aren't supported yet, and are dropped.
+
+SendTrap() and SendInform() send notifications to a manager. The manager and
+community are taken from the uri, and the port defaults to 162.
+SendInform() waits for the acknowledgement, retrying like a query:
+
+    params := gsnmpgo.NewDefaultParams(`snmp://public@nms.example.com`)
+    alarm := &gsnmpgo.Notification{
+        TrapOid: gsnmpgo.MustParseOID("1.3.6.1.6.3.1.1.5.3"), // linkDown
+        Uptime:  gsnmpgo.VBT_Timeticks(uptime),
+        Varbinds: []gsnmpgo.QueryResult{
+            {Oid: gsnmpgo.MustParseOID("1.3.6.1.2.1.2.2.1.1.3"), Value: gsnmpgo.VBT_Integer32(3)},
+        },
+    }
+    err := gsnmpgo.SendInform(params, alarm)
+
+A v1 trap is sent when params.Version is GNET_SNMP_V1. Its enterprise,
+generic-trap and specific-trap fields are made from TrapOid, unless
+Enterprise is set.
+
 RESULTS
 
 The results are returned as an LLRB tree to provide "ordered map"
@@ -100,13 +290,29 @@ functionality (ie finding items by "key", and iterating "in order"). Items in
 the tree are of type QueryResult:
 
     type QueryResult struct {
-        Oid   string
+        Oid   OID
         Value Varbinder
     }
 
 See http://github.com/petar/GoLLRB for more documentation on using the LLRB
 tree.
 
+An OID is a slice of sub-identifiers ([]uint32); its String() method gives the
+usual dotted format. Use ParseOID() (or MustParseOID() for constants) to
+convert a string to an OID, eg to look up a result:
+
+    sysdescr := gsnmpgo.MustParseOID("1.3.6.1.2.1.1.1.0")
+    if r := results.Get(gsnmpgo.QueryResult{Oid: sysdescr}); r != nil {
+        fmt.Println(r.(gsnmpgo.QueryResult).Value)
+    }
+
+OIDs have methods for working with the oid tree, eg Compare(), HasPrefix(),
+IsChildOf(), Parent(), Append() and Index(), which returns the row index of a
+table cell:
+
+    ifdescr := gsnmpgo.MustParseOID("1.3.6.1.2.1.2.2.1.2")
+    index, ok := result.Oid.Index(ifdescr) // eg 3 for 1.3.6.1.2.1.2.2.1.2.3
+
 SNMP types are represented by Go types that implement the Varbinder interface
 (eg "Octet String" is VBT_OctetString, "IP Address" is VBT_IPAddress). Use a
 type switch to make decisions based on the SNMP type:
@@ -126,11 +332,13 @@ type switch to make decisions based on the SNMP type:
         }
     }
 
-The Varbinder interface has two convenience functions Integer() and String()
-that allow you to get all your results "as a string" or "as a number":
+The Varbinder interface has convenience functions Integer(), String() and
+Bytes() that allow you to get all your results "as a number", "as a string"
+or as the octets that were received:
 
     type Varbinder interface {
         Integer() *big.Int
+        Bytes() []byte
         fmt.Stringer
     }
 
@@ -152,11 +360,161 @@ formatted as days, hours, etc when returned as a string:
     OID 1.3.6.1.2.1.1.3.0 as a number: 4381200
     OID 1.3.6.1.2.1.1.3.0 as a string: 0 days, 12:10:12.00
 
+An OCTET STRING may be text, a MAC address or binary data. VBT_OctetString
+keeps the octets as they were received, and its String() shows them as text
+if they're all printable and as a hex string (eg "00 25 89 27 56 1B")
+otherwise. Use Bytes() for the octets, or FormatMAC(), FormatHex() or
+FormatUTF8() to show them a particular way:
+
+    fmt.Println(gsnmpgo.FormatMAC(result.Value.Bytes())) // 00:25:89:27:56:1b
+
+TABLES
+
+Walking a table gives a flat tree of column.index oids. Session.Table walks a
+table and pivots it into rows keyed by index, each with the values of its
+columns. Give the table, and optionally the columns to walk. The table's
+entry may be given instead; without MIBs defining it, it's recognised by
+there being no rows below oid.1:
+
+    table, err := session.Table("1.3.6.1.2.1.2.2", 2, 10) // ifDescr, ifInOctets
+    for _, row := range table.Rows {
+        fmt.Printf("ifIndex %s: %s %s\n", row.Index, row.Values[2], row.Values[10])
+    }
+
+Rows of sparse tables only have values for some columns, so check the map
+before using a value. Row() finds a row by index. NewTable() pivots the
+results of a walk done with Query().
+
+Row indexes are OIDs; SplitIndex() splits multi-part indexes, eg the ifIndex
+and IpAddress of ipNetToMediaTable:
+
+    parts, err := gsnmpgo.SplitIndex(row.Index, 1, 4)
+
+RATES
+
+Rates() compares the counters (Counter32 and Counter64) in two polls of an
+agent and returns how much each went up, and its rate per second. Include
+sysUpTime.0 in each poll so restarts of the agent are detected; the counters
+then start again from 0, so their Rates are flagged as Reset rather than
+given a bogus value. Otherwise a counter that went down has wrapped (at 2^32
+or 2^64), and is flagged as Wrapped with the increase modulo that; without
+sysUpTime.0 a restart is taken for a wrap.
+
+A RateTracker keeps the previous poll, for polling in a loop:
+
+    var tracker gsnmpgo.RateTracker
+    ...
+    rates, err := tracker.Add(time.Now(), results)
+    for _, rate := range rates {
+        if !rate.Reset {
+            fmt.Printf("%s: %.1f/s\n", rate.Oid, rate.PerSecond)
+        }
+    }
+
+POLLING
+
+A Poller queries many devices periodically. Each Poll is a set of oids to get
+(or walk) from a Target every Interval; results arrive on Results(), or are
+passed to Callback:
+
+    poller := gsnmpgo.NewPoller()
+    poller.Jitter = 5 * time.Second
+    router := &gsnmpgo.Target{Host: "192.168.1.10", Community: "public", Version: gsnmpgo.GNET_SNMP_V2C}
+    poller.Add(&gsnmpgo.Poll{Target: router, Oids: []string{"1.3.6.1.2.1.1.3.0"}, Interval: time.Minute})
+    for result := range poller.Results() {
+        if result.Err != nil {
+            ...
+        }
+        rates, err := tracker.Add(result.Time, result.Results)
+    }
+
+MaxInFlight limits the queries in flight across all devices, and
+Target.MaxConcurrent those to each device. A device that doesn't respond is
+polled less often, backing off up to MaxBackoff. Stop() stops polling and
+closes Results().
+
+MIBS
+
+LoadMIBs() reads SMIv1 and SMIv2 MIB modules from files or directory trees
+(eg /usr/share/snmp/mibs), and translates between names and OIDs:
+
+    mib, err := gsnmpgo.LoadMIBs("/usr/share/snmp/mibs")
+    oid, err := mib.ParseName("IF-MIB::ifHCInOctets.3") // 1.3.6.1.2.1.31.1.1.1.6.3
+    fmt.Println(mib.Name(oid))                         // IF-MIB::ifHCInOctets.3
+
+Names can be qualified by their module or not ("ifHCInOctets.3"). Lookup()
+returns the definition of an object (its SYNTAX, MAX-ACCESS, INDEX etc), and
+Node() the nearest definition above an OID.
+
+Set gsnmpgo.Mib to use names in the uris given to Query() and in the oids
+given to Session methods, and to show names in Dump():
+
+    gsnmpgo.Mib = mib
+    params := gsnmpgo.NewDefaultParams("snmp://public@192.168.1.10//ifTable.*")
+
+Format() shows a value the way its MIB definition says to: named numbers
+("up(1)"), DISPLAY-HINTs (a MacAddress as "00:25:89:27:56:1b", a DateAndTime
+as "2010-12-5,10:31:44.0,+11:0") and BITS ("existence(0) boolean(1)"):
+
+    fmt.Println(mib.Format(result.Oid, result.Value))
+
+ERRORS
+
+Query() and Set() return typed errors, so failures can be told apart without
+string matching. Use errors.Is and errors.As:
+
+    results, err := gsnmpgo.Query(params)
+    var uri_err *gsnmpgo.UriError
+    var agent_err *gsnmpgo.AgentError
+    switch {
+    case errors.Is(err, gsnmpgo.ErrTimeout):
+        // the agent didn't respond (a *TimeoutError)
+    case errors.As(err, &uri_err):
+        // the uri couldn't be parsed
+    case errors.As(err, &agent_err):
+        // the agent returned an error-status, eg agent_err.Status ==
+        // gsnmpgo.GNET_SNMP_PDU_ERR_NOSUCHNAME, at agent_err.Index
+    }
+
+A *SessionError is returned when a session can't be set up, eg the host
+doesn't resolve or the v3 credentials are incomplete.
+
+Query() only returns an error when there are no results at all. Use
+QueryWithStatus() to get partial results as well as the agent's error-status
+and error-index, and any error message from gsnmp:
+
+    results, status, err := gsnmpgo.QueryWithStatus(params)
+    if err == nil && !status.Ok() {
+        fmt.Printf("partial results: %s at index %d (%s)\n",
+            status.Error, status.Index, status.Message)
+    }
+
 TESTS
 
-The tests use the Verax Snmp Simulator [1]; setup Verax before running "go test":
+The tests don't need a real device: an in-process Agent answers queries from
+snapshots of devices in Verax format (testing/snapshot). An Agent can also be
+used to test code that uses gsnmpgo:
 
-* download, install and run Verax with the default configuration
+    results, _ := gsnmpgo.ReadVeraxResults("testing/snapshot/os-linux-std.txt")
+    agent := gsnmpgo.NewAgent(results)
+    agent.Writable = true // allow SETs
+    if err := agent.Listen("127.0.0.1:0"); err != nil {
+        ...
+    }
+    defer agent.Close()
+    uri := "snmp://public@" + agent.Addr().String() + "//1.3.6.1.2.1.1.1.0"
+
+Agent.ListenStream("tcp", addr) and ListenStream("unix", path) answer over TCP
+and Unix-domain sockets instead. For v3 queries, add the users to Agent.Users
+before calling Listen:
+
+    agent.Users = []gsnmpgo.UsmParams{{UserName: "noc", SecLevel: gsnmpgo.GNET_SNMP_SECLEVEL_ANP,
+        AuthProtocol: gsnmpgo.USM_AUTH_SHA, AuthPassword: "maplesyrup"}}
+
+The device files of the Verax Snmp Simulator [1] are also tested when they're
+available; the simulator itself doesn't need to be running:
+
+* download and install Verax
 
 * in the gsnmpgo/testing directory, setup these symlinks (or equivalents for your system):
 
@@ -172,6 +530,27 @@ The tests use the Verax Snmp Simulator [1]; setup Verax before running "go test"
 
 [1] http://www.veraxsystems.com/en/products/snmpsimulator
 
+COMMAND-LINE TOOLS
+
+cmd/ has tools for ad-hoc queries, built on QueryRequest: gsnmpget,
+gsnmpgetnext, gsnmpwalk (GETNEXT), gsnmpbulkwalk (GETBULK), gsnmpset and
+gsnmptable. They take net-snmp style options (-v, -c, -t, -r, -Cr, -Cn, the v3
+options and -m to load MIBs) and an agent, either a uri or a net-snmp style
+[transport:]host[:port]:
+
+    go install github.com/soniah/gsnmpgo/cmd/...
+    gsnmpbulkwalk -v2c -c public -Cr50 192.168.1.10 1.3.6.1.2.1.2.2
+    gsnmpget 'snmp://public@192.168.1.10//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.3.0)'
+    gsnmpset tcp:192.168.1.10 1.3.6.1.2.1.1.6.0 s "Server Room"
+
+Results are printed as net-snmp prints them, so the outputs can be diffed:
+
+    .1.3.6.1.2.1.1.3.0 = Timeticks: (4381200) 12:10:12.00
+
+-On, -Oq and -Ov work as they do in net-snmp, and -OD prints in the format of
+Dump() (Fdump() prints it to any io.Writer). Run a tool with -h for all its
+options.
+
 HELPER FUNCTIONS
 
 There are a number of helper functions. Many of these have tests that serve as
@@ -182,11 +561,18 @@ PartitionAllP()
 If you have a many oids to retrieve for a single device, you could:
 
 * send all the oids in one SNMP Get - could cause network problems
-* do an SNMP BulkWalk - but that isn't implemented yet, and maybe
-  your target device only supports SNMP v1 anyway
+* do an SNMP walk using GETBULK - but you may not want the whole
+  subtree, and maybe your target device only supports SNMP v1 anyway
+
+Instead, use GetMany() (or Session.GetMany), which takes any number of OIDs
+and GETs them MAX_URI_COUNT at a time, halving the number in each request if
+the agent answers tooBig, and returns all the results in one tree:
+
+    params := gsnmpgo.NewDefaultParams("snmp://public@192.168.1.10")
+    results, err := gsnmpgo.GetMany(params, oids)
 
-Instead, use PartitionAllP() to break up your large list of OIDs into
-partitions of n.
+Or use PartitionAllP() to break up your large list of OIDs into partitions
+of n yourself.
 
 Sonia Hamilton, sonia@snowfrog.net, http://www.snowfrog.net.
 */
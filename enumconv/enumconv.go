@@ -1,5 +1,5 @@
 // Package enumconv provides helper functions for gocog, used in
-// gsnmpgo/stringers.go.
+// gsnmpgo/enums.go and gsnmpgo/stringers.go.
 package enumconv
 
 // gsnmpgo is a go/cgo wrapper around gsnmp.
@@ -40,6 +40,13 @@ import (
 // fields like gotypename and ctypename, as parsing would be overkill for this
 // project.
 func Write(gotypename string, ctypename string, enums []string, ccode string, start_at int) {
+	WriteGo(gotypename, ctypename, enums, start_at)
+	WriteC(gotypename, ctypename, ccode)
+}
+
+// WriteGo writes the Go type, values and Stringer for a C enum, ie the parts
+// that don't need cgo. See Write for the parameters.
+func WriteGo(gotypename string, ctypename string, enums []string, start_at int) {
 
 	// type
 	fmt.Printf("\n// type and values for %s\n", ctypename)
@@ -83,6 +90,11 @@ func Write(gotypename string, ctypename string, enums []string, ccode string, st
 
 	// end stringer function
 	fmt.Println("}")
+}
+
+// WriteC writes the Stringer for the C type of an enum, which uses the Go
+// type Stringer written by WriteGo. See Write for the parameters.
+func WriteC(gotypename string, ctypename string, ccode string) {
 	fmt.Println()
 
 	// C type Stringer
@@ -98,6 +110,7 @@ func Write(gotypename string, ctypename string, enums []string, ccode string, st
 	}
 
 	// start stringer function
+	receiver_name := strings.ToLower(gotypename)
 	fmt.Printf("func (%s %s) String() string {\n", receiver_name, ctypename)
 
 	// stringer body - use gotype stringer
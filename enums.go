@@ -0,0 +1,319 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// enums.go contains the Go types, values and Stringers for gsnmp's C enums;
+// the Stringers for the C types are in stringers.go. github.com/natefinch/gocog
+// is used to generate the boilerplate. AFTER EDITING any gocog sections
+// (between gocog open and close square brackets), you MUST run:
+//
+//     rm -f enums.go_cog; $GOPATH/bin/gocog enums.go; go fmt ./...
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"GNET_SNMP_VARBIND_TYPE_NULL", "GNET_SNMP_VARBIND_TYPE_OCTETSTRING", "GNET_SNMP_VARBIND_TYPE_OBJECTID", "GNET_SNMP_VARBIND_TYPE_IPADDRESS", "GNET_SNMP_VARBIND_TYPE_INTEGER32", "GNET_SNMP_VARBIND_TYPE_UNSIGNED32", "GNET_SNMP_VARBIND_TYPE_COUNTER32", "GNET_SNMP_VARBIND_TYPE_TIMETICKS", "GNET_SNMP_VARBIND_TYPE_OPAQUE", "GNET_SNMP_VARBIND_TYPE_COUNTER64", "GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT", "GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE", "GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW"}
+	enumconv.WriteGo("VarBindType", "_Ctype_GNetSnmpVarBindType", vals, 0)
+}
+gocog]]]*/
+
+// type and values for _Ctype_GNetSnmpVarBindType
+type VarBindType int
+
+const (
+	GNET_SNMP_VARBIND_TYPE_NULL VarBindType = iota
+	GNET_SNMP_VARBIND_TYPE_OCTETSTRING
+	GNET_SNMP_VARBIND_TYPE_OBJECTID
+	GNET_SNMP_VARBIND_TYPE_IPADDRESS
+	GNET_SNMP_VARBIND_TYPE_INTEGER32
+	GNET_SNMP_VARBIND_TYPE_UNSIGNED32
+	GNET_SNMP_VARBIND_TYPE_COUNTER32
+	GNET_SNMP_VARBIND_TYPE_TIMETICKS
+	GNET_SNMP_VARBIND_TYPE_OPAQUE
+	GNET_SNMP_VARBIND_TYPE_COUNTER64
+	GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT
+	GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE
+	GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW
+)
+
+// Stringer for VarBindType
+func (varbindtype VarBindType) String() string {
+	switch varbindtype {
+	case GNET_SNMP_VARBIND_TYPE_NULL:
+		return "GNET_SNMP_VARBIND_TYPE_NULL"
+	case GNET_SNMP_VARBIND_TYPE_OCTETSTRING:
+		return "GNET_SNMP_VARBIND_TYPE_OCTETSTRING"
+	case GNET_SNMP_VARBIND_TYPE_OBJECTID:
+		return "GNET_SNMP_VARBIND_TYPE_OBJECTID"
+	case GNET_SNMP_VARBIND_TYPE_IPADDRESS:
+		return "GNET_SNMP_VARBIND_TYPE_IPADDRESS"
+	case GNET_SNMP_VARBIND_TYPE_INTEGER32:
+		return "GNET_SNMP_VARBIND_TYPE_INTEGER32"
+	case GNET_SNMP_VARBIND_TYPE_UNSIGNED32:
+		return "GNET_SNMP_VARBIND_TYPE_UNSIGNED32"
+	case GNET_SNMP_VARBIND_TYPE_COUNTER32:
+		return "GNET_SNMP_VARBIND_TYPE_COUNTER32"
+	case GNET_SNMP_VARBIND_TYPE_TIMETICKS:
+		return "GNET_SNMP_VARBIND_TYPE_TIMETICKS"
+	case GNET_SNMP_VARBIND_TYPE_OPAQUE:
+		return "GNET_SNMP_VARBIND_TYPE_OPAQUE"
+	case GNET_SNMP_VARBIND_TYPE_COUNTER64:
+		return "GNET_SNMP_VARBIND_TYPE_COUNTER64"
+	case GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT:
+		return "GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT"
+	case GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE:
+		return "GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE"
+	case GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW:
+		return "GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW"
+	}
+	return "UNKNOWN VarBindType"
+}
+
+//[[[end]]]
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"GNET_SNMP_URI_GET", "GNET_SNMP_URI_NEXT", "GNET_SNMP_URI_WALK"}
+	enumconv.WriteGo("UriType", "_Ctype_GNetSnmpUriType", vals, 0)
+}
+gocog]]]*/
+
+// type and values for _Ctype_GNetSnmpUriType
+type UriType int
+
+const (
+	GNET_SNMP_URI_GET UriType = iota
+	GNET_SNMP_URI_NEXT
+	GNET_SNMP_URI_WALK
+)
+
+// Stringer for UriType
+func (uritype UriType) String() string {
+	switch uritype {
+	case GNET_SNMP_URI_GET:
+		return "GNET_SNMP_URI_GET"
+	case GNET_SNMP_URI_NEXT:
+		return "GNET_SNMP_URI_NEXT"
+	case GNET_SNMP_URI_WALK:
+		return "GNET_SNMP_URI_WALK"
+	}
+	return "UNKNOWN UriType"
+}
+
+//[[[end]]]
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"GNET_SNMP_SECMODEL_ANY", "GNET_SNMP_SECMODEL_SNMPV1", "GNET_SNMP_SECMODEL_SNMPV2C", "GNET_SNMP_SECMODEL_SNMPV3"}
+	enumconv.WriteGo("SecModel", "_Ctype_GNetSnmpSecModel", vals, 0)
+}
+gocog]]]*/
+
+// type and values for _Ctype_GNetSnmpSecModel
+type SecModel int
+
+const (
+	GNET_SNMP_SECMODEL_ANY SecModel = iota
+	GNET_SNMP_SECMODEL_SNMPV1
+	GNET_SNMP_SECMODEL_SNMPV2C
+	GNET_SNMP_SECMODEL_SNMPV3
+)
+
+// Stringer for SecModel
+func (secmodel SecModel) String() string {
+	switch secmodel {
+	case GNET_SNMP_SECMODEL_ANY:
+		return "GNET_SNMP_SECMODEL_ANY"
+	case GNET_SNMP_SECMODEL_SNMPV1:
+		return "GNET_SNMP_SECMODEL_SNMPV1"
+	case GNET_SNMP_SECMODEL_SNMPV2C:
+		return "GNET_SNMP_SECMODEL_SNMPV2C"
+	case GNET_SNMP_SECMODEL_SNMPV3:
+		return "GNET_SNMP_SECMODEL_SNMPV3"
+	}
+	return "UNKNOWN SecModel"
+}
+
+//[[[end]]]
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"GNET_SNMP_SECLEVEL_NANP", "GNET_SNMP_SECLEVEL_ANP", "GNET_SNMP_SECLEVEL_AP"}
+	enumconv.WriteGo("SecLevel", "_Ctype_GNetSnmpSecLevel", vals, 0)
+}
+gocog]]]*/
+
+// type and values for _Ctype_GNetSnmpSecLevel
+type SecLevel int
+
+const (
+	GNET_SNMP_SECLEVEL_NANP SecLevel = iota
+	GNET_SNMP_SECLEVEL_ANP
+	GNET_SNMP_SECLEVEL_AP
+)
+
+// Stringer for SecLevel
+func (seclevel SecLevel) String() string {
+	switch seclevel {
+	case GNET_SNMP_SECLEVEL_NANP:
+		return "GNET_SNMP_SECLEVEL_NANP"
+	case GNET_SNMP_SECLEVEL_ANP:
+		return "GNET_SNMP_SECLEVEL_ANP"
+	case GNET_SNMP_SECLEVEL_AP:
+		return "GNET_SNMP_SECLEVEL_AP"
+	}
+	return "UNKNOWN SecLevel"
+}
+
+//[[[end]]]
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"GNET_SNMP_PDU_ERR_DONE", "GNET_SNMP_PDU_ERR_PROCEDURE", "GNET_SNMP_PDU_ERR_INTERNAL", "GNET_SNMP_PDU_ERR_NORESPONSE", "GNET_SNMP_PDU_ERR_NOERROR", "GNET_SNMP_PDU_ERR_TOOBIG", "GNET_SNMP_PDU_ERR_NOSUCHNAME", "GNET_SNMP_PDU_ERR_BADVALUE", "GNET_SNMP_PDU_ERR_READONLY", "GNET_SNMP_PDU_ERR_GENERROR", "GNET_SNMP_PDU_ERR_NOACCESS", "GNET_SNMP_PDU_ERR_WRONGTYPE", "GNET_SNMP_PDU_ERR_WRONGLENGTH", "GNET_SNMP_PDU_ERR_WRONGENCODING", "GNET_SNMP_PDU_ERR_WRONGVALUE", "GNET_SNMP_PDU_ERR_NOCREATION", "GNET_SNMP_PDU_ERR_INCONSISTENTVALUE", "GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE", "GNET_SNMP_PDU_ERR_COMMITFAILED", "GNET_SNMP_PDU_ERR_UNDOFAILED", "GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR", "GNET_SNMP_PDU_ERR_NOTWRITABLE", "GNET_SNMP_PDU_ERR_INCONSISTENTNAME"}
+	enumconv.WriteGo("PduError", "_Ctype_gint32", vals, -4)
+}
+gocog]]]*/
+
+// type and values for _Ctype_gint32
+type PduError int
+
+const (
+	GNET_SNMP_PDU_ERR_DONE PduError = iota - 4
+	GNET_SNMP_PDU_ERR_PROCEDURE
+	GNET_SNMP_PDU_ERR_INTERNAL
+	GNET_SNMP_PDU_ERR_NORESPONSE
+	GNET_SNMP_PDU_ERR_NOERROR
+	GNET_SNMP_PDU_ERR_TOOBIG
+	GNET_SNMP_PDU_ERR_NOSUCHNAME
+	GNET_SNMP_PDU_ERR_BADVALUE
+	GNET_SNMP_PDU_ERR_READONLY
+	GNET_SNMP_PDU_ERR_GENERROR
+	GNET_SNMP_PDU_ERR_NOACCESS
+	GNET_SNMP_PDU_ERR_WRONGTYPE
+	GNET_SNMP_PDU_ERR_WRONGLENGTH
+	GNET_SNMP_PDU_ERR_WRONGENCODING
+	GNET_SNMP_PDU_ERR_WRONGVALUE
+	GNET_SNMP_PDU_ERR_NOCREATION
+	GNET_SNMP_PDU_ERR_INCONSISTENTVALUE
+	GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE
+	GNET_SNMP_PDU_ERR_COMMITFAILED
+	GNET_SNMP_PDU_ERR_UNDOFAILED
+	GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR
+	GNET_SNMP_PDU_ERR_NOTWRITABLE
+	GNET_SNMP_PDU_ERR_INCONSISTENTNAME
+)
+
+// Stringer for PduError
+func (pduerror PduError) String() string {
+	switch pduerror {
+	case GNET_SNMP_PDU_ERR_DONE:
+		return "GNET_SNMP_PDU_ERR_DONE"
+	case GNET_SNMP_PDU_ERR_PROCEDURE:
+		return "GNET_SNMP_PDU_ERR_PROCEDURE"
+	case GNET_SNMP_PDU_ERR_INTERNAL:
+		return "GNET_SNMP_PDU_ERR_INTERNAL"
+	case GNET_SNMP_PDU_ERR_NORESPONSE:
+		return "GNET_SNMP_PDU_ERR_NORESPONSE"
+	case GNET_SNMP_PDU_ERR_NOERROR:
+		return "GNET_SNMP_PDU_ERR_NOERROR"
+	case GNET_SNMP_PDU_ERR_TOOBIG:
+		return "GNET_SNMP_PDU_ERR_TOOBIG"
+	case GNET_SNMP_PDU_ERR_NOSUCHNAME:
+		return "GNET_SNMP_PDU_ERR_NOSUCHNAME"
+	case GNET_SNMP_PDU_ERR_BADVALUE:
+		return "GNET_SNMP_PDU_ERR_BADVALUE"
+	case GNET_SNMP_PDU_ERR_READONLY:
+		return "GNET_SNMP_PDU_ERR_READONLY"
+	case GNET_SNMP_PDU_ERR_GENERROR:
+		return "GNET_SNMP_PDU_ERR_GENERROR"
+	case GNET_SNMP_PDU_ERR_NOACCESS:
+		return "GNET_SNMP_PDU_ERR_NOACCESS"
+	case GNET_SNMP_PDU_ERR_WRONGTYPE:
+		return "GNET_SNMP_PDU_ERR_WRONGTYPE"
+	case GNET_SNMP_PDU_ERR_WRONGLENGTH:
+		return "GNET_SNMP_PDU_ERR_WRONGLENGTH"
+	case GNET_SNMP_PDU_ERR_WRONGENCODING:
+		return "GNET_SNMP_PDU_ERR_WRONGENCODING"
+	case GNET_SNMP_PDU_ERR_WRONGVALUE:
+		return "GNET_SNMP_PDU_ERR_WRONGVALUE"
+	case GNET_SNMP_PDU_ERR_NOCREATION:
+		return "GNET_SNMP_PDU_ERR_NOCREATION"
+	case GNET_SNMP_PDU_ERR_INCONSISTENTVALUE:
+		return "GNET_SNMP_PDU_ERR_INCONSISTENTVALUE"
+	case GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE:
+		return "GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE"
+	case GNET_SNMP_PDU_ERR_COMMITFAILED:
+		return "GNET_SNMP_PDU_ERR_COMMITFAILED"
+	case GNET_SNMP_PDU_ERR_UNDOFAILED:
+		return "GNET_SNMP_PDU_ERR_UNDOFAILED"
+	case GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR:
+		return "GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR"
+	case GNET_SNMP_PDU_ERR_NOTWRITABLE:
+		return "GNET_SNMP_PDU_ERR_NOTWRITABLE"
+	case GNET_SNMP_PDU_ERR_INCONSISTENTNAME:
+		return "GNET_SNMP_PDU_ERR_INCONSISTENTNAME"
+	}
+	return "UNKNOWN PduError"
+}
+
+//[[[end]]]
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"GNET_SNMP_V1", "GNET_SNMP_V2C", "GNET_SNMP_V2P", "GNET_SNMP_V3"}
+	enumconv.WriteGo("SnmpVersion", "_Ctype_GNetSnmpVersion", vals, 0)
+}
+gocog]]]*/
+
+// type and values for _Ctype_GNetSnmpVersion
+type SnmpVersion int
+
+const (
+	GNET_SNMP_V1 SnmpVersion = iota
+	GNET_SNMP_V2C
+	GNET_SNMP_V2P
+	GNET_SNMP_V3
+)
+
+// Stringer for SnmpVersion
+func (snmpversion SnmpVersion) String() string {
+	switch snmpversion {
+	case GNET_SNMP_V1:
+		return "GNET_SNMP_V1"
+	case GNET_SNMP_V2C:
+		return "GNET_SNMP_V2C"
+	case GNET_SNMP_V2P:
+		return "GNET_SNMP_V2P"
+	case GNET_SNMP_V3:
+		return "GNET_SNMP_V3"
+	}
+	return "UNKNOWN SnmpVersion"
+}
+
+//[[[end]]]
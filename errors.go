@@ -0,0 +1,118 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// errors.go contains the error types returned by Query() and friends. Use
+// errors.As to get at the details, eg:
+//
+//    var agent_err *gsnmpgo.AgentError
+//    if errors.As(err, &agent_err) && agent_err.Status == gsnmpgo.GNET_SNMP_PDU_ERR_TOOBIG {
+//        ...
+//    }
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTimeout matches any *TimeoutError, for use with errors.Is
+var ErrTimeout = errors.New(libname() + ": no response from agent")
+
+// UriError is returned when a uri (or the path of a uri) can't be parsed,
+// or has too many oids.
+type UriError struct {
+	Uri string
+	Pos int // byte offset in Uri of the problem, or -1 if unknown
+	Msg string
+}
+
+func (e *UriError) Error() string {
+	if e.Pos < 0 {
+		return fmt.Sprintf("%s: %s: <%s>", libname(), e.Msg, e.Uri)
+	}
+	return fmt.Sprintf("%s: %s at position %d: <%s>", libname(), e.Msg, e.Pos, e.Uri)
+}
+
+// SessionError is returned when a session can't be created or configured,
+// eg the host doesn't resolve or the v3 credentials are incomplete. Err is
+// the underlying error, if any.
+type SessionError struct {
+	Msg string
+	Err error
+}
+
+func (e *SessionError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %s", libname(), e.Msg, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", libname(), e.Msg)
+}
+
+func (e *SessionError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when an agent doesn't respond, after Retries
+// retries of Timeout milliseconds each.
+type TimeoutError struct {
+	Timeout int
+	Retries int
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s (timeout %dms, %d retries)", ErrTimeout, e.Timeout, e.Retries)
+}
+
+// Is makes errors.Is(err, ErrTimeout) true for a *TimeoutError
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// AgentError is returned when an agent responds with an error-status other
+// than noError, eg a SET of a read-only object.
+type AgentError struct {
+	Status PduError // the error-status
+	Index  int      // the error-index; the (1-based) varbind that caused the error
+}
+
+func (e *AgentError) Error() string {
+	return fmt.Sprintf("%s: agent returned %s at index %d", libname(), e.Status, e.Index)
+}
+
+// MibError is returned when a MIB file can't be read or parsed.
+type MibError struct {
+	File string
+	Line int // the line of the problem, or 0 if unknown
+	Msg  string
+	Err  error // the underlying error, if any
+}
+
+func (e *MibError) Error() string {
+	where := e.File
+	if e.Line > 0 {
+		where = fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %s: %s", libname(), where, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %s", libname(), where, e.Msg)
+}
+
+func (e *MibError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,82 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorsIsAs(t *testing.T) {
+	var err error = &TimeoutError{Timeout: 200, Retries: 3}
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected errors.Is(TimeoutError, ErrTimeout)")
+	}
+	wrapped := fmt.Errorf("polling router1: %w", err)
+	if !errors.Is(wrapped, ErrTimeout) {
+		t.Errorf("expected errors.Is(wrapped TimeoutError, ErrTimeout)")
+	}
+
+	err = &AgentError{Status: GNET_SNMP_PDU_ERR_NOTWRITABLE, Index: 2}
+	var agent_err *AgentError
+	if !errors.As(err, &agent_err) || agent_err.Status != GNET_SNMP_PDU_ERR_NOTWRITABLE || agent_err.Index != 2 {
+		t.Errorf("expected errors.As AgentError, got %v", agent_err)
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Errorf("expected AgentError not to be ErrTimeout")
+	}
+
+	inner := errors.New("auth password must be at least 8 characters")
+	err = &SessionError{Msg: "newUri(): invalid v3 credentials", Err: inner}
+	if !errors.Is(err, inner) {
+		t.Errorf("expected SessionError to unwrap to its Err")
+	}
+
+	_, err = validateUri("snmp://host//(1.2,1.3,1.4,1.5)", 3)
+	var uri_err *UriError
+	if !errors.As(err, &uri_err) {
+		t.Errorf("expected validateUri to return a UriError, got %T", err)
+	}
+}
+
+var queryStatusErrTests = []struct {
+	status QueryStatus
+	err    error // only the type is compared
+	ok     bool
+}{
+	{QueryStatus{Error: GNET_SNMP_PDU_ERR_NOERROR}, nil, true},
+	{QueryStatus{Error: GNET_SNMP_PDU_ERR_NORESPONSE}, &TimeoutError{}, false},
+	{QueryStatus{Error: GNET_SNMP_PDU_ERR_TOOBIG, Index: 0}, &AgentError{}, false},
+	{QueryStatus{Error: GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR, Index: 1}, &AgentError{}, false},
+	{QueryStatus{Error: GNET_SNMP_PDU_ERR_INTERNAL}, &SessionError{}, false},
+	{QueryStatus{Error: GNET_SNMP_PDU_ERR_NOERROR, Message: "host unreachable"}, &SessionError{}, false},
+}
+
+func TestQueryStatusErr(t *testing.T) {
+	params := NewDefaultParams("")
+	for i, test := range queryStatusErrTests {
+		if ok := test.status.Ok(); ok != test.ok {
+			t.Errorf("#%d: expected Ok() (%t) got (%t)", i, test.ok, ok)
+		}
+		err := test.status.err(params)
+		if fmt.Sprintf("%T", err) != fmt.Sprintf("%T", test.err) {
+			t.Errorf("#%d: expected error type (%T) got (%T)", i, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// gsnmp.go is the default backend, which does queries with gsnmp. Build
+// with the purego tag for the pure Go backend instead (see purego.go).
+//
+// gsnmp 0.3.0 can't authenticate or encrypt SNMP v3 messages, as it has no
+// USM user table to hold a user's keys, so v3 queries are done by the Go
+// client in client.go.
+//
+// glib typedefs - http://developer.gnome.org/glib/2.35/glib-Basic-Types.html
+// glib tutorial - http://www.dlhoffman.com/publiclibrary/software/gtk+-html-docs/gtk_tut-17.html
+// gsnmp sourcecode browser - http://sourcecodebrowser.com/gsnmp/0.3.0/index.html
+
+/*
+#cgo pkg-config: glib-2.0 gsnmp
+#include "c_bridge.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"code.google.com/p/tcgl/applog"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+	"unsafe"
+)
+
+// runQuery runs query() on the dispatcher worker, or the Go client for SNMP
+// v3.
+func runQuery(ctx context.Context, params *QueryParams) (results *llrb.Tree, status *QueryStatus, err error) {
+	if params.Version == GNET_SNMP_V3 {
+		return goQuery(ctx, params)
+	}
+	dispatch_err := dispatchContext(ctx, func() {
+		results, status, err = query(ctx, params)
+	})
+	if dispatch_err != nil {
+		return nil, nil, dispatch_err
+	}
+	return results, status, err
+}
+
+// runSet runs set() on the dispatcher worker, or the Go client for SNMP v3.
+func runSet(params *QueryParams, varbinds []QueryResult) (results *llrb.Tree, err error) {
+	if params.Version == GNET_SNMP_V3 {
+		return goSet(params, varbinds)
+	}
+	dispatch(func() {
+		results, err = set(params, varbinds)
+	})
+	return results, err
+}
+
+// query does the work of Query(); it must be run on the dispatcher worker.
+//
+// All C memory (the session and var bind lists) is freed before returning.
+func query(ctx context.Context, params *QueryParams) (results *llrb.Tree, status *QueryStatus, err error) {
+
+	// gsnmp can't parse the tcp and unix schemes, nor say what is wrong
+	// with a uri, so the session is built from the uri parsed in Go
+	uri, err := validateUri(params.Uri, MAX_URI_COUNT)
+	if err != nil {
+		return nil, nil, err
+	}
+	if Debug {
+		applog.Warningf("number of incoming uris: %d", len(uri.oids))
+	}
+	if len(uri.oids) == 0 {
+		return nil, nil, &UriError{Uri: params.Uri, Pos: -1, Msg: "no oids in uri"}
+	}
+	addr, err := queryAddr(ctx, params, uri)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vbl := vblFromOids(uri.oids)
+	defer vblDelete(vbl)
+	if Debug {
+		applog.Debugf("vbl, uritype: %s, %s", gListOidsString(vbl), uri.uritype)
+	}
+
+	session, err := newSession(ctx, params, addr, uri.community)
+	defer sessionDelete(session)
+	/*
+		causing <undefined symbol: gnet_snmp_taddress_get_short_name>
+		if Debug {
+			applog.Warningf("session: %s\n\n", session)
+		}
+	*/
+	if err != nil {
+		return nil, nil, err
+	}
+
+	varbinds, status, err := querySync(ctx, session, vbl, C.GNetSnmpUriType(uri.uritype), params)
+	if status != nil {
+		status.Addr = addr
+	}
+	if ctx.Err() != nil {
+		// return whatever was collected before the cancel or deadline
+		return resultsTree(params, varbinds), status, ctx.Err()
+	}
+	if err != nil {
+		return nil, status, err
+	}
+	return resultsTree(params, varbinds), status, nil
+}
+
+// set does the work of Set(); it must be run on the dispatcher worker.
+func set(params *QueryParams, varbinds []QueryResult) (results *llrb.Tree, err error) {
+	vbl, err := vblFromResults(varbinds)
+	defer vblDelete(vbl)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := validateUri(params.Uri, MAX_URI_COUNT)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := queryAddr(context.Background(), params, uri)
+	if err != nil {
+		return nil, err
+	}
+	session, err := newSession(context.Background(), params, addr, uri.community)
+	defer sessionDelete(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var gerror *C.GError
+	out := C.gnet_snmp_sync_set(session, vbl, &gerror)
+	defer vblDelete(out)
+	if err = newQueryStatus(session, &gerror).err(params); err != nil {
+		if out == nil {
+			return nil, err
+		}
+		return convertResults(params, out), err
+	}
+	return convertResults(params, out), nil
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// configureSession sets the version, timeout and retries of a session from
+// params.
+//
+// The timeout is reduced if necessary to fit the deadline of ctx.
+func configureSession(ctx context.Context, session *_Ctype_GNetSnmp, params *QueryParams) {
+	if params.Version == GNET_SNMP_V1 { // default in library is v2c
+		C.gnet_snmp_set_version(session, 0)
+	}
+	C.gnet_snmp_set_timeout(session, (_Ctype_guint)(contextTimeout(ctx, params.Timeout)))
+	C.gnet_snmp_set_retries(session, (_Ctype_guint)(params.Retries))
+}
+
+// convertResults converts C results to a Go struct.
+func convertResults(params *QueryParams, out *_Ctype_GList) (results *llrb.Tree) {
+	varbinds := goVarbinds(out)
+	if Debug {
+		applog.Warningf("number of results converted: %d", len(varbinds))
+	}
+	return resultsTree(params, varbinds)
+}
+
+// gIntArrayOid converts an oid from C array of guint32's to a Go slice
+func gIntArrayOid(oid *_Ctype_guint32, oid_len _Ctype_gsize) (result []uint32) {
+	size := int(unsafe.Sizeof(*oid))
+	length := int(oid_len)
+	gbytes := C.GoBytes(unsafe.Pointer(oid), (_Ctype_int)(size*length))
+	result = make([]uint32, length)
+	if err := binary.Read(bytes.NewBuffer(gbytes), binary.LittleEndian, result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// gListOids returns the OIDs in a GList of varbinds
+func gListOids(vbl *_Ctype_GList) (result [][]uint32) {
+	for ; vbl != nil; vbl = vbl.next {
+		data := (*C.GNetSnmpVarBind)(vbl.data)
+		result = append(result, gIntArrayOid(data.oid, data.oid_len))
+	}
+	return result
+}
+
+// goVarbinds converts a list of C varbinds to Go, in the same order.
+func goVarbinds(out *_Ctype_GList) (varbinds []QueryResult) {
+	for ; out != nil; out = out.next {
+		data := (*C.GNetSnmpVarBind)(out.data)
+		oid := OID(gIntArrayOid(data.oid, data.oid_len))
+		var value Varbinder
+
+		// convert C values to Go values
+		vbt := VarBindType(data._type)
+		switch vbt {
+
+		case GNET_SNMP_VARBIND_TYPE_NULL:
+			value = new(VBT_Null)
+
+		case GNET_SNMP_VARBIND_TYPE_OCTETSTRING:
+			value = VBT_OctetString(union_ui8v_bytes(data.value, data.value_len))
+
+		case GNET_SNMP_VARBIND_TYPE_OBJECTID:
+			guint32_ptr := union_ui32v(data.value)
+			value = VBT_ObjectID("." + gIntArrayOidString(guint32_ptr, data.value_len))
+
+		case GNET_SNMP_VARBIND_TYPE_IPADDRESS:
+			value = VBT_IPAddress(union_ui8v_ipaddress(data.value, data.value_len))
+
+		case GNET_SNMP_VARBIND_TYPE_INTEGER32:
+			value = VBT_Integer32(union_i32(data.value))
+
+		case GNET_SNMP_VARBIND_TYPE_UNSIGNED32:
+			value = VBT_Unsigned32(union_ui32(data.value))
+
+		case GNET_SNMP_VARBIND_TYPE_COUNTER32:
+			value = VBT_Counter32(union_ui32(data.value))
+
+		case GNET_SNMP_VARBIND_TYPE_TIMETICKS:
+			value = VBT_Timeticks(union_ui32(data.value))
+
+		case GNET_SNMP_VARBIND_TYPE_OPAQUE:
+			value = VBT_Opaque(union_ui8v_bytes(data.value, data.value_len))
+
+		case GNET_SNMP_VARBIND_TYPE_COUNTER64:
+			value = VBT_Counter64(union_ui64(data.value))
+
+		case GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT:
+			value = new(VBT_NoSuchObject)
+
+		case GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE:
+			value = new(VBT_NoSuchInstance)
+
+		case GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW:
+			value = new(VBT_EndOfMibView)
+
+		}
+		varbinds = append(varbinds, QueryResult{Oid: oid, Value: value})
+	}
+	return varbinds
+}
+
+// newQueryStatus returns the status of a gsnmp sync_* query, from the
+// session's error-status and error-index and the GError, which is cleared.
+func newQueryStatus(session *_Ctype_GNetSnmp, gerror **_Ctype_GError) (status *QueryStatus) {
+	status = &QueryStatus{
+		Error: PduError(session.error_status),
+		Index: int(session.error_index),
+	}
+	if *gerror != nil {
+		status.Message = C.GoString((*C.char)((*gerror).message))
+		C.g_clear_error(gerror)
+	}
+	return status
+}
+
+// newSession creates a session for the agent at addr, with community as
+// the security name.
+//
+// The timeout is reduced if necessary to fit the deadline of ctx.
+func newSession(ctx context.Context, params *QueryParams, addr net.Addr,
+	community string) (session *_Ctype_GNetSnmp, err error) {
+
+	ccommunity := C.CString(community)
+	defer C.free(unsafe.Pointer(ccommunity))
+	session = C.session_new((*C.gchar)(ccommunity))
+	if session == nil {
+		return session, &SessionError{Msg: "newSession(): unable to create session"}
+	}
+	if err = setTransport(session, addr); err != nil {
+		return session, err
+	}
+	configureSession(ctx, session, params)
+	return session, nil
+}
+
+// querySync - do an gsnmp library sync_* query
+//
+// Walks are done using GETBULK (see walk()), except for SNMP v1 which
+// doesn't have GETBULK and falls back to a series of GETNEXTs.
+//
+// The C results are converted to Go and freed.
+func querySync(ctx context.Context, session *_Ctype_GNetSnmp, vbl *_Ctype_GList,
+	uritype _Ctype_GNetSnmpUriType, params *QueryParams) ([]QueryResult, *QueryStatus, error) {
+	var gerror *C.GError
+	var out *_Ctype_GList
+
+	if Debug {
+		applog.Debugf("Starting a %s", uritype)
+	}
+	switch UriType(uritype) {
+	case GNET_SNMP_URI_GET:
+		out = C.gnet_snmp_sync_get(session, vbl, &gerror)
+	case GNET_SNMP_URI_NEXT:
+		out = C.gnet_snmp_sync_getnext(session, vbl, &gerror)
+	case GNET_SNMP_URI_WALK:
+		return walk(ctx, session, gListOids(vbl), params.Version != GNET_SNMP_V1, params)
+	default:
+		return nil, nil, &UriError{Uri: params.Uri, Pos: -1, Msg: "querySync(): unknown uritype"}
+	}
+	defer vblDelete(out)
+
+	/*
+		Originally error handling was done at this point, like
+		gsnmp-0.3.0/examples/gsnmp-get.c. However in production too many results
+		were being discarded. Hence just return out, and goVarbinds() will
+		convert any errors in out to nil values. Errors are only returned
+		when there are no results at all; QueryWithStatus() gives access
+		to the status regardless.
+	*/
+	status := newQueryStatus(session, &gerror)
+	if out == nil {
+		return nil, status, status.err(params)
+	}
+	return goVarbinds(out), status, nil
+}
+
+// sessionDelete frees the memory used by a session.
+//
+// A deferred call to sessionDelete should be made after newSession().
+func sessionDelete(session *_Ctype_GNetSnmp) {
+	if session != nil {
+		C.gnet_snmp_delete(session)
+	}
+}
+
+// setTransport sets the transport of a session to addr: udp or tcp over
+// ipv4 or ipv6, or a Unix-domain socket.
+func setTransport(session *_Ctype_GNetSnmp, addr net.Addr) error {
+	var ok C.gboolean
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		cip := C.CString(addr.IP.String())
+		defer C.free(unsafe.Pointer(cip))
+		ok = C.session_set_address(session, (*C.gchar)(cip), C.gint(addr.Port), 0)
+	case *net.TCPAddr:
+		cip := C.CString(addr.IP.String())
+		defer C.free(unsafe.Pointer(cip))
+		ok = C.session_set_address(session, (*C.gchar)(cip), C.gint(addr.Port), 1)
+	case *net.UnixAddr:
+		cpath := C.CString(addr.Name)
+		defer C.free(unsafe.Pointer(cpath))
+		ok = C.session_set_path(session, (*C.gchar)(cpath))
+	}
+	if ok == 0 {
+		return &SessionError{Msg: "setTransport(): invalid address " + addr.String()}
+	}
+	return nil
+}
+
+// vblDelete frees the memory used by a var bind list.
+//
+// A deferred call to vblDelete should be made after call to
+// gnet_snmp_sync_get (or similar).
+func vblDelete(vbl *_Ctype_GList) {
+	C.vbl_delete(vbl)
+}
+
+// vblFromOids creates a var bind list of NULL varbinds, for use as a request.
+//
+// A deferred call to vblDelete should be made on the result.
+func vblFromOids(oids [][]uint32) (vbl *_Ctype_GList) {
+	for _, oid := range oids {
+		if len(oid) == 0 {
+			continue
+		}
+		vbl = C.vbl_append_oid(vbl, (*C.guint32)(unsafe.Pointer(&oid[0])), C.gsize(len(oid)))
+	}
+	return vbl
+}
+
+// vblFromResults creates a var bind list from Go varbinds, for use as a SET
+// request.
+//
+// A deferred call to vblDelete should be made on the result.
+func vblFromResults(varbinds []QueryResult) (vbl *_Ctype_GList, err error) {
+	for _, varbind := range varbinds {
+		oid := varbind.Oid
+		if len(oid) == 0 {
+			return vbl, fmt.Errorf("%s: vblFromResults(): empty oid", libname())
+		}
+
+		var vbt VarBindType
+		var value unsafe.Pointer
+		var value_len int
+		switch v := varbind.Value.(type) {
+
+		case VBT_Integer32:
+			i32 := C.gint32(v)
+			vbt, value = GNET_SNMP_VARBIND_TYPE_INTEGER32, unsafe.Pointer(&i32)
+
+		case VBT_Unsigned32:
+			ui32 := C.guint32(v)
+			vbt, value = GNET_SNMP_VARBIND_TYPE_UNSIGNED32, unsafe.Pointer(&ui32)
+
+		case VBT_Counter32:
+			ui32 := C.guint32(v)
+			vbt, value = GNET_SNMP_VARBIND_TYPE_COUNTER32, unsafe.Pointer(&ui32)
+
+		case VBT_Timeticks:
+			ui32 := C.guint32(v)
+			vbt, value = GNET_SNMP_VARBIND_TYPE_TIMETICKS, unsafe.Pointer(&ui32)
+
+		case VBT_Counter64:
+			ui64 := C.guint64(v)
+			vbt, value = GNET_SNMP_VARBIND_TYPE_COUNTER64, unsafe.Pointer(&ui64)
+
+		case VBT_OctetString:
+			vbt = GNET_SNMP_VARBIND_TYPE_OCTETSTRING
+			if value_len = len(v); value_len > 0 {
+				octets := []byte(v)
+				value = unsafe.Pointer(&octets[0])
+			}
+
+		case VBT_IPAddress:
+			ip := net.ParseIP(string(v)).To4()
+			if ip == nil {
+				return vbl, fmt.Errorf("%s: vblFromResults(): invalid ip address %s for oid %s",
+					libname(), v, varbind.Oid)
+			}
+			vbt, value, value_len = GNET_SNMP_VARBIND_TYPE_IPADDRESS, unsafe.Pointer(&ip[0]), len(ip)
+
+		case VBT_ObjectID:
+			value_oid, err := parseOid(string(v))
+			if err != nil {
+				return vbl, err
+			}
+			vbt, value, value_len = GNET_SNMP_VARBIND_TYPE_OBJECTID, unsafe.Pointer(&value_oid[0]), len(value_oid)
+
+		default:
+			return vbl, fmt.Errorf("%s: vblFromResults(): unsupported type %T for oid %s",
+				libname(), varbind.Value, varbind.Oid)
+		}
+
+		vbl = C.vbl_append_value(vbl, (*C.guint32)(unsafe.Pointer(&oid[0])), C.gsize(len(oid)),
+			C.GNetSnmpVarBindType(vbt), C.gpointer(value), C.gsize(value_len))
+	}
+	return vbl, nil
+}
+
+// walk walks each of oids with walkOids(), using GETBULK requests if bulk
+// is true or GETNEXT requests otherwise (SNMP v1). The timeout of each
+// request is reduced to fit ctx's deadline.
+//
+// As with querySync(), an error is only returned if there are no results.
+func walk(ctx context.Context, session *_Ctype_GNetSnmp, oids [][]uint32, bulk bool,
+	params *QueryParams) (varbinds []QueryResult, status *QueryStatus, err error) {
+	fetch := func(request [][]uint32, nonrep, maxrep int) ([]QueryResult, *QueryStatus, error) {
+		vbl := vblFromOids(request)
+		defer vblDelete(vbl)
+		var gerror *C.GError
+		var response *_Ctype_GList
+		C.gnet_snmp_set_timeout(session, C.guint(contextTimeout(ctx, params.Timeout)))
+		if bulk {
+			response = C.gnet_snmp_sync_getbulk(session, vbl, C.guint32(nonrep), C.guint32(maxrep), &gerror)
+		} else {
+			response = C.gnet_snmp_sync_getnext(session, vbl, &gerror)
+		}
+		defer vblDelete(response)
+		status := newQueryStatus(session, &gerror)
+		if response == nil || !status.Ok() {
+			// an error response echoes the request, it has no results
+			return nil, status, status.err(params)
+		}
+		return goVarbinds(response), status, nil
+	}
+	varbinds, status, err = walkOids(ctx, oids, bulk, params.Nonrep, params.Maxrep, fetch)
+	if len(varbinds) > 0 {
+		err = nil
+	}
+	return varbinds, status, err
+}
@@ -0,0 +1,109 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// tests for the gsnmp backend
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var vblFromResultsErrorTests = []QueryResult{
+	{Oid: OID{}, Value: VBT_Integer32(1)},
+	{Oid: MustParseOID("1.3.6.1.4.1.2680.1.2.7.3.2.0"), Value: VBT_IPAddress("192.168.1")},
+	{Oid: MustParseOID("1.3.6.1.4.1.2680.1.2.7.3.2.0"), Value: VBT_ObjectID("")},
+	{Oid: MustParseOID("1.3.6.1.4.1.2680.1.2.7.3.2.0"), Value: new(VBT_NoSuchObject)},
+}
+
+func TestVblFromResultsErrors(t *testing.T) {
+	for i, test := range vblFromResultsErrorTests {
+		vbl, err := vblFromResults([]QueryResult{test})
+		vblDelete(vbl)
+		if err == nil {
+			t.Errorf("#%d: expected error for oid (%s) value (%#v)", i, test.Oid, test.Value)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var calls, running, max_running int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dispatch(func() {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&max_running)
+					if n <= m || atomic.CompareAndSwapInt32(&max_running, m, n) {
+						break
+					}
+				}
+				atomic.AddInt32(&calls, 1)
+				atomic.AddInt32(&running, -1)
+			})
+		}()
+	}
+	wg.Wait()
+	if calls != 100 {
+		t.Errorf("expected 100 calls, got %d", calls)
+	}
+	if max_running > 1 {
+		t.Errorf("expected calls to be serialized, got %d concurrent calls", max_running)
+	}
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("expected panic to be passed to caller, got %v", r)
+		}
+	}()
+	dispatch(func() { panic("boom") })
+}
+
+func TestDispatchContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var ran bool
+	if err := dispatchContext(ctx, func() { ran = true }); err != context.Canceled || ran {
+		t.Errorf("expected cancelled dispatch not to run, got err (%v) ran (%t)", err, ran)
+	}
+
+	// keep the worker busy, so the next dispatch has to wait
+	release := make(chan bool)
+	var started sync.WaitGroup
+	started.Add(1)
+	go dispatch(func() {
+		started.Done()
+		<-release
+	})
+	started.Wait()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := dispatchContext(ctx, func() { ran = true }); err != context.DeadlineExceeded || ran {
+		t.Errorf("expected dispatch to time out waiting for the worker, got err (%v) ran (%t)", err, ran)
+	}
+	close(release)
+}
@@ -17,28 +17,26 @@ package gsnmpgo
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// glib typedefs - http://developer.gnome.org/glib/2.35/glib-Basic-Types.html
-// glib tutorial - http://www.dlhoffman.com/publiclibrary/software/gtk+-html-docs/gtk_tut-17.html
-// gsnmp sourcecode browser - http://sourcecodebrowser.com/gsnmp/0.3.0/index.html
-
-/*
-#cgo pkg-config: glib-2.0 gsnmp
-#include "c_bridge.h"
-*/
-import "C"
-
 import (
 	"code.google.com/p/tcgl/applog"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/petar/GoLLRB/llrb"
+	"io"
+	"net"
+	"os"
 	"strconv"
 	"strings"
-	"unsafe"
+	"time"
 )
 
 // the maximum number of paths that can be in a single uri
 const MAX_URI_COUNT = 50
 
+// the maximum number of sub-identifiers in an oid (RFC 2578 3.5)
+const MAX_OID_LEN = 128
+
 var Debug bool // global debugging flag
 
 // Struct of parameters to pass to Query
@@ -47,160 +45,180 @@ type QueryParams struct {
 	Version SnmpVersion
 	Timeout int // timeout in milliseconds
 	Retries int // number of retries
-	// Nonrep and Maxrep will be used by v2c BULK GETs.
+	// Nonrep and Maxrep are used by v2c walks, which are done with GETBULK.
 	// From O'Reilly "Essential SNMP": "nonrep is the number of scalar
 	// objects that this command will return; rep is the number of
 	// instances of each nonscalar object that the command will return."
+	// The first Nonrep oids of a walk are only retrieved once.
 	Nonrep int
 	Maxrep int
+	// Usm holds the SNMP v3 credentials; it is required when Version is
+	// GNET_SNMP_V3 and ignored otherwise.
+	Usm *UsmParams
+	// Resolver resolves the host of the uri; DefaultResolver is used if it
+	// is nil.
+	Resolver *Resolver
+	// Transport is the transport used for snmp:// uris, TRANSPORT_UDP by
+	// default; snmp+tcp:// and snmp+unix:// uris select TCP and Unix-domain
+	// sockets themselves.
+	Transport Transport
 	// if Tree is non-nil, it will be used for appending Query()
 	// results eg when doing two GETs in a row
 	Tree *llrb.Tree
 }
 
+// QueryStatus is the outcome of a query, as reported by the agent and gsnmp.
+//
+// A query can return results and still have failed part way, eg a walk
+// that gets a tooBig response after a number of successful GETBULKs.
+type QueryStatus struct {
+	Error   PduError // the agent's error-status, or eg GNET_SNMP_PDU_ERR_NORESPONSE
+	Index   int      // the agent's error-index; the (1-based) varbind in error
+	Message string   // the GError message from gsnmp, if any
+	// Addr is the transport address the query was sent to, ie the host of
+	// the uri as resolved by the Resolver: a *net.UDPAddr, *net.TCPAddr or
+	// *net.UnixAddr.
+	Addr net.Addr
+}
+
+// Ok returns true if the query completed without any error.
+func (s *QueryStatus) Ok() bool {
+	return s.Error == GNET_SNMP_PDU_ERR_NOERROR && s.Message == ""
+}
+
+// err converts a status into one of the typed errors in errors.go, or nil.
+func (s *QueryStatus) err(params *QueryParams) error {
+	switch {
+	case s.Error == GNET_SNMP_PDU_ERR_NORESPONSE:
+		return &TimeoutError{Timeout: params.Timeout, Retries: params.Retries}
+	case s.Error > GNET_SNMP_PDU_ERR_NOERROR:
+		return &AgentError{Status: s.Error, Index: s.Index}
+	case s.Error < GNET_SNMP_PDU_ERR_NOERROR:
+		return &SessionError{Msg: strings.TrimSpace(s.Error.String() + " " + s.Message)}
+	case s.Message != "":
+		return &SessionError{Msg: s.Message}
+	}
+	return nil
+}
+
 // A single result, used as an Item in the llrb tree
 type QueryResult struct {
-	Oid   string
+	Oid   OID
 	Value Varbinder
 }
 
 // Query takes a URI in RFC 4088 format, does an SNMP query and returns the results.
+//
+// Errors are one of *UriError, *SessionError, *TimeoutError or *AgentError
+// (see errors.go).
+//
+// Query can be called concurrently from any number of goroutines; with the
+// gsnmp backend the calls into gsnmp are serialized on the dispatcher
+// goroutine (see dispatch.go).
 func Query(params *QueryParams) (results *llrb.Tree, err error) {
+	results, _, err = QueryWithStatus(params)
+	return results, err
+}
 
-	parsed_uri, err := parseURI(params.Uri)
-	if Debug {
-		applog.Debugf("parsed_uri: %s\n\n", parsed_uri)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	path := C.GoString((*C.char)(parsed_uri.path))
-	if Debug {
-		applog.Warningf("number of incoming uris: %d", uriCount(path))
-	}
-	if err := uriCountMaxed(path, MAX_URI_COUNT); err != nil {
-		return nil, err
-	}
-
-	vbl, uritype, err := parsePath(params.Uri, parsed_uri)
-	defer uriDelete(parsed_uri)
-	if Debug {
-		applog.Debugf("vbl, uritype: %s, %s", gListOidsString(vbl), uritype)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	session, err := newUri(params, parsed_uri)
-	/*
-		causing <undefined symbol: gnet_snmp_taddress_get_short_name>
-		if Debug {
-			applog.Warningf("session: %s\n\n", session)
-		}
-	*/
-	if err != nil {
-		return nil, err
+// QueryWithStatus is like Query, but also returns the status of the query.
+//
+// Unlike Query, partial results are returned along with a status describing
+// why the query stopped (eg an agent error-status of tooBig, and the index
+// of the varbind at fault). status is nil if the query wasn't sent, eg the
+// uri couldn't be parsed.
+func QueryWithStatus(params *QueryParams) (results *llrb.Tree, status *QueryStatus, err error) {
+	if params, err = mibParams(params); err != nil {
+		return nil, nil, err
 	}
+	return runQuery(context.Background(), params)
+}
 
-	vbl_results, err := querySync(session, vbl, uritype, params.Version)
-	defer vblDelete(vbl_results)
-	if err != nil {
+// QueryContext is like Query, but stops when ctx is cancelled or its
+// deadline passes.
+//
+// Walks are stopped between requests, and the results collected so far are
+// returned along with ctx.Err(). The timeout of each request is reduced so
+// it doesn't run past the deadline.
+func QueryContext(ctx context.Context, params *QueryParams) (results *llrb.Tree, err error) {
+	if params, err = mibParams(params); err != nil {
 		return nil, err
 	}
-	return convertResults(params, vbl_results), nil
+	results, _, err = runQuery(ctx, params)
+	return results, err
 }
 
-// ------------------- other functions in alphabetical order --------------------
-
-// convertResults converts C results to a Go struct.
-func convertResults(params *QueryParams, out *_Ctype_GList) (results *llrb.Tree) {
-
-	var out_count int
+// Set does an SNMP SET of varbinds, and returns the agent's response.
+//
+// The target and community are taken from params.Uri, any path in the uri
+// is ignored (eg snmp://private@192.168.1.10). The value of each varbind
+// must be one of VBT_Integer32, VBT_OctetString, VBT_IPAddress,
+// VBT_ObjectID, VBT_Unsigned32, VBT_Counter32, VBT_Timeticks or
+// VBT_Counter64.
+//
+// If the agent rejects the SET, an *AgentError is returned with the
+// agent's error-status and error-index, along with the varbinds of its
+// response.
+func Set(params *QueryParams, varbinds []QueryResult) (results *llrb.Tree, err error) {
+	return runSet(params, varbinds)
+}
 
-	// create or re-use an existing llrb Tree
-	if params.Tree == nil {
+// GetMany does an SNMP GET of any number of oids, and returns all the
+// results in one tree.
+//
+// The target and community are taken from params.Uri, and any oids in it
+// are ignored (eg snmp://public@192.168.1.10). The oids are requested
+// MAX_URI_COUNT at a time; when the agent answers tooBig the requests are
+// halved in size until they fit. An error stops the GETs, and is returned
+// with the results so far.
+func GetMany(params *QueryParams, oids []string) (results *llrb.Tree, err error) {
+	results = params.Tree
+	if results == nil {
 		results = llrb.New(LessOID)
-	} else {
-		results = params.Tree
 	}
-
-	for {
-		if out == nil {
-			// finished
-			if Debug {
-				applog.Warningf("number of results converted: %d", out_count)
-			}
-			return results
+	err = getMany(oids, results, func(batch []string) (*llrb.Tree, error) {
+		batch_params := *params
+		batch_params.Tree = nil
+		batch_uri, err := uriWithOids(params.Uri, batch)
+		if err != nil {
+			return nil, err
 		}
+		batch_params.Uri = batch_uri
+		return Query(&batch_params)
+	})
+	return results, err
+}
 
-		// another result: initialise
-		out_count++
-		data := (*C.GNetSnmpVarBind)(out.data)
-		oid := gIntArrayOidString(data.oid, data.oid_len)
-		var value Varbinder
-
-		// convert C values to Go values
-		vbt := VarBindType(data._type)
-		switch vbt {
-
-		case GNET_SNMP_VARBIND_TYPE_NULL:
-			value = new(VBT_Null)
-
-		case GNET_SNMP_VARBIND_TYPE_OCTETSTRING:
-			value = VBT_OctetString(union_ui8v_string(data.value, data.value_len))
-
-		case GNET_SNMP_VARBIND_TYPE_OBJECTID:
-			guint32_ptr := union_ui32v(data.value)
-			value = VBT_ObjectID("." + gIntArrayOidString(guint32_ptr, data.value_len))
-
-		case GNET_SNMP_VARBIND_TYPE_IPADDRESS:
-			value = VBT_IPAddress(union_ui8v_ipaddress(data.value, data.value_len))
-
-		case GNET_SNMP_VARBIND_TYPE_INTEGER32:
-			value = VBT_Integer32(union_i32(data.value))
-
-		case GNET_SNMP_VARBIND_TYPE_UNSIGNED32:
-			value = VBT_Unsigned32(union_ui32(data.value))
-
-		case GNET_SNMP_VARBIND_TYPE_COUNTER32:
-			value = VBT_Counter32(union_ui32(data.value))
-
-		case GNET_SNMP_VARBIND_TYPE_TIMETICKS:
-			value = VBT_Timeticks(union_ui32(data.value))
-
-		case GNET_SNMP_VARBIND_TYPE_OPAQUE:
-			value = VBT_Opaque(union_ui8v_hexstring(data.value, data.value_len))
-
-		case GNET_SNMP_VARBIND_TYPE_COUNTER64:
-			value = VBT_Counter64(union_ui64(data.value))
-
-		case GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT:
-			value = new(VBT_NoSuchObject)
-
-		case GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE:
-			value = new(VBT_NoSuchInstance)
-
-		case GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW:
-			value = new(VBT_EndOfMibView)
-
-		}
-		result := QueryResult{Oid: oid, Value: value}
-		results.ReplaceOrInsert(result)
+// ------------------- other functions in alphabetical order --------------------
 
-		// move on to next element in list
-		out = out.next
+// contextTimeout returns timeout (in milliseconds), reduced if necessary so
+// that a request doesn't run past the deadline of ctx.
+func contextTimeout(ctx context.Context, timeout int) int {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
 	}
-	panic(fmt.Sprintf("%s: convertResults(): fell out of for loop", libname()))
+	remaining := int(time.Until(deadline) / time.Millisecond)
+	if remaining < 1 {
+		remaining = 1
+	}
+	if timeout <= 0 || remaining < timeout {
+		return remaining
+	}
+	return timeout
 }
 
 // Dump is a convenience function for printing the results of a Query.
 func Dump(results *llrb.Tree) {
+	Fdump(os.Stdout, results)
+}
+
+// Fdump prints the results of a Query to w, in the same format as Dump.
+func Fdump(w io.Writer, results *llrb.Tree) {
 	if results == nil {
-		fmt.Println("Dump: results are NIL")
+		fmt.Fprintln(w, "Dump: results are NIL")
 		return
 	}
-	fmt.Println("Dump:")
+	fmt.Fprintln(w, "Dump:")
 	ch := results.IterAscend()
 	for {
 		r := <-ch
@@ -208,45 +226,58 @@ func Dump(results *llrb.Tree) {
 			break
 		}
 		result := r.(QueryResult)
-		fmt.Printf("oid, type: %s, %T\n", result.Oid, result.Value)
-		fmt.Printf("INTEGER: %d\n", result.Value.Integer())
-		fmt.Printf("STRING : %s\n", result.Value)
-		fmt.Println()
+		fmt.Fprintf(w, "oid, type: %s, %T\n", result.Oid, result.Value)
+		if Mib != nil {
+			fmt.Fprintf(w, "NAME   : %s\n", Mib.Name(result.Oid))
+		}
+		fmt.Fprintf(w, "INTEGER: %d\n", result.Value.Integer())
+		fmt.Fprintf(w, "STRING : %s\n", result.Value)
+		if Mib != nil {
+			fmt.Fprintf(w, "VALUE  : %s\n", Mib.Format(result.Oid, result.Value))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// getMany GETs oids in batches with get, adding the results to results.
+// Batches start at MAX_URI_COUNT oids, and are halved whenever the agent
+// answers tooBig.
+func getMany(oids []string, results *llrb.Tree, get func(batch []string) (*llrb.Tree, error)) error {
+	size := MAX_URI_COUNT
+	for len(oids) > 0 {
+		if size > len(oids) {
+			size = len(oids)
+		}
+		batch, err := get(oids[:size])
+		var agent_err *AgentError
+		if errors.As(err, &agent_err) && agent_err.Status == GNET_SNMP_PDU_ERR_TOOBIG && size > 1 {
+			size = (size + 1) / 2
+			if Debug {
+				applog.Debugf("%s: getMany(): tooBig, retrying with %d oids", libname(), size)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		ch := batch.IterAscend()
+		for {
+			r := <-ch
+			if r == nil {
+				break
+			}
+			results.ReplaceOrInsert(r)
+		}
+		oids = oids[size:]
 	}
+	return nil
 }
 
 // LessOID is the LessFunc for GoLLRB
 //
 // It returns true if oid a is less than oid b.
 func LessOID(astruct, bstruct interface{}) bool {
-	a := astruct.(QueryResult).Oid
-	b := bstruct.(QueryResult).Oid
-
-	if a == "" && b == "" {
-		return false
-	} else if a == "" {
-		return true
-	} else if b == "" {
-		return false
-	}
-
-	a_splits := strings.Split(a, ".")
-	b_splits := strings.Split(b, ".")
-	len_b := len(b_splits)
-
-	for i, a_digit := range a_splits {
-		if i > len_b-1 {
-			return false
-		}
-		a_num, _ := strconv.Atoi(a_digit)
-		b_num, _ := strconv.Atoi(b_splits[i])
-		if a_num < b_num {
-			return true
-		} else if i == len_b-1 {
-			return false
-		}
-	}
-	return true
+	return astruct.(QueryResult).Oid.Compare(bstruct.(QueryResult).Oid) < 0
 }
 
 // libname returns the name of this library, for generating error messages.
@@ -265,83 +296,73 @@ func NewDefaultParams(uri string) *QueryParams {
 		// objects that this command will return; rep is the number of
 		// instances of each nonscalar object that the command will return. If
 		// you omit this option the default values of nonrep and rep, 1 and
-		// 100, respectively, will be used." So use these defaults for the
-		// moment.
-		Nonrep: 1,
+		// 100, respectively, will be used." However a nonrep of 1 would make
+		// the first oid of every walk a scalar; use 0 like net-snmp's
+		// snmpbulkwalk does.
+		Nonrep: 0,
 		Maxrep: 100,
 	}
 }
 
-// newUri creates a session from a parsed uri.
-func newUri(params *QueryParams, parsed_uri *_Ctype_GURI) (session *_Ctype_GNetSnmp, err error) {
-
-	var gerror *C.GError
-	session = C.gnet_snmp_new_uri(parsed_uri, &gerror)
-
-	// error handling
-	if gerror != nil {
-		err_string := C.GoString((*_Ctype_char)(gerror.message))
-		C.g_clear_error(&gerror)
-		return session, fmt.Errorf("%s: newUri(): %s", libname(), err_string)
-	}
-	if session == nil {
-		return session, fmt.Errorf("%s: newUri(): unable to create session", libname())
+// Converts an OID in a slice of int format (eg []int{1, 3, 6, 1} to a string.
+func OidAsString(o []int) string {
+	if len(o) == 0 {
+		return ""
 	}
+	result := fmt.Sprintf("%v", o)
+	result = result[1 : len(result)-1] // strip [ ] of Array representation
+	return "." + strings.Join(strings.Split(result, " "), ".")
+}
 
-	if params.Version == GNET_SNMP_V1 { // default in library is v2c
-		C.gnet_snmp_set_version(session, 0)
+// oidCompare returns -1, 0 or 1 depending on whether oid a is less than,
+// equal to or greater than oid b.
+func oidCompare(a, b []uint32) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
 	}
-	C.gnet_snmp_set_timeout(session, (_Ctype_guint)(params.Timeout))
-	C.gnet_snmp_set_retries(session, (_Ctype_guint)(params.Timeout))
-
-	return session, nil
+	if len(a) < len(b) {
+		return -1
+	} else if len(a) > len(b) {
+		return 1
+	}
+	return 0
 }
 
-// parsePath parses an SNMP URI.
-//
-// The uritype will default to GNET_SNMP_URI_GET. If the uri ends in:
+// oidInSubtree returns true if oid is below root in the oid tree.
 //
-// '*' the uritype will be GNET_SNMP_URI_WALK
-//
-// '+' the uritype will be GNET_SNMP_URI_NEXT
-//
-// See RFC 4088 "Uniform Resource Identifier (URI) Scheme for the Simple
-// Network Management Protocol (SNMP)" for further documentation.
-func parsePath(uri string, parsed_uri *_Ctype_GURI) (vbl *_Ctype_GList, uritype _Ctype_GNetSnmpUriType, err error) {
-	var gerror *C.GError
-	rv := C.gnet_snmp_parse_path(parsed_uri.path, &vbl, &uritype, &gerror)
-	if rv == 0 {
-		err_string := C.GoString((*_Ctype_char)(gerror.message))
-		return vbl, uritype, fmt.Errorf("%s: parsePath(): %s: <%s>", libname(), err_string, uri)
+// For example 1.3.6.1.2.1.2.2.1.2.1 is in the subtree of 1.3.6.1.2.1.2.2,
+// but 1.3.6.1.2.1.2.2 and 1.3.6.1.2.1.31 are not.
+func oidInSubtree(root, oid []uint32) bool {
+	if len(oid) <= len(root) {
+		return false
 	}
-	return vbl, uritype, nil
+	for i, sub := range root {
+		if oid[i] != sub {
+			return false
+		}
+	}
+	return true
 }
 
-// parseURI parses an SNMP URI into fields.
-//
-// The generic URI parsing is done by gnet_uri_new(), and the SNMP specific
-// portions by gnet_snmp_parse_uri(). Only basic URI validation is done here,
-// more is done by parsePath()
-//
-// Example:
-//
-//    uri := `snmp://public@192.168.1.10//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.2.0)`
-//    parsed_uri, err := gsnmpgo.parseURI(uri)
-//    if err != nil {
-//    	fmt.Println(err)
-//    	os.Exit(1)
-//    }
-//    fmt.Println("parseURI():", parsed_uri)
-func parseURI(uri string) (parsed_uri *_Ctype_GURI, err error) {
-	curi := (*C.gchar)(C.CString(uri))
-	defer C.free(unsafe.Pointer(curi))
-
-	var gerror *C.GError
-	parsed_uri = C.gnet_snmp_parse_uri(curi, &gerror)
-	if parsed_uri == nil {
-		return nil, fmt.Errorf("%s: parseURI(): invalid snmp uri: %s", libname(), uri)
+// parseOid converts an oid in dotted string format (with or without a leading
+// dot) to a slice of uint32's.
+func parseOid(oid string) (result []uint32, err error) {
+	oid = strings.TrimPrefix(oid, ".")
+	if oid == "" {
+		return nil, fmt.Errorf("%s: parseOid(): empty oid", libname())
+	}
+	for _, sub := range strings.Split(oid, ".") {
+		n, err := strconv.ParseUint(sub, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parseOid(): invalid oid: %s", libname(), oid)
+		}
+		result = append(result, uint32(n))
 	}
-	return parsed_uri, nil
+	return result, nil
 }
 
 // PartitionAllP - returns true when dividing a slice into
@@ -374,75 +395,40 @@ func PartitionAllP(current_position, partition_size, slice_length int) bool {
 	return false
 }
 
-// querySync - do an gsnmp library sync_* query
-//
-// Results are returned in C form, use convertResults() to convert to a Go struct.
-func querySync(session *_Ctype_GNetSnmp, vbl *_Ctype_GList, uritype _Ctype_GNetSnmpUriType,
-	version SnmpVersion) (*_Ctype_GList, error) {
-	var gerror *C.GError
-	var out *_Ctype_GList
-
-	if Debug {
-		applog.Debugf("Starting a %s", uritype)
+// resultsTree puts varbinds in params.Tree, or a new tree if it's nil.
+func resultsTree(params *QueryParams, varbinds []QueryResult) *llrb.Tree {
+	results := params.Tree
+	if results == nil {
+		results = llrb.New(LessOID)
 	}
-	switch UriType(uritype) {
-	case GNET_SNMP_URI_GET:
-		out = C.gnet_snmp_sync_get(session, vbl, &gerror)
-	case GNET_SNMP_URI_NEXT:
-		out = C.gnet_snmp_sync_getnext(session, vbl, &gerror)
-	case GNET_SNMP_URI_WALK:
-		out = C.gnet_snmp_sync_walk(session, vbl, &gerror)
-		/* TODO gnet_snmp_sync_walk is just a series of 'getnexts'
-		if version == GNET_SNMP_V1 {
-			out = C.gnet_snmp_sync_walk(session, vbl, &gerror)
-		} else {
-			// do a proper bulkwalk
-		}
-		*/
-	default:
-		return nil, fmt.Errorf("%s: querySync(): unknown uritype", libname())
+	for _, varbind := range varbinds {
+		results.ReplaceOrInsert(varbind)
 	}
-
-	/*
-		Originally error handling was done at this point, like
-		gsnmp-0.3.0/examples/gsnmp-get.c. However in production too many results
-		were being discarded. Hence just return out, and convertResults() will
-		convert any errors in out to nil values.
-	*/
-
-	return out, nil
+	return results
 }
 
-// uriCount returns a count of the number of uri's in the path
-func uriCount(path string) int {
-	left_paren := strings.Index(path, "(")
-	right_paren := strings.Index(path, ")")
-	if left_paren < 0 || right_paren < 0 {
-		return -1
+// uriWithOids returns uri with its oids replaced by a GET of oids, keeping
+// the transport, community, host, port and context, eg
+// snmp://public@192.168.1.10//(1.3.6.1.2.1.1.1.0). Names can be used in
+// uri and oids if Mib is set.
+func uriWithOids(uri string, oids []string) (string, error) {
+	var err error
+	if Mib != nil {
+		if uri, err = Mib.translateUri(uri); err != nil {
+			return "", err
+		}
 	}
-	uris := path[left_paren+1 : right_paren]
-	return len(strings.Split(uris, ","))
-}
-
-// uriCountMaxed returns an error if there are more uri's in path than max
-func uriCountMaxed(path string, max int) (err error) {
-	if uri_count := uriCount(path); uri_count > max {
-		return fmt.Errorf("number of uris is greater than max (%d/%d) in path %s", uri_count, max, path)
+	parsed, err := parseSnmpUri(uri)
+	if err != nil {
+		return "", err
 	}
-	return nil
-}
-
-// uriDelete frees the memory used by a parsed_uri.
-//
-// A defered call to uriDelete should be made after parsePath().
-func uriDelete(parsed_uri *_Ctype_GURI) {
-	C.gnet_uri_delete(parsed_uri)
-}
-
-// vblDelete frees the memory used by a var bind list.
-//
-// A deferred call to vblDelete should be made after call to
-// gnet_snmp_sync_get (or similar).
-func vblDelete(vbl *_Ctype_GList) {
-	C.vbl_delete(vbl)
+	parsed.oids, parsed.uritype = nil, GNET_SNMP_URI_GET
+	for _, name := range oids {
+		oid, err := parseOidName(name)
+		if err != nil {
+			return "", &UriError{Uri: uri, Pos: -1, Msg: "invalid oid " + name}
+		}
+		parsed.oids = append(parsed.oids, oid)
+	}
+	return parsed.String(), nil
 }
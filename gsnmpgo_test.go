@@ -18,10 +18,16 @@ package gsnmpgo
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/petar/GoLLRB/llrb"
+	"net"
+	"os"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 var _ = fmt.Sprintf("dummy") // dummy
@@ -64,8 +70,10 @@ var lessOIDTests = []struct {
 
 func TestLessOID(t *testing.T) {
 	for i, test := range lessOIDTests {
-		astruct := QueryResult{Oid: test.oid_a}
-		bstruct := QueryResult{Oid: test.oid_b}
+		oid_a, _ := ParseOID(test.oid_a) // "" is an empty OID
+		oid_b, _ := ParseOID(test.oid_b)
+		astruct := QueryResult{Oid: oid_a}
+		bstruct := QueryResult{Oid: oid_b}
 		if res := LessOID(astruct, bstruct); res != test.less {
 			t.Errorf("#%d: expected (%t) got (%t) oid_a (%s) oid_b (%s)",
 				i, test.less, res, test.oid_a, test.oid_b)
@@ -73,22 +81,110 @@ func TestLessOID(t *testing.T) {
 	}
 }
 
-var veraxDevices = []struct {
-	path string
-	port int
+var oidInSubtreeTests = []struct {
+	root []uint32
+	oid  []uint32
+	ok   bool
 }{
-	{"testing/device/os/os-linux-std.txt", 161},
-	{"testing/device/cisco/cisco_router.txt", 162},
+	{[]uint32{1, 3, 6, 1, 2, 1, 2, 2}, []uint32{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 1}, true},
+	{[]uint32{1, 3, 6, 1, 2, 1, 2, 2}, []uint32{1, 3, 6, 1, 2, 1, 2, 2}, false},  // same oid
+	{[]uint32{1, 3, 6, 1, 2, 1, 2, 2}, []uint32{1, 3, 6, 1, 2, 1, 2}, false},     // parent
+	{[]uint32{1, 3, 6, 1, 2, 1, 2, 2}, []uint32{1, 3, 6, 1, 2, 1, 31, 1}, false}, // next subtree
+	{[]uint32{}, []uint32{1, 3}, true},
 }
 
-func TestQueryGets(t *testing.T) {
-	for i, test := range veraxDevices {
-		var err error
+func TestOidInSubtree(t *testing.T) {
+	for i, test := range oidInSubtreeTests {
+		if ok := oidInSubtree(test.root, test.oid); ok != test.ok {
+			t.Errorf("#%d: expected (%t) got (%t) root (%v) oid (%v)", i, test.ok, ok, test.root, test.oid)
+		}
+	}
+}
+
+var oidCompareTests = []struct {
+	a      []uint32
+	b      []uint32
+	result int
+}{
+	{[]uint32{1, 2, 3}, []uint32{1, 2, 4}, -1},
+	{[]uint32{1, 2, 3}, []uint32{1, 2, 3, 4}, -1},
+	{[]uint32{1, 2, 3}, []uint32{1, 2, 3}, 0},
+	{[]uint32{1, 10}, []uint32{1, 9}, 1},
+	{[]uint32{1, 2, 3, 1}, []uint32{1, 2, 3}, 1},
+}
+
+func TestOidCompare(t *testing.T) {
+	for i, test := range oidCompareTests {
+		if result := oidCompare(test.a, test.b); result != test.result {
+			t.Errorf("#%d: expected (%d) got (%d) a (%v) b (%v)", i, test.result, result, test.a, test.b)
+		}
+	}
+}
+
+var parseOidTests = []struct {
+	in  string
+	out []uint32
+	ok  bool
+}{
+	{"1.3.6.1.2.1.1.1.0", []uint32{1, 3, 6, 1, 2, 1, 1, 1, 0}, true},
+	{".1.3.6.1.4.1.2680", []uint32{1, 3, 6, 1, 4, 1, 2680}, true},
+	{"1.3.6.1.4.1.4294967295", []uint32{1, 3, 6, 1, 4, 1, 4294967295}, true},
+	{"1.3.6.1.4.1.4294967296", nil, false}, // too large
+	{"1.3..6", nil, false},
+	{"1.3.x", nil, false},
+	{"", nil, false},
+	{".", nil, false},
+}
 
-		var vresults *llrb.Tree
-		if vresults, err = ReadVeraxResults(test.path); err != nil {
-			t.Errorf("#%d, %s: ReadVeraxResults error: %s", i, test.path, err)
+func TestParseOid(t *testing.T) {
+	for i, test := range parseOidTests {
+		out, err := parseOid(test.in)
+		if (err == nil) != test.ok {
+			t.Errorf("#%d: expected ok (%t) got err (%v) in (%s)", i, test.ok, err, test.in)
+		} else if test.ok && oidCompare(out, test.out) != 0 {
+			t.Errorf("#%d: expected (%v) got (%v)", i, test.out, out)
 		}
+	}
+}
+
+// veraxDevices are snapshots of devices in Verax format. Each is served
+// by an Agent on a loopback port, so the Verax simulator isn't needed; the
+// simulator's device files are also tested if they're under testing/device.
+var veraxDevices = []string{
+	"testing/snapshot/os-linux-std.txt",
+	"testing/device/os/os-linux-std.txt",
+	"testing/device/cisco/cisco_router.txt",
+}
+
+// veraxAgent starts an Agent serving the Verax device file path, and returns
+// its results and port. ok is false if path doesn't exist. Each of configure
+// is called with the Agent before it starts listening, to set its fields.
+func veraxAgent(t *testing.T, path string, configure ...func(*Agent)) (vresults *llrb.Tree, agent *Agent, port int, ok bool) {
+	if _, err := os.Stat(path); err != nil {
+		t.Logf("%s: skipping, %s", path, err)
+		return nil, nil, 0, false
+	}
+	vresults, err := ReadVeraxResults(path)
+	if err != nil {
+		t.Fatalf("%s: ReadVeraxResults error: %s", path, err)
+	}
+	agent = NewAgent(vresults)
+	for _, f := range configure {
+		f(agent)
+	}
+	if err = agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("%s: Listen error: %s", path, err)
+	}
+	return vresults, agent, agent.Addr().(*net.UDPAddr).Port, true
+}
+
+func TestQueryGets(t *testing.T) {
+	for i, path := range veraxDevices {
+		vresults, agent, port, ok := veraxAgent(t, path)
+		if !ok {
+			continue
+		}
+		defer agent.Close()
 
 		var counter int
 		var uri, oids string
@@ -102,9 +198,9 @@ func TestQueryGets(t *testing.T) {
 			counter += 1
 			if counter <= 3 { // TODO random number of oids, not hardcoded n
 				oid := r.(QueryResult)
-				oids += "," + oid.Oid
+				oids += "," + oid.Oid.String()
 			} else {
-				uri = `snmp://public@127.0.0.1:` + strconv.Itoa(test.port) + "//(" + oids[1:] + ")"
+				uri = `snmp://public@127.0.0.1:` + strconv.Itoa(port) + "//(" + oids[1:] + ")"
 				counter = 0 // reset
 				oids = ""   // reset
 
@@ -116,14 +212,103 @@ func TestQueryGets(t *testing.T) {
 					Tree:    gresults,
 				}
 				if _, err := Query(params); err != nil {
-					t.Errorf("Query error: %s. Uri: %s", err, uri)
+					t.Errorf("#%d: Query error: %s. Uri: %s", i, err, uri)
 				}
 			}
 		}
+		if gresults.Len() == 0 {
+			t.Errorf("#%d, %s: no results", i, path)
+		}
 		CompareVerax(t, gresults, vresults)
 	}
 }
 
+func TestQueryConcurrent(t *testing.T) {
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0])
+	defer agent.Close()
+	sysdescr := vresults.Get(QueryResult{Oid: MustParseOID("1.3.6.1.2.1.1.1.0")})
+	if sysdescr == nil {
+		t.Fatalf("%s: no sysDescr", veraxDevices[0])
+	}
+
+	uri := `snmp://public@127.0.0.1:` + strconv.Itoa(port) + "//(1.3.6.1.2.1.1.1.0)"
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results, err := Query(NewDefaultParams(uri))
+			if err != nil {
+				t.Errorf("#%d: Query error: %s. Uri: %s", i, err, uri)
+				return
+			}
+			r := results.Get(QueryResult{Oid: MustParseOID("1.3.6.1.2.1.1.1.0")})
+			if r == nil || r.(QueryResult).Value.String() != sysdescr.(QueryResult).Value.String() {
+				t.Errorf("#%d: expected sysDescr (%s) got (%v)", i, sysdescr.(QueryResult).Value, r)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGetMany(t *testing.T) {
+	// small enough that MAX_URI_COUNT oids get tooBig
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0], func(a *Agent) { a.MaxMessage = 1000 })
+	defer agent.Close()
+
+	var oids []string
+	ch := vresults.IterAscend()
+	for {
+		r := <-ch
+		if r == nil {
+			break
+		}
+		oids = append(oids, r.(QueryResult).Oid.String())
+	}
+	// more than fit in one uri
+	for len(oids) <= MAX_URI_COUNT {
+		oids = append(oids, oids...)
+	}
+
+	params := NewDefaultParams(`snmp://public@127.0.0.1:` + strconv.Itoa(port))
+	results, err := GetMany(params, oids)
+	if err != nil {
+		t.Fatalf("GetMany error: %s", err)
+	}
+	if results.Len() != vresults.Len() {
+		t.Errorf("GetMany expected %d results got %d", vresults.Len(), results.Len())
+	}
+	CompareVerax(t, results, vresults)
+
+	// a single oid that doesn't fit can't be split
+	_, small, small_port, _ := veraxAgent(t, veraxDevices[0], func(a *Agent) { a.MaxMessage = 40 })
+	defer small.Close()
+	params = NewDefaultParams(`snmp://public@127.0.0.1:` + strconv.Itoa(small_port))
+	var agent_err *AgentError
+	if _, err := GetMany(params, oids[:3]); !errors.As(err, &agent_err) || agent_err.Status != GNET_SNMP_PDU_ERR_TOOBIG {
+		t.Errorf("GetMany expected tooBig error, got %v", err)
+	}
+}
+
+func TestContextTimeout(t *testing.T) {
+	if timeout := contextTimeout(context.Background(), 200); timeout != 200 {
+		t.Errorf("expected timeout without deadline to be unchanged, got %d", timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if timeout := contextTimeout(ctx, 200); timeout > 50 || timeout < 1 {
+		t.Errorf("expected timeout to be reduced to fit deadline, got %d", timeout)
+	}
+	if timeout := contextTimeout(ctx, 20); timeout != 20 {
+		t.Errorf("expected timeout shorter than deadline to be unchanged, got %d", timeout)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if timeout := contextTimeout(ctx, 200); timeout != 1 {
+		t.Errorf("expected timeout past deadline to be 1, got %d", timeout)
+	}
+}
+
 var partitionAllPTests = []struct {
 	current_position int
 	partition_size   int
@@ -158,27 +343,33 @@ func TestPartitionAllP(t *testing.T) {
 
 // -----------------------------------------------------------------------------
 
-var tests_uriCountMaxed = []struct {
-	path string
-	max  int
-	ok   bool
+var tests_uriWithOids = []struct {
+	uri      string
+	expected string // empty for an error
 }{
-	{"", 3, true},                          // malformed
-	{"((", 3, true},                        // malformed
-	{"))", 3, true},                        // malformed
-	{"())", 3, true},                       // malformed
-	{"(,,)", 3, true},                      // malformed
-	{"(,,,,)", 3, false},                   // malformed
-	{"path://()", 3, true},                 // zero
-	{"path://(1.2,1.3)", 3, true},          // less than
-	{"path://(1.2,1.3,1.4)", 3, true},      // equal
-	{"path://(1.2,1.3,1.4,1.5)", 3, false}, // greater than
-}
-
-func Test_uriCountMaxed(t *testing.T) {
-	for i, test := range tests_uriCountMaxed {
-		if err := uriCountMaxed(test.path, test.max); (err == nil) != test.ok {
-			t.Errorf("#%d: expected %t got |%v| max:%d path:%s", i, test.ok, err, test.max, test.path)
+	{"snmp://public@127.0.0.1", "snmp://public@127.0.0.1//(1.2,1.3)"},
+	{"snmp://public@127.0.0.1:1161/", "snmp://public@127.0.0.1:1161//(1.2,1.3)"},
+	{"snmp://127.0.0.1//(1.3.6.1.2.1.1.1.0)", "snmp://public@127.0.0.1//(1.2,1.3)"},
+	{"snmp://public@[::1]:161/vlan1;8000/1.3.6.*", "snmp://public@[::1]/vlan1;8000/(1.2,1.3)"},
+	{"snmp://p%40ss%2Fword@127.0.0.1//1.3.6+", "snmp://p%40ss%2Fword@127.0.0.1//(1.2,1.3)"},
+	{"snmp+tcp://public@127.0.0.1:1161", "snmp+tcp://public@127.0.0.1:1161//(1.2,1.3)"},
+	{"snmp+unix://public@%2Fvar%2Fagentx%2Fmaster", "snmp+unix://public@%2Fvar%2Fagentx%2Fmaster//(1.2,1.3)"},
+	{"http://127.0.0.1", ""},
+}
+
+func Test_uriWithOids(t *testing.T) {
+	for i, test := range tests_uriWithOids {
+		uri, err := uriWithOids(test.uri, []string{"1.2", "1.3"})
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("#%d: uriWithOids(%s) expected an error", i, test.uri)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: uriWithOids(%s) error: %s", i, test.uri, err)
+		} else if uri != test.expected {
+			t.Errorf("#%d: uriWithOids(%s) expected %s got %s", i, test.uri, test.expected, uri)
 		}
 	}
 }
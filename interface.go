@@ -21,14 +21,16 @@ import (
 	"fmt"
 	"math"
 	"math/big"
-	"strconv"
-	"strings"
+	"net"
 )
 
 type Varbinder interface {
 	// Integer() needs to handle both signed numbers (int32), as well as
 	// unsigned int 64 (uint64). Therefore it returns a *big.Int.
 	Integer() *big.Int
+	// Bytes() returns the octets of an OCTET STRING, Opaque or IpAddress,
+	// exactly as they were received; it returns nil for other types.
+	Bytes() []byte
 	fmt.Stringer
 }
 
@@ -39,19 +41,32 @@ func (r VBT_Null) Integer() *big.Int {
 	return big.NewInt(0)
 }
 
+func (r VBT_Null) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_Null) String() string {
 	return "NULL"
 }
 
 // GNET_SNMP_VARBIND_TYPE_OCTETSTRING
+//
+// A VBT_OctetString holds the octets as received, which may be text, a MAC
+// address or any binary data. String() shows it as text if every octet is
+// printable and as a hex string (eg "00 25 89 27 56 1B") otherwise; use
+// Bytes(), FormatHex(), FormatMAC() or FormatUTF8() to choose.
 type VBT_OctetString string
 
 func (r VBT_OctetString) Integer() *big.Int {
 	return big.NewInt(0)
 }
 
+func (r VBT_OctetString) Bytes() []byte {
+	return []byte(r)
+}
+
 func (r VBT_OctetString) String() string {
-	return fmt.Sprintf("%s", string(r))
+	return octetString([]byte(r))
 }
 
 // GNET_SNMP_VARBIND_TYPE_OBJECTID
@@ -61,6 +76,10 @@ func (r VBT_ObjectID) Integer() *big.Int {
 	return big.NewInt(0)
 }
 
+func (r VBT_ObjectID) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_ObjectID) String() string {
 	return fmt.Sprintf("%s", string(r))
 }
@@ -69,16 +88,16 @@ func (r VBT_ObjectID) String() string {
 type VBT_IPAddress string
 
 func (r VBT_IPAddress) Integer() *big.Int {
-	// convert ip address to a uint32 ie it's numeric form
-	// alternatively, could return a net/IP from union_ui8v_ipaddress
-	var result uint32
-	for _, octet := range strings.Split(string(r), ".") {
-		result <<= 8
-		n, _ := strconv.ParseUint(octet, 10, 8)
-		result += uint32(n)
+	// convert ip address to its numeric form, eg a uint32 for ipv4
+	return new(big.Int).SetBytes(r.Bytes())
+}
 
+func (r VBT_IPAddress) Bytes() []byte {
+	ip := net.ParseIP(string(r))
+	if ip4 := ip.To4(); ip4 != nil {
+		return []byte(ip4)
 	}
-	return big.NewInt(int64(result))
+	return []byte(ip)
 }
 
 func (r VBT_IPAddress) String() string {
@@ -92,6 +111,10 @@ func (r VBT_Integer32) Integer() *big.Int {
 	return big.NewInt(int64(r))
 }
 
+func (r VBT_Integer32) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_Integer32) String() string {
 	return fmt.Sprintf("%d", r)
 }
@@ -103,6 +126,10 @@ func (r VBT_Unsigned32) Integer() *big.Int {
 	return big.NewInt(int64(r))
 }
 
+func (r VBT_Unsigned32) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_Unsigned32) String() string {
 	return fmt.Sprintf("%d", r)
 }
@@ -114,6 +141,10 @@ func (r VBT_Counter32) Integer() *big.Int {
 	return big.NewInt(int64(r))
 }
 
+func (r VBT_Counter32) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_Counter32) String() string {
 	return fmt.Sprintf("%d", r)
 }
@@ -125,6 +156,10 @@ func (r VBT_Timeticks) Integer() *big.Int {
 	return big.NewInt(int64(r))
 }
 
+func (r VBT_Timeticks) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_Timeticks) String() string {
 	ticks := uint32(r)
 	if ticks == uint32(0) {
@@ -142,14 +177,21 @@ func (r VBT_Timeticks) String() string {
 }
 
 // GNET_SNMP_VARBIND_TYPE_OPAQUE
+//
+// A VBT_Opaque holds the octets as received. String() shows them as a hex
+// string, eg "9F 78 04".
 type VBT_Opaque string
 
 func (r VBT_Opaque) Integer() *big.Int {
 	return big.NewInt(0)
 }
 
+func (r VBT_Opaque) Bytes() []byte {
+	return []byte(r)
+}
+
 func (r VBT_Opaque) String() string {
-	return fmt.Sprintf("%s", string(r))
+	return hexString([]byte(r))
 }
 
 // GNET_SNMP_VARBIND_TYPE_COUNTER64
@@ -160,6 +202,10 @@ func (r VBT_Counter64) Integer() *big.Int {
 	return uint64ToBigInt(uint64(r))
 }
 
+func (r VBT_Counter64) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_Counter64) String() string {
 	return fmt.Sprintf("%d", r)
 }
@@ -171,6 +217,10 @@ func (r VBT_NoSuchObject) Integer() *big.Int {
 	return big.NewInt(0)
 }
 
+func (r VBT_NoSuchObject) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_NoSuchObject) String() string {
 	return "No Such Object available on this agent at this OID" // same as netsnmp
 }
@@ -182,6 +232,10 @@ func (r VBT_NoSuchInstance) Integer() *big.Int {
 	return big.NewInt(0)
 }
 
+func (r VBT_NoSuchInstance) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_NoSuchInstance) String() string {
 	return "No Such Instance"
 }
@@ -193,6 +247,10 @@ func (r VBT_EndOfMibView) Integer() *big.Int {
 	return big.NewInt(0)
 }
 
+func (r VBT_EndOfMibView) Bytes() []byte {
+	return nil
+}
+
 func (r VBT_EndOfMibView) String() string {
 	return "End of MIB View"
 }
@@ -0,0 +1,791 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// mib.go is a parser for SMIv1 and SMIv2 MIB modules. It only reads what's
+// needed to name oids and describe objects (the oid assignments, textual
+// conventions, and the SYNTAX, MAX-ACCESS, INDEX etc clauses); anything else
+// is skipped.
+
+import (
+	"bytes"
+	"code.google.com/p/tcgl/applog"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Mib, if not nil, is used to translate oid names (eg
+// "IF-MIB::ifHCInOctets.3" or "sysDescr.0") in the uris given to Query()
+// and in the oids given to Session methods, and to name oids in Dump().
+// Set it before starting any queries.
+var Mib *MIB
+
+// MIB is a tree of oid names, loaded from MIB modules by LoadMIBs.
+type MIB struct {
+	root    *MibNode
+	modules map[string]map[string]*MibNode // module name to object name to node
+	names   map[string][]*MibNode          // object name to nodes, in load order
+	pending []*mibDefinition               // definitions whose parent isn't known yet
+
+	types     map[string]map[string]*MibType // module name to textual convention name to type
+	typeNames map[string][]*MibType          // textual convention name to types, in load order
+}
+
+// MibNode is an object defined in a MIB module.
+type MibNode struct {
+	Name        string         // eg "ifDescr"
+	Module      string         // eg "IF-MIB"
+	Oid         OID            // eg 1.3.6.1.2.1.2.2.1.2
+	Macro       string         // eg "OBJECT-TYPE", "OBJECT IDENTIFIER" or "NOTIFICATION-TYPE"
+	Syntax      string         // eg "DisplayString", "INTEGER" or "OCTET STRING"
+	Enums       map[int]string // the named numbers of an INTEGER or BITS syntax
+	Access      string         // MAX-ACCESS (ACCESS in SMIv1), eg "read-only"
+	Status      string         // eg "current"
+	Units       string
+	Index       []string // the INDEX of a table entry
+	Augments    string   // the entry augmented by a table entry
+	Objects     []string // the OBJECTS of a notification or group (VARIABLES in SMIv1)
+	Description string
+
+	children map[uint32]*MibNode
+}
+
+// MibType is a textual convention defined in a MIB module, eg DisplayString
+// or MacAddress.
+type MibType struct {
+	Name        string         // eg "MacAddress"
+	Module      string         // eg "SNMPv2-TC"
+	DisplayHint string         // eg "1x:"
+	Syntax      string         // the underlying type, eg "OCTET STRING"
+	Enums       map[int]string // the named numbers of an INTEGER or BITS syntax
+}
+
+// NewMIB returns a MIB that only knows the roots of the oid tree (ccitt,
+// iso and joint-iso-ccitt).
+func NewMIB() *MIB {
+	m := &MIB{
+		root:    &MibNode{children: make(map[uint32]*MibNode)},
+		modules: make(map[string]map[string]*MibNode),
+		names:   make(map[string][]*MibNode),
+
+		types:     make(map[string]map[string]*MibType),
+		typeNames: make(map[string][]*MibType),
+	}
+	for i, name := range []string{"ccitt", "iso", "joint-iso-ccitt"} {
+		node := &MibNode{Name: name, Oid: OID{uint32(i)}, children: make(map[uint32]*MibNode)}
+		m.root.children[uint32(i)] = node
+		m.names[name] = []*MibNode{node}
+	}
+	return m
+}
+
+// LoadMIBs returns a MIB loaded from paths; see Load.
+func LoadMIBs(paths ...string) (*MIB, error) {
+	m := NewMIB()
+	if err := m.Load(paths...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load loads the MIB modules in paths, which are files or directory trees.
+// Files that don't contain a module (eg a README) are ignored, as are files
+// and directories starting with a '.'.
+//
+// Modules can be loaded in any order; objects whose parents haven't been
+// loaded yet are added when they are. Errors are a *MibError.
+func (m *MIB) Load(paths ...string) error {
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return &MibError{File: file, Msg: "unable to read", Err: err}
+			}
+			if file != path && strings.HasPrefix(info.Name(), ".") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return &MibError{File: file, Msg: "unable to read", Err: err}
+			}
+			if !bytes.Contains(data, []byte("DEFINITIONS")) {
+				return nil
+			}
+			return m.parse(file, string(data))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	m.resolve()
+	if Debug {
+		for _, definition := range m.pending {
+			applog.Warningf("%s:%d: %s::%s: unknown parent %s", definition.file, definition.line,
+				definition.node.Module, definition.node.Name, definition.parent)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the node called name, either qualified by its module (eg
+// "IF-MIB::ifDescr") or not ("ifDescr"), or nil. If several modules define
+// an unqualified name, the first one loaded is returned.
+func (m *MIB) Lookup(name string) *MibNode {
+	if i := strings.Index(name, "::"); i >= 0 {
+		return m.modules[name[:i]][name[i+2:]]
+	}
+	if nodes := m.names[name]; len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// Node returns the nearest named node at or above oid, and the rest of oid
+// below it (eg the row index of a table cell). node is nil if no part of oid
+// is known.
+func (m *MIB) Node(oid OID) (node *MibNode, index OID) {
+	current := m.root
+	depth := 0
+	for i, sub := range oid {
+		current = current.children[sub]
+		if current == nil {
+			break
+		}
+		if current.Name != "" {
+			node, depth = current, i+1
+		}
+	}
+	if node == nil {
+		return nil, oid.Append()
+	}
+	return node, oid[depth:].Append()
+}
+
+// Name returns oid as a name qualified by its module, eg
+// "IF-MIB::ifHCInOctets.3" for 1.3.6.1.2.1.31.1.1.1.6.3. Unknown oids are
+// returned in numeric form.
+func (m *MIB) Name(oid OID) string {
+	node, index := m.Node(oid)
+	if node == nil {
+		return oid.String()
+	}
+	name := node.String()
+	if len(index) > 0 {
+		name += "." + index.String()
+	}
+	return name
+}
+
+// ParseName converts a name such as "IF-MIB::ifHCInOctets.3" or
+// "sysDescr.0" to an OID. Numeric oids are also accepted.
+func (m *MIB) ParseName(s string) (OID, error) {
+	if s == "" || s[0] == '.' || (s[0] >= '0' && s[0] <= '9') {
+		return ParseOID(s)
+	}
+	name, suffix := s, ""
+	start := 0
+	if i := strings.Index(s, "::"); i >= 0 {
+		start = i + 2
+	}
+	if i := strings.Index(s[start:], "."); i >= 0 {
+		name, suffix = s[:start+i], s[start+i+1:]
+	}
+	node := m.Lookup(name)
+	if node == nil {
+		return nil, fmt.Errorf("%s: ParseName(): unknown name %s", libname(), name)
+	}
+	if suffix == "" {
+		return node.Oid.Append(), nil
+	}
+	index, err := parseOid(suffix)
+	if err != nil {
+		return nil, fmt.Errorf("%s: ParseName(): invalid index in %s", libname(), s)
+	}
+	return node.Oid.Append(index...), nil
+}
+
+// Type returns the textual convention called name, either qualified by its
+// module (eg "SNMPv2-TC::MacAddress") or not ("MacAddress"), or nil.
+func (m *MIB) Type(name string) *MibType {
+	if i := strings.Index(name, "::"); i >= 0 {
+		return m.types[name[:i]][name[i+2:]]
+	}
+	if types := m.typeNames[name]; len(types) > 0 {
+		return types[0]
+	}
+	return nil
+}
+
+// String returns the node's name qualified by its module, eg "IF-MIB::ifDescr".
+func (n *MibNode) String() string {
+	if n.Module == "" {
+		return n.Name
+	}
+	return n.Module + "::" + n.Name
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// insert adds definition to the tree below parent. If the oid is already
+// named (eg mib-2 is defined by both SNMPv2-SMI and RFC1213-MIB) the first
+// definition stays in the tree, but both can be looked up by name.
+func (m *MIB) insert(parent *MibNode, definition *mibDefinition) {
+	node := parent
+	for i, sub := range definition.subs {
+		child := node.children[sub.number]
+		if child == nil {
+			child = &MibNode{Oid: node.Oid.Append(sub.number), children: make(map[uint32]*MibNode)}
+			node.children[sub.number] = child
+		}
+		if i < len(definition.subs)-1 && sub.name != "" && child.Name == "" {
+			// a node named in passing, eg org(3) in { iso org(3) }
+			child.Name, child.Module = sub.name, definition.node.Module
+			m.register(child)
+		}
+		node = child
+	}
+
+	defined := definition.node
+	defined.Oid, defined.children = node.Oid, node.children
+	if node.Name == "" {
+		*node = *defined
+	} else {
+		node = defined // shares its children with the node in the tree
+	}
+	m.register(node)
+}
+
+// mibClauses are the keywords that start a clause of a macro; a SYNTAX
+// clause runs until the next one.
+var mibClauses = map[string]bool{
+	"ACCESS": true, "AUGMENTS": true, "CONTACT-INFO": true, "CREATION-REQUIRES": true,
+	"DEFVAL": true, "DESCRIPTION": true, "DISPLAY-HINT": true, "ENTERPRISE": true,
+	"GROUP": true, "INCLUDES": true, "INDEX": true, "LAST-UPDATED": true,
+	"MANDATORY-GROUPS": true, "MAX-ACCESS": true, "MIN-ACCESS": true, "MODULE": true,
+	"NOTIFICATIONS": true, "OBJECT": true, "OBJECTS": true, "ORGANIZATION": true,
+	"PRODUCT-RELEASE": true, "REFERENCE": true, "REVISION": true, "STATUS": true,
+	"SUPPORTS": true, "SYNTAX": true, "UNITS": true, "VARIABLES": true,
+	"VARIATION": true, "WRITE-SYNTAX": true,
+}
+
+// mibDefinition is an oid assignment read from a module, eg
+// "ifEntry OBJECT-TYPE ... ::= { ifTable 1 }".
+type mibDefinition struct {
+	node   *MibNode
+	parent string   // the first name in the value, or "" if it's absolute
+	subs   []mibSub // the rest of the value
+	file   string
+	line   int
+}
+
+// mibMacros are the macros that assign an oid.
+var mibMacros = map[string]bool{
+	"AGENT-CAPABILITIES": true, "MODULE-COMPLIANCE": true, "MODULE-IDENTITY": true,
+	"NOTIFICATION-GROUP": true, "NOTIFICATION-TYPE": true, "OBJECT-GROUP": true,
+	"OBJECT-IDENTITY": true, "OBJECT-TYPE": true, "TRAP-TYPE": true,
+}
+
+// mibParams returns params with any oid names in its uri translated by Mib.
+func mibParams(params *QueryParams) (*QueryParams, error) {
+	if Mib == nil {
+		return params, nil
+	}
+	uri, err := Mib.translateUri(params.Uri)
+	if err != nil || uri == params.Uri {
+		return params, err
+	}
+	translated := *params
+	translated.Uri = uri
+	return &translated, nil
+}
+
+// mibSub is one sub-identifier of an oid value, eg "org(3)" or "1".
+type mibSub struct {
+	name   string
+	number uint32
+}
+
+// mibSyntaxEnd returns the position after the syntax starting at
+// tokens[start], eg OCTET STRING (SIZE (0..255)) or INTEGER { true(1),
+// false(2) }.
+func mibSyntaxEnd(tokens []mibToken, start int) (end int) {
+	end = start + 1
+	if end < len(tokens) && (tokens[start].text == "OCTET" || tokens[start].text == "OBJECT") {
+		end++
+	}
+	for end < len(tokens) && (tokens[end].text == "{" || tokens[end].text == "(") {
+		for depth := 0; end < len(tokens); {
+			switch tokens[end].text {
+			case "{", "(":
+				depth++
+			case "}", ")":
+				depth--
+			}
+			end++
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	return end
+}
+
+// mibToken is a token of a MIB module.
+type mibToken struct {
+	text   string
+	line   int
+	quoted bool // a "string"
+}
+
+// mibTokenize splits a MIB module into tokens, dropping comments.
+func mibTokenize(file, data string) (tokens []mibToken, err error) {
+	line := 1
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(data[i:], "--"):
+			// a comment runs to the end of the line, or the next "--"
+			i += 2
+			for i < len(data) && data[i] != '\n' {
+				if strings.HasPrefix(data[i:], "--") {
+					i += 2
+					break
+				}
+				i++
+			}
+		case c == '"':
+			end := strings.IndexByte(data[i+1:], '"')
+			if end < 0 {
+				return nil, &MibError{File: file, Line: line, Msg: "unterminated string"}
+			}
+			text := data[i+1 : i+1+end]
+			tokens = append(tokens, mibToken{text: text, line: line, quoted: true})
+			line += strings.Count(text, "\n")
+			i += end + 2
+		case c == '\'':
+			// a binary or hex string, eg '00'H
+			end := strings.IndexByte(data[i+1:], '\'')
+			if end < 0 {
+				return nil, &MibError{File: file, Line: line, Msg: "unterminated binary or hex string"}
+			}
+			end += i + 2
+			if end < len(data) && (data[end] == 'H' || data[end] == 'h' || data[end] == 'B' || data[end] == 'b') {
+				end++
+			}
+			tokens = append(tokens, mibToken{text: data[i:end], line: line})
+			i = end
+		case strings.HasPrefix(data[i:], "::="):
+			tokens = append(tokens, mibToken{text: "::=", line: line})
+			i += 3
+		case strings.HasPrefix(data[i:], ".."):
+			tokens = append(tokens, mibToken{text: "..", line: line})
+			i += 2
+		case mibWordByte(c) || (c == '-' && i+1 < len(data) && data[i+1] >= '0' && data[i+1] <= '9'):
+			// a name or number; names can contain single hyphens
+			end := i + 1
+			for end < len(data) && (mibWordByte(data[end]) ||
+				(data[end] == '-' && end+1 < len(data) && mibWordByte(data[end+1]))) {
+				end++
+			}
+			tokens = append(tokens, mibToken{text: data[i:end], line: line})
+			i = end
+		default:
+			tokens = append(tokens, mibToken{text: data[i : i+1], line: line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// mibWordByte returns true if c can be part of a name or number.
+func mibWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// parse reads the modules in a file, adding their definitions to m.pending.
+func (m *MIB) parse(file, data string) error {
+	tokens, err := mibTokenize(file, data)
+	if err != nil {
+		return err
+	}
+	var module string
+	for i := 0; i < len(tokens); i++ {
+		text := tokens[i].text
+		if tokens[i].quoted {
+			continue
+		}
+		switch {
+		case text == "DEFINITIONS" && i > 0:
+			module = tokens[i-1].text
+			continue
+		case text == "MACRO":
+			// a macro definition, eg in SNMPv2-SMI; skip to its END
+			for i < len(tokens) && tokens[i].text != "END" {
+				i++
+			}
+			continue
+		case text == "IMPORTS" || text == "EXPORTS":
+			for i < len(tokens) && tokens[i].text != ";" {
+				i++
+			}
+			continue
+		case text == "END":
+			module = ""
+			continue
+		}
+		if module != "" && i+2 < len(tokens) && text[0] >= 'A' && text[0] <= 'Z' &&
+			tokens[i+1].text == "::=" && tokens[i+2].text == "TEXTUAL-CONVENTION" {
+			i = m.parseMibType(module, tokens, i) - 1
+			continue
+		}
+		if module == "" || i+2 >= len(tokens) || text[0] < 'a' || text[0] > 'z' {
+			continue
+		}
+
+		node := &MibNode{Name: text, Module: module}
+		body := i + 1
+		switch macro := tokens[i+1].text; {
+		case macro == "OBJECT" && tokens[i+2].text == "IDENTIFIER" && i+3 < len(tokens) && tokens[i+3].text == "::=":
+			node.Macro = "OBJECT IDENTIFIER"
+			i += 3
+		case mibMacros[macro]:
+			node.Macro = macro
+			for i = body; i < len(tokens) && tokens[i].text != "::="; i++ {
+			}
+			if i == len(tokens) {
+				return &MibError{File: file, Line: tokens[body].line, Msg: "no value for " + text}
+			}
+		default:
+			continue
+		}
+		enterprise := parseMibClauses(node, tokens[body+1:i])
+
+		// the value, eg { ifTable 1 }, or for a TRAP-TYPE the trap number
+		definition := &mibDefinition{node: node, file: file, line: tokens[body].line}
+		i++
+		if node.Macro == "TRAP-TYPE" {
+			if i == len(tokens) || enterprise == "" {
+				return &MibError{File: file, Line: definition.line, Msg: "invalid TRAP-TYPE " + text}
+			}
+			number, err := strconv.ParseUint(tokens[i].text, 10, 32)
+			if err != nil {
+				return &MibError{File: file, Line: tokens[i].line, Msg: "invalid trap number for " + text}
+			}
+			definition.parent = enterprise
+			definition.subs = []mibSub{{number: 0}, {number: uint32(number)}}
+		} else {
+			end, err := parseMibValue(definition, tokens, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		}
+		m.pending = append(m.pending, definition)
+	}
+	return nil
+}
+
+// parseMibClauses fills in node from the clauses of a macro, and returns
+// the ENTERPRISE of a TRAP-TYPE. Only the first of each clause is used.
+func parseMibClauses(node *MibNode, tokens []mibToken) (enterprise string) {
+	for i := 0; i < len(tokens); i++ {
+		clause := tokens[i].text
+		if tokens[i].quoted || !mibClauses[clause] || i+1 == len(tokens) {
+			continue
+		}
+		next := tokens[i+1]
+		switch clause {
+		case "SYNTAX":
+			if node.Syntax == "" {
+				end := i + 1
+				for depth := 0; end < len(tokens); end++ {
+					text := tokens[end].text
+					if depth == 0 && mibClauses[text] && !(text == "OBJECT" && end == i+1) {
+						break
+					}
+					switch text {
+					case "{", "(":
+						depth++
+					case "}", ")":
+						depth--
+					}
+				}
+				node.Syntax, node.Enums = parseMibSyntax(tokens[i+1 : end])
+			}
+		case "ACCESS", "MAX-ACCESS":
+			if node.Access == "" {
+				node.Access = next.text
+			}
+		case "STATUS":
+			if node.Status == "" {
+				node.Status = next.text
+			}
+		case "UNITS":
+			if node.Units == "" {
+				node.Units = next.text
+			}
+		case "DESCRIPTION":
+			if node.Description == "" {
+				node.Description = strings.TrimSpace(next.text)
+			}
+		case "INDEX":
+			if node.Index == nil {
+				node.Index = parseMibList(tokens[i+1:])
+			}
+		case "AUGMENTS":
+			if list := parseMibList(tokens[i+1:]); len(list) > 0 && node.Augments == "" {
+				node.Augments = list[0]
+			}
+		case "OBJECTS", "VARIABLES", "NOTIFICATIONS":
+			if node.Objects == nil {
+				node.Objects = parseMibList(tokens[i+1:])
+			}
+		case "ENTERPRISE":
+			enterprise = next.text
+		}
+	}
+	return enterprise
+}
+
+// parseMibList returns the names in a list such as { ifIndex, IMPLIED
+// ifName }, which starts at tokens[0].
+func parseMibList(tokens []mibToken) (names []string) {
+	if len(tokens) == 0 || tokens[0].text != "{" {
+		return nil
+	}
+	for _, token := range tokens[1:] {
+		switch token.text {
+		case "}":
+			return names
+		case ",", "IMPLIED":
+		default:
+			names = append(names, token.text)
+		}
+	}
+	return names
+}
+
+// parseMibSyntax returns the type of a SYNTAX clause, eg "INTEGER" for
+// INTEGER { up(1), down(2) }, and its named numbers.
+func parseMibSyntax(tokens []mibToken) (syntax string, enums map[int]string) {
+	if len(tokens) == 0 {
+		return "", nil
+	}
+	syntax = tokens[0].text
+	rest := tokens[1:]
+	switch {
+	case len(tokens) > 1 && (syntax == "OCTET" || syntax == "OBJECT"):
+		syntax += " " + tokens[1].text
+		rest = tokens[2:]
+	case len(tokens) > 2 && syntax == "SEQUENCE" && tokens[1].text == "OF":
+		syntax += " OF " + tokens[2].text
+		rest = nil
+	}
+	if len(rest) == 0 || rest[0].text != "{" {
+		return syntax, nil
+	}
+	// named numbers: { name(number), ... }
+	enums = make(map[int]string)
+	for i := 1; i+3 < len(rest) && rest[i].text != "}"; i++ {
+		if rest[i+1].text != "(" || rest[i+3].text != ")" {
+			continue
+		}
+		if number, err := strconv.Atoi(rest[i+2].text); err == nil {
+			enums[number] = rest[i].text
+		}
+		i += 3
+	}
+	return syntax, enums
+}
+
+// parseMibType reads a textual convention such as "MacAddress ::=
+// TEXTUAL-CONVENTION ... SYNTAX OCTET STRING (SIZE (6))", which starts at
+// tokens[start]. It returns the position after it.
+func (m *MIB) parseMibType(module string, tokens []mibToken, start int) (end int) {
+	mib_type := &MibType{Name: tokens[start].text, Module: module}
+	for end = start + 3; end < len(tokens); end++ {
+		if tokens[end].quoted {
+			continue
+		}
+		if tokens[end].text == "DISPLAY-HINT" && end+1 < len(tokens) {
+			mib_type.DisplayHint = tokens[end+1].text
+		}
+		if tokens[end].text == "SYNTAX" {
+			break // SYNTAX is the last clause
+		}
+	}
+	if end+1 >= len(tokens) {
+		return end
+	}
+	syntax_end := mibSyntaxEnd(tokens, end+1)
+	mib_type.Syntax, mib_type.Enums = parseMibSyntax(tokens[end+1 : syntax_end])
+
+	if m.types[module] == nil {
+		m.types[module] = make(map[string]*MibType)
+	}
+	if m.types[module][mib_type.Name] == nil {
+		m.types[module][mib_type.Name] = mib_type
+		m.typeNames[mib_type.Name] = append(m.typeNames[mib_type.Name], mib_type)
+	}
+	return syntax_end
+}
+
+// parseMibValue reads an oid value such as { iso org(3) dod(6) 1 }, which
+// starts at tokens[start], into definition. It returns the position of the
+// closing brace.
+func parseMibValue(definition *mibDefinition, tokens []mibToken, start int) (end int, err error) {
+	invalid := &MibError{File: definition.file, Line: definition.line, Msg: "invalid oid value for " + definition.node.Name}
+	if start >= len(tokens) || tokens[start].text != "{" {
+		return 0, invalid
+	}
+	for i := start + 1; i < len(tokens); i++ {
+		text := tokens[i].text
+		if text == "}" {
+			if definition.parent == "" && len(definition.subs) == 0 {
+				return 0, invalid
+			}
+			return i, nil
+		}
+		if number, err := strconv.ParseUint(text, 10, 32); err == nil {
+			definition.subs = append(definition.subs, mibSub{number: uint32(number)})
+			continue
+		}
+		if i+3 < len(tokens) && tokens[i+1].text == "(" && tokens[i+3].text == ")" {
+			// name(number)
+			number, err := strconv.ParseUint(tokens[i+2].text, 10, 32)
+			if err != nil {
+				return 0, invalid
+			}
+			definition.subs = append(definition.subs, mibSub{name: text, number: uint32(number)})
+			i += 3
+			continue
+		}
+		if i != start+1 {
+			return 0, invalid // only the first element can be a bare name
+		}
+		definition.parent = text
+	}
+	return 0, invalid
+}
+
+// parseOidName parses a numeric oid, or an oid name if Mib is set.
+func parseOidName(oid string) (OID, error) {
+	if Mib != nil {
+		return Mib.ParseName(oid)
+	}
+	return ParseOID(oid)
+}
+
+// register adds node to the name maps.
+func (m *MIB) register(node *MibNode) {
+	if m.modules[node.Module] == nil {
+		m.modules[node.Module] = make(map[string]*MibNode)
+	}
+	if m.modules[node.Module][node.Name] == nil {
+		m.modules[node.Module][node.Name] = node
+		m.names[node.Name] = append(m.names[node.Name], node)
+	}
+}
+
+// resolve adds the pending definitions whose parents are known to the tree,
+// until no more can be added.
+func (m *MIB) resolve() {
+	for progress := true; progress; {
+		progress = false
+		var still_pending []*mibDefinition
+		for _, definition := range m.pending {
+			parent := m.root
+			if definition.parent != "" {
+				parent = m.modules[definition.node.Module][definition.parent]
+				if parent == nil {
+					parent = m.Lookup(definition.parent)
+				}
+			}
+			if parent == nil {
+				still_pending = append(still_pending, definition)
+				continue
+			}
+			m.insert(parent, definition)
+			progress = true
+		}
+		m.pending = still_pending
+	}
+}
+
+// translateUri returns uri with the oid names in its path translated to
+// numeric oids, eg snmp://public@host//(sysDescr.0,IF-MIB::ifDescr)* becomes
+// snmp://public@host//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.2.2.1.2)*
+func (m *MIB) translateUri(uri string) (string, error) {
+	// the oids follow the second '/' after the authority
+	scheme := strings.Index(uri, "://")
+	if scheme < 0 {
+		return uri, nil
+	}
+	start := scheme + 3
+	for slashes := 0; slashes < 2; slashes++ {
+		i := strings.Index(uri[start:], "/")
+		if i < 0 {
+			return uri, nil
+		}
+		start += i + 1
+	}
+
+	translated := uri[:start]
+	for pos := start; pos < len(uri); {
+		end := strings.IndexAny(uri[pos:], "(),+*")
+		if end < 0 {
+			end = len(uri)
+		} else {
+			end += pos
+		}
+		name := uri[pos:end]
+		if name != "" && name != "." && name[0] != '.' && (name[0] < '0' || name[0] > '9') {
+			// a name, perhaps followed by the '.' of a ".*" walk
+			dot := ""
+			if strings.HasSuffix(name, ".") {
+				name, dot = name[:len(name)-1], "."
+			}
+			oid, err := m.ParseName(name)
+			if err != nil {
+				return "", &UriError{Uri: uri, Pos: pos, Msg: "unknown oid name " + name}
+			}
+			translated += oid.String() + dot
+		} else {
+			translated += name
+		}
+		if end < len(uri) {
+			translated += uri[end : end+1]
+		}
+		pos = end + 1
+	}
+	return translated, nil
+}
@@ -0,0 +1,233 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var mibNameTests = []struct {
+	in   string
+	oid  string
+	name string // Name() of oid
+}{
+	{"IF-MIB::ifHCInOctets.3", "1.3.6.1.2.1.31.1.1.1.6.3", "IF-MIB::ifHCInOctets.3"},
+	{"ifHCInOctets.3", "1.3.6.1.2.1.31.1.1.1.6.3", "IF-MIB::ifHCInOctets.3"},
+	{"sysDescr.0", "1.3.6.1.2.1.1.1.0", "SNMPv2-MIB::sysDescr.0"},
+	{"IF-MIB::ifTable", "1.3.6.1.2.1.2.2", "IF-MIB::ifTable"},
+	{"SNMPv2-MIB::coldStart", "1.3.6.1.6.3.1.1.5.1", "SNMPv2-MIB::coldStart"},
+	{"IF-MIB::linkDown", "1.3.6.1.6.3.1.1.5.3", "IF-MIB::linkDown"},
+	{"RFC-1215::linkDown", "1.3.6.1.2.1.11.0.2", "RFC-1215::linkDown"},
+	{"RFC1213-MIB::ipNetToMediaType.2.10.0.0.1", "1.3.6.1.2.1.4.22.1.4.2.10.0.0.1", "RFC1213-MIB::ipNetToMediaType.2.10.0.0.1"},
+	{"zeroDotZero", "0.0", "SNMPv2-SMI::zeroDotZero"},
+	{"1.3.6.1.2.1.31.1.1.1.99.1", "1.3.6.1.2.1.31.1.1.1.99.1", "IF-MIB::ifXEntry.99.1"},
+	{".1.2.3", "1.2.3", "iso.2.3"},
+	{"3.1", "3.1", "3.1"},
+}
+
+func TestMibNames(t *testing.T) {
+	mib, err := LoadMIBs("testing/mibs")
+	if err != nil {
+		t.Fatalf("LoadMIBs error: %s", err)
+	}
+	for i, test := range mibNameTests {
+		oid, err := mib.ParseName(test.in)
+		if err != nil {
+			t.Errorf("#%d: ParseName(%s) error: %s", i, test.in, err)
+			continue
+		}
+		if oid.String() != test.oid {
+			t.Errorf("#%d: ParseName(%s) expected %s got %s", i, test.in, test.oid, oid)
+		}
+		if name := mib.Name(oid); name != test.name {
+			t.Errorf("#%d: Name(%s) expected %s got %s", i, oid, test.name, name)
+		}
+	}
+
+	for i, bad := range []string{"", "ifBogus.1", "IF-MIB::sysDescr.0", "sysDescr.x", "NO-MIB::ifDescr"} {
+		if _, err := mib.ParseName(bad); err == nil {
+			t.Errorf("#%d: ParseName(%q) expected error", i, bad)
+		}
+	}
+}
+
+func TestMibNodes(t *testing.T) {
+	mib, err := LoadMIBs("testing/mibs")
+	if err != nil {
+		t.Fatalf("LoadMIBs error: %s", err)
+	}
+	tests := []struct {
+		name string
+		want MibNode // only the non-zero fields are compared
+	}{
+		{"IF-MIB::ifOperStatus", MibNode{Macro: "OBJECT-TYPE", Syntax: "INTEGER", Access: "read-only", Status: "current"}},
+		{"IF-MIB::ifTable", MibNode{Syntax: "SEQUENCE OF IfEntry", Access: "not-accessible"}},
+		{"IF-MIB::ifEntry", MibNode{Syntax: "IfEntry", Index: []string{"ifIndex"}}},
+		{"IF-MIB::ifXEntry", MibNode{Augments: "ifEntry"}},
+		{"IF-MIB::ifHighSpeed", MibNode{Syntax: "Gauge32", Units: "Mb/s"}},
+		{"IF-MIB::ifPhysAddress", MibNode{Syntax: "PhysAddress"}},
+		{"IF-MIB::linkUp", MibNode{Macro: "NOTIFICATION-TYPE", Objects: []string{"ifIndex", "ifAdminStatus", "ifOperStatus"}}},
+		{"IF-MIB::ifCompliance3", MibNode{Macro: "MODULE-COMPLIANCE", Status: "current"}},
+		{"SNMPv2-MIB::sysObjectID", MibNode{Syntax: "OBJECT IDENTIFIER"}},
+		{"SNMPv2-MIB::sysDescr", MibNode{Syntax: "DisplayString", Access: "read-only",
+			Description: "A textual description of the entity.  This value should\n            include the full name and version identification of\n            the system's hardware type, software operating-system,\n            and networking software."}},
+		{"SNMPv2-MIB::snmpTrap", MibNode{Macro: "OBJECT IDENTIFIER"}},
+		{"RFC1213-MIB::ipNetToMediaEntry", MibNode{Index: []string{"ipNetToMediaIfIndex", "ipNetToMediaNetAddress"}}},
+		{"RFC1213-MIB::ipAdEntAddr", MibNode{Syntax: "IpAddress", Access: "read-only", Status: "mandatory"}},
+		{"RFC-1215::linkDown", MibNode{Macro: "TRAP-TYPE", Objects: []string{"ifIndex"}}},
+		{"RFC1155-SMI::dod", MibNode{Oid: OID{1, 3, 6}}},
+	}
+	for i, test := range tests {
+		node := mib.Lookup(test.name)
+		if node == nil {
+			t.Errorf("#%d: Lookup(%s) not found", i, test.name)
+			continue
+		}
+		got := reflect.ValueOf(*node)
+		want := reflect.ValueOf(test.want)
+		for f := 0; f < want.NumField(); f++ {
+			field := want.Type().Field(f)
+			if field.PkgPath != "" || reflect.DeepEqual(want.Field(f).Interface(), reflect.Zero(field.Type).Interface()) {
+				continue
+			}
+			if !reflect.DeepEqual(got.Field(f).Interface(), want.Field(f).Interface()) {
+				t.Errorf("#%d: %s: expected %s %#v got %#v", i, test.name, field.Name,
+					want.Field(f).Interface(), got.Field(f).Interface())
+			}
+		}
+	}
+
+	enums := map[int]string{1: "up", 2: "down", 3: "testing"}
+	if node := mib.Lookup("ifAdminStatus"); node == nil || !reflect.DeepEqual(node.Enums, enums) {
+		t.Errorf("ifAdminStatus: expected enums %v got %v", enums, node)
+	}
+	if node := mib.Lookup("ifOperStatus"); node == nil || node.Enums[7] != "lowerLayerDown" {
+		t.Errorf("ifOperStatus: expected enum 7 lowerLayerDown, got %v", node)
+	}
+}
+
+var mibUriTests = []struct {
+	in  string
+	out string // "" for an error
+}{
+	{"snmp://public@127.0.0.1//(sysDescr.0,IF-MIB::ifDescr.1)",
+		"snmp://public@127.0.0.1//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.2.2.1.2.1)"},
+	{"snmp://public@127.0.0.1//ifTable.*", "snmp://public@127.0.0.1//1.3.6.1.2.1.2.2.*"},
+	{"snmp://public@127.0.0.1//ifTable*", "snmp://public@127.0.0.1//1.3.6.1.2.1.2.2*"},
+	{"snmp://public@127.0.0.1//(ifDescr,ifHCInOctets)+", "snmp://public@127.0.0.1//(1.3.6.1.2.1.2.2.1.2,1.3.6.1.2.1.31.1.1.1.6)+"},
+	{"snmp://public@127.0.0.1/ctx/sysName.0", "snmp://public@127.0.0.1/ctx/1.3.6.1.2.1.1.5.0"},
+	{"snmp://public@127.0.0.1//1.3.6.1.2.1.1.1.0", "snmp://public@127.0.0.1//1.3.6.1.2.1.1.1.0"},
+	{"snmp://127.0.0.1", "snmp://127.0.0.1"},
+	{"snmp://public@127.0.0.1//(sysDescr.0,ifBogus.1)", ""},
+}
+
+func TestMibUri(t *testing.T) {
+	mib, err := LoadMIBs("testing/mibs")
+	if err != nil {
+		t.Fatalf("LoadMIBs error: %s", err)
+	}
+	for i, test := range mibUriTests {
+		out, err := mib.translateUri(test.in)
+		if test.out == "" {
+			var uri_err *UriError
+			if !errors.As(err, &uri_err) || uri_err.Pos != len("snmp://public@127.0.0.1//(sysDescr.0,") {
+				t.Errorf("#%d: translateUri(%s) expected UriError, got %v", i, test.in, err)
+			}
+			continue
+		}
+		if err != nil || out != test.out {
+			t.Errorf("#%d: translateUri(%s) expected %s got %s (%v)", i, test.in, test.out, out, err)
+		}
+	}
+}
+
+func TestMibErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gsnmpgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		module string
+		line   int
+	}{
+		{"BAD-MIB DEFINITIONS ::= BEGIN\n\nfoo OBJECT IDENTIFIER ::= { iso 3\n", 3},
+		{"BAD-MIB DEFINITIONS ::= BEGIN\nfoo OBJECT-TYPE\n  DESCRIPTION \"no end\n", 3},
+		{"BAD-MIB DEFINITIONS ::= BEGIN\nfoo OBJECT IDENTIFIER ::= { iso bar baz }\nEND\n", 2},
+	}
+	for i, test := range tests {
+		file := filepath.Join(dir, "BAD-MIB")
+		if err := ioutil.WriteFile(file, []byte(test.module), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadMIBs(dir)
+		var mib_err *MibError
+		if !errors.As(err, &mib_err) || mib_err.File != file || mib_err.Line != test.line {
+			t.Errorf("#%d: expected MibError at line %d, got %v", i, test.line, err)
+		}
+	}
+
+	var mib_err *MibError
+	if _, err := LoadMIBs(filepath.Join(dir, "missing")); !errors.As(err, &mib_err) {
+		t.Errorf("expected MibError for a missing path, got %v", err)
+	}
+}
+
+func TestMibQuery(t *testing.T) {
+	mib, err := LoadMIBs("testing/mibs")
+	if err != nil {
+		t.Fatalf("LoadMIBs error: %s", err)
+	}
+	Mib = mib
+	defer func() { Mib = nil }()
+
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0])
+	defer agent.Close()
+
+	params := NewDefaultParams(fmt.Sprintf("snmp://public@127.0.0.1:%d//(sysDescr.0,IF-MIB::ifDescr.2)", port))
+	results, err := Query(params)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if results.Len() != 2 {
+		t.Errorf("Query expected 2 results got %d", results.Len())
+	}
+	CompareVerax(t, results, vresults)
+
+	s := NewSession("127.0.0.1", "public", GNET_SNMP_V2C)
+	s.Port = port
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer s.Close()
+	results, err = s.Get([]string{"sysName.0"})
+	if err != nil || results.Get(QueryResult{Oid: MustParseOID("1.3.6.1.2.1.1.5.0")}) == nil {
+		t.Errorf("Session Get(sysName.0) expected a result, got %v", err)
+	}
+	table, err := s.Table("IF-MIB::ifTable", 2)
+	if err != nil || len(table.Rows) != 3 {
+		t.Errorf("Session Table(IF-MIB::ifTable) expected 3 rows, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"fmt"
+)
+
+// OID is an SNMP object identifier, eg 1.3.6.1.2.1.1.1.0 (sysDescr.0).
+//
+// OIDs are compared sub-identifier by sub-identifier as numbers, so
+// 1.3.6.1.2.1.2.2.1.2.10 sorts after 1.3.6.1.2.1.2.2.1.2.9.
+type OID []uint32
+
+// ParseOID converts an oid in dotted string format (with or without a
+// leading dot) to an OID.
+func ParseOID(s string) (OID, error) {
+	oid, err := parseOid(s)
+	if err != nil {
+		return nil, err
+	}
+	return OID(oid), nil
+}
+
+// MustParseOID is like ParseOID, but panics if s isn't a valid oid. It's
+// intended for oids written in source code, eg
+//
+//    results.Get(gsnmpgo.QueryResult{Oid: gsnmpgo.MustParseOID("1.3.6.1.2.1.1.1.0")})
+func MustParseOID(s string) OID {
+	oid, err := ParseOID(s)
+	if err != nil {
+		panic(fmt.Sprintf("%s: MustParseOID(%q): %s", libname(), s, err))
+	}
+	return oid
+}
+
+// Append returns a new OID made of o followed by subs, eg a table column
+// followed by a row index.
+func (o OID) Append(subs ...uint32) OID {
+	result := make(OID, len(o), len(o)+len(subs))
+	copy(result, o)
+	return append(result, subs...)
+}
+
+// Compare returns -1, 0 or 1 depending on whether o is less than, equal to
+// or greater than other.
+func (o OID) Compare(other OID) int {
+	return oidCompare(o, other)
+}
+
+// HasPrefix returns true if o starts with prefix, including when o equals
+// prefix.
+func (o OID) HasPrefix(prefix OID) bool {
+	return len(o) >= len(prefix) && oidCompare(o[:len(prefix)], prefix) == 0
+}
+
+// Index returns the sub-identifiers of o that follow prefix, eg the row index
+// of a table cell given its column. ok is false if o isn't below prefix.
+//
+// For example the index of 1.3.6.1.2.1.2.2.1.2.3 below ifDescr
+// (1.3.6.1.2.1.2.2.1.2) is 3.
+func (o OID) Index(prefix OID) (index OID, ok bool) {
+	if !o.IsChildOf(prefix) {
+		return nil, false
+	}
+	return o[len(prefix):].Append(), true
+}
+
+// IsChildOf returns true if o is below parent in the oid tree; an OID isn't
+// a child of itself.
+func (o OID) IsChildOf(parent OID) bool {
+	return oidInSubtree(parent, o)
+}
+
+// Parent returns the OID above o in the oid tree (o without its last
+// sub-identifier), or nil if o is empty.
+func (o OID) Parent() OID {
+	if len(o) == 0 {
+		return nil
+	}
+	return o[:len(o)-1].Append()
+}
+
+// String returns o in dotted format without a leading dot, eg
+// "1.3.6.1.2.1.1.1.0".
+func (o OID) String() string {
+	return oidString(o)
+}
@@ -0,0 +1,112 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"reflect"
+	"testing"
+)
+
+var oidTests = []struct {
+	in     string
+	oid    OID
+	parent OID
+}{
+	{"1.3.6.1.2.1.1.1.0", OID{1, 3, 6, 1, 2, 1, 1, 1, 0}, OID{1, 3, 6, 1, 2, 1, 1, 1}},
+	{".1.3.6.1.4.1.2021.250.10", OID{1, 3, 6, 1, 4, 1, 2021, 250, 10}, OID{1, 3, 6, 1, 4, 1, 2021, 250}},
+	{"1.3.4294967295", OID{1, 3, 4294967295}, OID{1, 3}},
+	{"1", OID{1}, OID{}},
+}
+
+func TestOID(t *testing.T) {
+	for i, test := range oidTests {
+		oid, err := ParseOID(test.in)
+		if err != nil || !reflect.DeepEqual(oid, test.oid) {
+			t.Errorf("#%d: ParseOID(%s) expected %v got %v (%v)", i, test.in, test.oid, oid, err)
+			continue
+		}
+		if s := oid.String(); "."+s != test.in && s != test.in {
+			t.Errorf("#%d: String() expected %s got %s", i, test.in, s)
+		}
+		if parent := oid.Parent(); !reflect.DeepEqual(parent, test.parent) {
+			t.Errorf("#%d: Parent() expected %v got %v", i, test.parent, parent)
+		}
+	}
+
+	for i, bad := range []string{"", ".", "1.3.6.x", "1..3", "1.3.4294967296"} {
+		if _, err := ParseOID(bad); err == nil {
+			t.Errorf("#%d: ParseOID(%q) expected error", i, bad)
+		}
+	}
+	if OID(nil).Parent() != nil {
+		t.Errorf("expected nil Parent() of empty OID")
+	}
+}
+
+var oidTreeTests = []struct {
+	a, b      string
+	compare   int
+	hasPrefix bool // a.HasPrefix(b)
+	isChild   bool // a.IsChildOf(b)
+	index     string
+}{
+	{"1.3.6.1.2.1.2.2.1.2.3", "1.3.6.1.2.1.2.2.1.2", 1, true, true, "3"},
+	{"1.3.6.1.4.1.9.9.13.1.3.1.3.1007.2", "1.3.6.1.4.1.9.9.13.1.3.1.3", 1, true, true, "1007.2"},
+	{"1.3.6.1.2.1.2.2.1.2", "1.3.6.1.2.1.2.2.1.2", 0, true, false, ""},
+	{"1.3.6.1.2.1.2.2.1.2", "1.3.6.1.2.1.2.2.1.2.3", -1, false, false, ""},
+	{"1.3.6.1.2.1.2.2.1.20", "1.3.6.1.2.1.2.2.1.2", 1, false, false, ""},
+	{"1.3.6.1.2.1.2.2.1.9", "1.3.6.1.2.1.2.2.1.10", -1, false, false, ""},
+}
+
+func TestOIDTree(t *testing.T) {
+	for i, test := range oidTreeTests {
+		a, b := MustParseOID(test.a), MustParseOID(test.b)
+		if compare := a.Compare(b); compare != test.compare {
+			t.Errorf("#%d: %s.Compare(%s) expected %d got %d", i, a, b, test.compare, compare)
+		}
+		if compare := b.Compare(a); compare != -test.compare {
+			t.Errorf("#%d: %s.Compare(%s) expected %d got %d", i, b, a, -test.compare, compare)
+		}
+		if has_prefix := a.HasPrefix(b); has_prefix != test.hasPrefix {
+			t.Errorf("#%d: %s.HasPrefix(%s) expected %t", i, a, b, test.hasPrefix)
+		}
+		if is_child := a.IsChildOf(b); is_child != test.isChild {
+			t.Errorf("#%d: %s.IsChildOf(%s) expected %t", i, a, b, test.isChild)
+		}
+		index, ok := a.Index(b)
+		if ok != test.isChild || (ok && index.String() != test.index) {
+			t.Errorf("#%d: %s.Index(%s) expected %s got %s (%t)", i, a, b, test.index, index, ok)
+		}
+	}
+
+	// Append and Index don't share memory with the original
+	column := MustParseOID("1.3.6.1.2.1.2.2.1.2")
+	cell := column.Append(3)
+	index, _ := cell.Index(column)
+	index[0] = 99
+	if cell.String() != "1.3.6.1.2.1.2.2.1.2.3" || column.String() != "1.3.6.1.2.1.2.2.1.2" {
+		t.Errorf("expected Append and Index to copy, got cell %s column %s", cell, column)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected MustParseOID to panic on an invalid oid")
+		}
+	}()
+	MustParseOID("1.3.6.x")
+}
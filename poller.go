@@ -0,0 +1,331 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"code.google.com/p/tcgl/applog"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/petar/GoLLRB/llrb"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Target is a device polled by a Poller. Several Polls can share a Target,
+// and then share its concurrency limit and backoff.
+type Target struct {
+	Host      string
+	Port      int // defaults to 161
+	Community string
+	Version   SnmpVersion
+	Usm       *UsmParams
+	Timeout   int       // timeout in milliseconds, defaults to that of NewDefaultParams
+	Retries   int       // number of retries
+	Resolver  *Resolver // resolves Host for each query; DefaultResolver is used if it is nil
+	Transport Transport // TRANSPORT_UDP by default; for TRANSPORT_UNIX, Host is the socket's path
+	// MaxConcurrent is the number of queries that can be in flight to the
+	// device at once; 0 means 1.
+	MaxConcurrent int
+}
+
+// Poll is a set of oids to query from a Target every Interval.
+type Poll struct {
+	Target   *Target
+	Oids     []string // oids, or names if Mib is set; gets are split into requests that fit (see GetMany)
+	Walk     bool     // walk the oids (with GETBULK for v2c) instead of getting them
+	Interval time.Duration
+}
+
+// PollResult is the outcome of one query of a Poll.
+type PollResult struct {
+	Poll    *Poll
+	Time    time.Time // when the query was sent
+	Addr    net.Addr  // the address the Target's Host resolved to, if it did
+	Results *llrb.Tree
+	Err     error
+}
+
+// Sample returns the result as a Sample, for use with Rates().
+func (r PollResult) Sample() Sample {
+	return Sample{Time: r.Time, Results: r.Results}
+}
+
+// Poller queries Polls periodically, and delivers the results on the
+// Results() channel or to Callback.
+//
+// Polls start after a random delay of up to Jitter, and each interval is
+// varied by up to Jitter, so that polls with the same interval don't all
+// query at once. At most MaxInFlight queries are in flight at once, and at
+// most Target.MaxConcurrent to each device.
+//
+// When a device doesn't respond (or its Host doesn't resolve), the interval
+// of its polls is doubled after each failure, up to MaxBackoff, and goes
+// back to normal once it responds.
+//
+// Set the fields before the first Add(); changing them afterwards has no
+// effect.
+type Poller struct {
+	MaxInFlight int           // defaults to 10
+	Jitter      time.Duration // defaults to 0, no jitter
+	MaxBackoff  time.Duration // defaults to 10 minutes
+	// If Callback is set, it is called with each result (from the goroutine
+	// running the poll) instead of the result being sent on Results().
+	Callback func(PollResult)
+
+	mu        sync.Mutex
+	started   bool
+	stopped   bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	in_flight chan struct{}
+	polls     map[*Poll]context.CancelFunc
+	targets   map[*Target]*pollTarget
+	results   chan PollResult
+}
+
+// NewPoller returns a Poller with default values.
+func NewPoller() *Poller {
+	return &Poller{
+		MaxInFlight: 10,
+		MaxBackoff:  10 * time.Minute,
+	}
+}
+
+// Add starts polling poll. Adding a Poll that is already being polled is an
+// error, as is adding after Stop().
+func (p *Poller) Add(poll *Poll) error {
+	switch {
+	case poll == nil || poll.Target == nil:
+		return fmt.Errorf("%s: Poller.Add(): poll has no Target", libname())
+	case poll.Target.Host == "":
+		return fmt.Errorf("%s: Poller.Add(): poll Target has no Host", libname())
+	case len(poll.Oids) == 0:
+		return fmt.Errorf("%s: Poller.Add(): poll has no Oids", libname())
+	case poll.Interval <= 0:
+		return fmt.Errorf("%s: Poller.Add(): poll Interval must be positive", libname())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return fmt.Errorf("%s: Poller.Add(): poller is stopped", libname())
+	}
+	p.start()
+	if _, ok := p.polls[poll]; ok {
+		return fmt.Errorf("%s: Poller.Add(): poll is already added", libname())
+	}
+	target, ok := p.targets[poll.Target]
+	if !ok {
+		concurrent := poll.Target.MaxConcurrent
+		if concurrent < 1 {
+			concurrent = 1
+		}
+		target = &pollTarget{in_flight: make(chan struct{}, concurrent)}
+		p.targets[poll.Target] = target
+	}
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.polls[poll] = cancel
+	p.wg.Add(1)
+	go p.run(ctx, poll, target)
+	return nil
+}
+
+// Remove stops polling poll. A query that is in flight is finished, but its
+// result isn't delivered.
+func (p *Poller) Remove(poll *Poll) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.polls[poll]; ok {
+		cancel()
+		delete(p.polls, poll)
+	}
+}
+
+// Results returns the channel results are delivered on, when Callback isn't
+// set. It is closed by Stop(). Results must be read promptly, as polls wait
+// for their results to be read.
+func (p *Poller) Results() <-chan PollResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.start()
+	return p.results
+}
+
+// Stop stops all polls, waits for the queries in flight to finish, and
+// closes the Results() channel.
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.start()
+	p.stopped = true
+	p.cancel()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	close(p.results)
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// deliver sends result on the results channel or to Callback. It returns
+// false if the poll was stopped first.
+func (p *Poller) deliver(ctx context.Context, result PollResult) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if p.Callback != nil {
+		p.Callback(result)
+		return true
+	}
+	select {
+	case p.results <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// jitter returns a random delay of up to p.Jitter.
+func (p *Poller) jitter() time.Duration {
+	if p.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(p.Jitter)))
+}
+
+// pollInterval returns the interval before the next query of a poll, backed
+// off because its device has failed to respond failures times in a row. A
+// max_backoff of 0 is the default of 10 minutes.
+func pollInterval(interval, max_backoff time.Duration, failures int) time.Duration {
+	if max_backoff == 0 {
+		max_backoff = 10 * time.Minute
+	}
+	backoff := interval
+	for i := 0; i < failures && backoff < max_backoff; i++ {
+		backoff *= 2
+	}
+	if backoff > max_backoff && max_backoff > interval {
+		return max_backoff
+	}
+	return backoff
+}
+
+// pollTarget is the state shared by the polls of a Target.
+type pollTarget struct {
+	in_flight chan struct{} // limits the queries in flight to the device
+	mu        sync.Mutex
+	failures  int // consecutive queries the device didn't respond to
+}
+
+// query does one query of poll, once there's room in flight for it.
+func (p *Poller) query(ctx context.Context, poll *Poll, target *pollTarget) (result PollResult, ok bool) {
+	// wait for the device before taking one of the poller's slots, so a
+	// busy device doesn't hold up the others
+	for _, in_flight := range []chan struct{}{target.in_flight, p.in_flight} {
+		select {
+		case in_flight <- struct{}{}:
+			defer func(in_flight chan struct{}) { <-in_flight }(in_flight)
+		case <-ctx.Done():
+			return result, false
+		}
+	}
+
+	result = PollResult{Poll: poll, Time: time.Now()}
+	session := NewSession(poll.Target.Host, poll.Target.Community, poll.Target.Version)
+	if poll.Target.Port != 0 {
+		session.Port = poll.Target.Port
+	}
+	if poll.Target.Timeout != 0 {
+		session.Timeout = poll.Target.Timeout
+	}
+	session.Retries = poll.Target.Retries
+	session.Usm = poll.Target.Usm
+	session.Resolver = poll.Target.Resolver
+	session.Transport = poll.Target.Transport
+	if result.Err = session.Open(); result.Err != nil {
+		return result, true
+	}
+	defer session.Close()
+	result.Addr = session.Addr()
+	if poll.Walk {
+		result.Results, result.Err = session.BulkWalk(poll.Oids)
+	} else {
+		result.Results, result.Err = session.GetMany(poll.Oids)
+	}
+	return result, true
+}
+
+// run queries poll every interval until ctx is cancelled.
+func (p *Poller) run(ctx context.Context, poll *Poll, target *pollTarget) {
+	defer p.wg.Done()
+	delay := p.jitter()
+	for {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		result, ok := p.query(ctx, poll, target)
+		if !ok {
+			return
+		}
+
+		var session_err *SessionError
+		unreachable := errors.Is(result.Err, ErrTimeout) || errors.As(result.Err, &session_err)
+		target.mu.Lock()
+		if unreachable {
+			target.failures++
+		} else {
+			target.failures = 0
+		}
+		failures := target.failures
+		target.mu.Unlock()
+		if unreachable && Debug {
+			applog.Warningf("%s: poll of %s failed %d times: %s", libname(), poll.Target.Host, failures, result.Err)
+		}
+
+		if !p.deliver(ctx, result) {
+			return
+		}
+		delay = pollInterval(poll.Interval, p.MaxBackoff, failures) + p.jitter()
+	}
+}
+
+// start initialises the poller on first use; p.mu must be held.
+func (p *Poller) start() {
+	if p.started {
+		return
+	}
+	p.started = true
+	max_in_flight := p.MaxInFlight
+	if max_in_flight < 1 {
+		max_in_flight = 10
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.in_flight = make(chan struct{}, max_in_flight)
+	p.polls = make(map[*Poll]context.CancelFunc)
+	p.targets = make(map[*Target]*pollTarget)
+	p.results = make(chan PollResult)
+}
@@ -0,0 +1,237 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoller(t *testing.T) {
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0])
+	defer agent.Close()
+
+	target := &Target{Host: "127.0.0.1", Port: port, Community: "public", Version: GNET_SNMP_V2C}
+	get := &Poll{Target: target, Oids: []string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.3.0"}, Interval: 10 * time.Millisecond}
+	walk := &Poll{Target: target, Oids: []string{"1.3.6.1.2.1.2.2.1.2"}, Walk: true, Interval: 10 * time.Millisecond}
+
+	poller := NewPoller()
+	poller.Jitter = 5 * time.Millisecond
+	for _, poll := range []*Poll{get, walk} {
+		if err := poller.Add(poll); err != nil {
+			t.Fatalf("Add error: %s", err)
+		}
+	}
+	if err := poller.Add(get); err == nil {
+		t.Errorf("Add of a poll twice expected error")
+	}
+
+	counts := make(map[*Poll]int)
+	for counts[get] < 3 || counts[walk] < 3 {
+		result := <-poller.Results()
+		if result.Err != nil {
+			t.Fatalf("poll error: %s", result.Err)
+		}
+		if result.Time.IsZero() || result.Results == nil || result.Results.Len() == 0 {
+			t.Fatalf("poll returned no results: %+v", result)
+		}
+		CompareVerax(t, result.Results, vresults)
+		counts[result.Poll]++
+	}
+
+	// Results() is closed by Stop()
+	poller.Remove(walk)
+	poller.Stop()
+	for range poller.Results() {
+	}
+	if err := poller.Add(walk); err == nil {
+		t.Errorf("Add after Stop expected error")
+	}
+}
+
+func TestPollerGetMany(t *testing.T) {
+	// small enough that MAX_URI_COUNT oids get tooBig
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0], func(a *Agent) { a.MaxMessage = 1000 })
+	defer agent.Close()
+
+	var oids []string
+	ch := vresults.IterAscend()
+	for {
+		r := <-ch
+		if r == nil {
+			break
+		}
+		oids = append(oids, r.(QueryResult).Oid.String())
+	}
+	// more than fit in one request
+	for len(oids) <= MAX_URI_COUNT {
+		oids = append(oids, oids...)
+	}
+
+	target := &Target{Host: "127.0.0.1", Port: port, Community: "public", Version: GNET_SNMP_V2C}
+	poller := NewPoller()
+	defer poller.Stop()
+	if err := poller.Add(&Poll{Target: target, Oids: oids, Interval: time.Second}); err != nil {
+		t.Fatalf("Add error: %s", err)
+	}
+	result := <-poller.Results()
+	if result.Err != nil {
+		t.Fatalf("poll error: %s", result.Err)
+	}
+	if result.Results.Len() != vresults.Len() {
+		t.Errorf("poll expected %d results got %d", vresults.Len(), result.Results.Len())
+	}
+	CompareVerax(t, result.Results, vresults)
+}
+
+func TestPollerCallback(t *testing.T) {
+	_, agent, port, _ := veraxAgent(t, veraxDevices[0])
+	defer agent.Close()
+
+	var mu sync.Mutex
+	var in_flight, max_in_flight, count int
+	done := make(chan bool)
+	poller := NewPoller()
+	poller.MaxInFlight = 2
+	poller.Callback = func(result PollResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if result.Err != nil {
+			t.Errorf("poll error: %s", result.Err)
+		}
+		if count++; count == 20 {
+			close(done)
+		}
+	}
+
+	// several targets for the one agent, each allowing 2 queries at once
+	for i := 0; i < 4; i++ {
+		target := &Target{Host: "127.0.0.1", Port: port, Community: "public", Version: GNET_SNMP_V2C, MaxConcurrent: 2}
+		for j := 0; j < 2; j++ {
+			poll := &Poll{Target: target, Oids: []string{"1.3.6.1.2.1.1.1.0"}, Interval: time.Millisecond}
+			if err := poller.Add(poll); err != nil {
+				t.Fatalf("Add error: %s", err)
+			}
+		}
+	}
+
+	// watch the poller's slots while the polls run
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			mu.Lock()
+			if in_flight = len(poller.in_flight); in_flight > max_in_flight {
+				max_in_flight = in_flight
+			}
+			mu.Unlock()
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for callbacks")
+	}
+	poller.Stop()
+	if max_in_flight > 2 {
+		t.Errorf("expected at most 2 queries in flight, got %d", max_in_flight)
+	}
+}
+
+func TestPollerBackoff(t *testing.T) {
+	// a port with nothing listening on it
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket error: %s", err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	target := &Target{Host: "127.0.0.1", Port: port, Community: "public", Version: GNET_SNMP_V2C, Timeout: 10}
+	poll := &Poll{Target: target, Oids: []string{"1.3.6.1.2.1.1.1.0"}, Interval: 10 * time.Millisecond}
+	poller := NewPoller()
+	poller.MaxBackoff = 40 * time.Millisecond
+	if err := poller.Add(poll); err != nil {
+		t.Fatalf("Add error: %s", err)
+	}
+	var times []time.Time
+	for len(times) < 4 {
+		result := <-poller.Results()
+		if !errors.Is(result.Err, ErrTimeout) {
+			t.Fatalf("expected a timeout, got %v", result.Err)
+		}
+		times = append(times, result.Time)
+	}
+	poller.Stop()
+
+	// 10ms timeout, then a 20ms, 40ms and 40ms backoff
+	if gap := times[3].Sub(times[2]); gap < 40*time.Millisecond {
+		t.Errorf("expected backoff of at least 40ms, got %s", gap)
+	}
+}
+
+var pollIntervalTests = []struct {
+	interval    time.Duration
+	max_backoff time.Duration
+	failures    int
+	expected    time.Duration
+}{
+	{time.Minute, 10 * time.Minute, 0, time.Minute},
+	{time.Minute, 10 * time.Minute, 1, 2 * time.Minute},
+	{time.Minute, 10 * time.Minute, 3, 8 * time.Minute},
+	{time.Minute, 10 * time.Minute, 4, 10 * time.Minute},
+	{time.Minute, 10 * time.Minute, 100, 10 * time.Minute},
+	{time.Hour, 10 * time.Minute, 5, time.Hour},
+	{time.Minute, 0, 2, 4 * time.Minute},
+	{time.Minute, 0, 5, 10 * time.Minute},
+}
+
+func TestPollInterval(t *testing.T) {
+	for i, test := range pollIntervalTests {
+		if interval := pollInterval(test.interval, test.max_backoff, test.failures); interval != test.expected {
+			t.Errorf("#%d: pollInterval(%s, %s, %d) expected %s got %s",
+				i, test.interval, test.max_backoff, test.failures, test.expected, interval)
+		}
+	}
+}
+
+func TestPollerAddErrors(t *testing.T) {
+	target := &Target{Host: "127.0.0.1"}
+	polls := []*Poll{
+		nil,
+		{Oids: []string{"1.3.6.1.2.1.1.1.0"}, Interval: time.Second},
+		{Target: &Target{}, Oids: []string{"1.3.6.1.2.1.1.1.0"}, Interval: time.Second},
+		{Target: target, Interval: time.Second},
+		{Target: target, Oids: []string{"1.3.6.1.2.1.1.1.0"}},
+	}
+	poller := NewPoller()
+	defer poller.Stop()
+	for i, poll := range polls {
+		if err := poller.Add(poll); err == nil {
+			t.Errorf("#%d: Add(%+v) expected error", i, poll)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// purego.go is the pure Go backend, used when building with the purego tag
+// (or without cgo). It doesn't need gsnmp or glib: queries are done by the Go
+// client in client.go.
+
+import (
+	"context"
+	"github.com/petar/GoLLRB/llrb"
+)
+
+// Workers is kept for compatibility, and is ignored, as it is by the gsnmp
+// backend. In the pure Go backend each query uses its own socket.
+var Workers = 1
+
+// runQuery does the work of Query().
+func runQuery(ctx context.Context, params *QueryParams) (results *llrb.Tree, status *QueryStatus, err error) {
+	return goQuery(ctx, params)
+}
+
+// runSet does the work of Set().
+func runSet(params *QueryParams, varbinds []QueryResult) (results *llrb.Tree, err error) {
+	return goSet(params, varbinds)
+}
@@ -0,0 +1,155 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// tests for the pure Go backend
+
+import (
+	"errors"
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPureGoQuery(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP error: %s", err)
+	}
+	defer conn.Close()
+
+	// answer GETs from walkMib, and reject everything else with genErr
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			length, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			msg, err := decodeMessage(buf[:length])
+			if err != nil {
+				continue
+			}
+			if msg.pduType != pduGet {
+				msg.errorStatus, msg.errorIndex = int(GNET_SNMP_PDU_ERR_GENERROR), 1
+			}
+			for i, varbind := range msg.varbinds {
+				msg.varbinds[i].Value = new(VBT_NoSuchObject)
+				for _, mib_varbind := range walkMib {
+					if mib_varbind.Oid.Compare(varbind.Oid) == 0 {
+						msg.varbinds[i].Value = mib_varbind.Value
+					}
+				}
+			}
+			msg.pduType = pduResponse
+			packet, _ := encodeMessage(msg)
+			conn.WriteToUDP(packet, addr)
+		}
+	}()
+
+	uri := "snmp://public@" + conn.LocalAddr().String() + "//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.2.0)"
+	results, err := Query(NewDefaultParams(uri))
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	var got []string
+	ch := results.IterAscend()
+	for r := <-ch; r != nil; r = <-ch {
+		result := r.(QueryResult)
+		got = append(got, result.Oid.String()+" "+result.Value.String())
+	}
+	sort.Strings(got)
+	expected := []string{"1.3.6.1.2.1.1.1.0 Linux", "1.3.6.1.2.1.1.2.0 " + new(VBT_NoSuchObject).String()}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Query expected %v got %v", expected, got)
+	}
+
+	results, err = Set(NewDefaultParams(uri), []QueryResult{{Oid: MustParseOID("1.3.6.1.2.1.1.5.0"), Value: VBT_OctetString("x")}})
+	if agent_err, ok := err.(*AgentError); !ok || agent_err.Status != GNET_SNMP_PDU_ERR_GENERROR {
+		t.Errorf("expected genErr AgentError from Set, got %v", err)
+	}
+	if results == nil || results.Get(QueryResult{Oid: MustParseOID("1.3.6.1.2.1.1.5.0")}) == nil {
+		t.Errorf("expected the response's varbinds with the Set error")
+	}
+
+	params := NewDefaultParams(uri)
+	params.Version = GNET_SNMP_V3
+	if _, err = Query(params); err == nil {
+		t.Errorf("expected error for v3 query")
+	}
+}
+
+func TestPureGoStream(t *testing.T) {
+	// an agent that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	params := NewDefaultParams("snmp+tcp://public@" + listener.Addr().String() + "//1.3.6.1.2.1.1.1.0")
+	params.Timeout, params.Retries = 50, 1
+	_, status, err := QueryWithStatus(params)
+	if !errors.Is(err, ErrTimeout) || status == nil || status.Error != GNET_SNMP_PDU_ERR_NORESPONSE {
+		t.Errorf("expected a timeout from a silent agent, got %v", err)
+	}
+
+	// a Session redials when its connection is broken, eg by the agent
+	// restarting
+	agent, _, ok := streamAgent(t, "unix")
+	if !ok {
+		return
+	}
+	socket := agent.Addr().String()
+	s := NewSession(socket, "public", GNET_SNMP_V2C)
+	s.Transport = TRANSPORT_UNIX
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer s.Close()
+	if _, err := s.Get([]string{"1.3.6.1.2.1.1.1.0"}); err != nil {
+		t.Fatalf("Get error: %s", err)
+	}
+	agent.Close()
+	agent = NewAgent(llrb.New(LessOID))
+	agent.tree.ReplaceOrInsert(walkMib[0])
+	if err := agent.ListenStream("unix", socket); err != nil {
+		t.Fatalf("ListenStream error: %s", err)
+	}
+	defer agent.Close()
+	results, err := s.Get([]string{"1.3.6.1.2.1.1.1.0"})
+	if err != nil {
+		t.Fatalf("Get after the agent restarted error: %s", err)
+	}
+	if value := results.Min().(QueryResult).Value.String(); value != "Linux" {
+		t.Errorf("expected Linux from the restarted agent, got %s", value)
+	}
+}
@@ -0,0 +1,148 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"fmt"
+	"github.com/petar/GoLLRB/llrb"
+	"math"
+	"time"
+)
+
+// Sample is the results of one poll of an agent, and when it was made.
+type Sample struct {
+	Time    time.Time
+	Results *llrb.Tree
+}
+
+// Rate is the change in a counter (a VBT_Counter32 or VBT_Counter64)
+// between two Samples.
+type Rate struct {
+	Oid       OID
+	Delta     uint64        // the increase in the counter
+	Elapsed   time.Duration // the time between the samples
+	PerSecond float64       // Delta / Elapsed
+	Wrapped   bool          // the counter wrapped past its maximum
+	Reset     bool          // the counter was discontinuous (eg the agent restarted), so Delta and PerSecond are 0
+}
+
+// Rates returns the rate of change of each counter found in both previous
+// and current, in oid order. Other types (including Gauge32, which is
+// VBT_Unsigned32) are ignored, as are counters whose type changed.
+//
+// If both samples have sysUpTime.0 and it went down, the agent restarted
+// and its counters started again from 0: every Rate is flagged as Reset and
+// has no Delta, as there's no knowing what the counters were when the agent
+// restarted.
+//
+// Otherwise a counter that went down has wrapped, at 2^32 for a Counter32 and
+// 2^64 for a Counter64, and Delta is the increase modulo that. Include
+// sysUpTime.0 in the samples, as without it a restart is taken for a wrap.
+func Rates(previous, current Sample) (rates []Rate, err error) {
+	elapsed := current.Time.Sub(previous.Time)
+	if elapsed <= 0 {
+		return nil, fmt.Errorf("%s: Rates(): samples are out of order (elapsed %s)", libname(), elapsed)
+	}
+	restarted := rateRestarted(previous.Results, current.Results, elapsed)
+
+	ch := current.Results.IterAscend()
+	for {
+		r := <-ch
+		if r == nil {
+			break
+		}
+		result := r.(QueryResult)
+		before := previous.Results.Get(QueryResult{Oid: result.Oid})
+		if before == nil {
+			continue
+		}
+		rate := Rate{Oid: result.Oid, Elapsed: elapsed, Reset: restarted}
+		switch now := result.Value.(type) {
+		case VBT_Counter32:
+			then, ok := before.(QueryResult).Value.(VBT_Counter32)
+			if !ok {
+				continue
+			}
+			rate.Delta, rate.Wrapped = uint64(now-then), now < then // uint32 arithmetic wraps
+		case VBT_Counter64:
+			then, ok := before.(QueryResult).Value.(VBT_Counter64)
+			if !ok {
+				continue
+			}
+			rate.Delta, rate.Wrapped = uint64(now-then), now < then // as does uint64
+		default:
+			continue
+		}
+		if rate.Reset {
+			rate.Delta, rate.Wrapped = 0, false
+		} else {
+			rate.PerSecond = float64(rate.Delta) / elapsed.Seconds()
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// RateTracker computes Rates between successive polls of an agent. The
+// zero value is ready to use.
+//
+// Example:
+//
+//    var tracker gsnmpgo.RateTracker
+//    for range time.Tick(time.Minute) {
+//        results, err := gsnmpgo.Query(params) // including sysUpTime.0
+//        ...
+//        rates, err := tracker.Add(time.Now(), results)
+//    }
+type RateTracker struct {
+	last *Sample
+}
+
+// Add records the results of a poll made at time t, and returns the Rates
+// since the previous poll; it returns no Rates for the first poll. A poll
+// that returns an error is not recorded.
+func (rt *RateTracker) Add(t time.Time, results *llrb.Tree) (rates []Rate, err error) {
+	sample := &Sample{Time: t, Results: results}
+	if rt.last != nil {
+		if rates, err = Rates(*rt.last, *sample); err != nil {
+			return nil, err
+		}
+	}
+	rt.last = sample
+	return rates, nil
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// rateRestarted returns true if both the previous and current results have
+// sysUpTime.0 and it went down, ie the agent restarted. sysUpTime.0 itself
+// wraps after 497 days; that isn't a restart if the uptime expected from
+// elapsed would have passed 2^32.
+func rateRestarted(previous, current *llrb.Tree, elapsed time.Duration) bool {
+	before, now := previous.Get(QueryResult{Oid: sysUpTimeOid}), current.Get(QueryResult{Oid: sysUpTimeOid})
+	if before == nil || now == nil {
+		return false
+	}
+	then, ok_then := before.(QueryResult).Value.(VBT_Timeticks)
+	uptime, ok_now := now.(QueryResult).Value.(VBT_Timeticks)
+	if !ok_then || !ok_now || uptime >= then {
+		return false
+	}
+	expected := uint64(then) + uint64(elapsed/(10*time.Millisecond))
+	return expected <= math.MaxUint32
+}
@@ -0,0 +1,163 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"github.com/petar/GoLLRB/llrb"
+	"testing"
+	"time"
+)
+
+var (
+	ifInOctets2   = MustParseOID("1.3.6.1.2.1.2.2.1.10.2")
+	ifHCInOctets2 = MustParseOID("1.3.6.1.2.1.31.1.1.1.6.2")
+	ifMtu2        = MustParseOID("1.3.6.1.2.1.2.2.1.4.2")
+)
+
+// rateResults returns a tree of results for testing Rates.
+func rateResults(results ...QueryResult) *llrb.Tree {
+	tree := llrb.New(LessOID)
+	for _, result := range results {
+		tree.ReplaceOrInsert(result)
+	}
+	return tree
+}
+
+var ratesTests = []struct {
+	previous []QueryResult
+	current  []QueryResult
+	expected []Rate
+}{
+	// a plain increase; ifMtu isn't a counter
+	{
+		[]QueryResult{{ifInOctets2, VBT_Counter32(1000)}, {ifMtu2, VBT_Integer32(1500)}},
+		[]QueryResult{{ifInOctets2, VBT_Counter32(7000)}, {ifMtu2, VBT_Integer32(1500)}},
+		[]Rate{{Oid: ifInOctets2, Delta: 6000, PerSecond: 100}},
+	},
+	// 32 bit wrap, sysUpTime shows the agent didn't restart
+	{
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4381200)}, {ifInOctets2, VBT_Counter32(4294967000)}},
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4387200)}, {ifInOctets2, VBT_Counter32(5704)}},
+		[]Rate{{Oid: ifInOctets2, Delta: 6000, PerSecond: 100, Wrapped: true}},
+	},
+	// 32 bit wrap without sysUpTime
+	{
+		[]QueryResult{{ifInOctets2, VBT_Counter32(4294967000)}},
+		[]QueryResult{{ifInOctets2, VBT_Counter32(5704)}},
+		[]Rate{{Oid: ifInOctets2, Delta: 6000, PerSecond: 100, Wrapped: true}},
+	},
+	// 64 bit wrap, with and without sysUpTime
+	{
+		[]QueryResult{{ifHCInOctets2, VBT_Counter64(18446744073709551000)}},
+		[]QueryResult{{ifHCInOctets2, VBT_Counter64(5384)}},
+		[]Rate{{Oid: ifHCInOctets2, Delta: 6000, PerSecond: 100, Wrapped: true}},
+	},
+	{
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4381200)}, {ifHCInOctets2, VBT_Counter64(18446744073709551000)}},
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4387200)}, {ifHCInOctets2, VBT_Counter64(5384)}},
+		[]Rate{{Oid: ifHCInOctets2, Delta: 6000, PerSecond: 100, Wrapped: true}},
+	},
+	// a Counter64 went down because the agent restarted
+	{
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4381200)}, {ifHCInOctets2, VBT_Counter64(90000)}},
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(3000)}, {ifHCInOctets2, VBT_Counter64(5384)}},
+		[]Rate{{Oid: ifHCInOctets2, Reset: true}},
+	},
+	// a Counter64 increase
+	{
+		[]QueryResult{{ifHCInOctets2, VBT_Counter64(18446744073709545000)}},
+		[]QueryResult{{ifHCInOctets2, VBT_Counter64(18446744073709551000)}},
+		[]Rate{{Oid: ifHCInOctets2, Delta: 6000, PerSecond: 100}},
+	},
+	// sysUpTime went up, no reset
+	{
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4381200)}, {ifInOctets2, VBT_Counter32(1000)}},
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4387200)}, {ifInOctets2, VBT_Counter32(1600)}},
+		[]Rate{{Oid: ifInOctets2, Delta: 600, PerSecond: 10}},
+	},
+	// sysUpTime went down: the agent restarted
+	{
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4381200)}, {ifInOctets2, VBT_Counter32(1000)}},
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(3000)}, {ifInOctets2, VBT_Counter32(10)}},
+		[]Rate{{Oid: ifInOctets2, Reset: true}},
+	},
+	// sysUpTime wrapped after 497 days, not a restart
+	{
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4294966000)}, {ifInOctets2, VBT_Counter32(1000)}},
+		[]QueryResult{{sysUpTimeOid, VBT_Timeticks(4704)}, {ifInOctets2, VBT_Counter32(1600)}},
+		[]Rate{{Oid: ifInOctets2, Delta: 600, PerSecond: 10}},
+	},
+	// missing from one sample, or changed type
+	{
+		[]QueryResult{{ifInOctets2, VBT_Counter32(1000)}},
+		[]QueryResult{{ifInOctets2, VBT_Counter64(1600)}, {ifHCInOctets2, VBT_Counter64(1)}},
+		nil,
+	},
+}
+
+func TestRates(t *testing.T) {
+	start := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	elapsed := time.Minute
+	for i, test := range ratesTests {
+		previous := Sample{Time: start, Results: rateResults(test.previous...)}
+		current := Sample{Time: start.Add(elapsed), Results: rateResults(test.current...)}
+		rates, err := Rates(previous, current)
+		if err != nil {
+			t.Errorf("#%d: Rates() error: %s", i, err)
+			continue
+		}
+		if len(rates) != len(test.expected) {
+			t.Errorf("#%d: Rates() expected %d rates got %d: %v", i, len(test.expected), len(rates), rates)
+			continue
+		}
+		for j, expected := range test.expected {
+			expected.Elapsed = elapsed
+			got := rates[j]
+			if got.Oid.Compare(expected.Oid) != 0 || got.Delta != expected.Delta || got.Elapsed != expected.Elapsed ||
+				got.PerSecond != expected.PerSecond || got.Wrapped != expected.Wrapped || got.Reset != expected.Reset {
+				t.Errorf("#%d: Rates() expected %+v got %+v", i, expected, got)
+			}
+		}
+	}
+
+	results := rateResults()
+	if _, err := Rates(Sample{start, results}, Sample{start, results}); err == nil {
+		t.Errorf("Rates() with no elapsed time expected error")
+	}
+}
+
+func TestRateTracker(t *testing.T) {
+	var tracker RateTracker
+	start := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	polls := []uint32{1000, 2000, 2500}
+	expected := []int{0, 1, 1}
+	for i, poll := range polls {
+		results := rateResults(QueryResult{ifInOctets2, VBT_Counter32(poll)})
+		rates, err := tracker.Add(start.Add(time.Duration(i)*time.Second), results)
+		if err != nil {
+			t.Errorf("#%d: Add() error: %s", i, err)
+			continue
+		}
+		if len(rates) != expected[i] {
+			t.Errorf("#%d: Add() expected %d rates got %d", i, expected[i], len(rates))
+		}
+	}
+	if _, err := tracker.Add(start, rateResults()); err == nil {
+		t.Errorf("Add() of an earlier poll expected error")
+	}
+}
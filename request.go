@@ -0,0 +1,246 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// request.go contains Request, a query made without a uri.
+// github.com/natefinch/gocog is used to generate the boilerplate for
+// Operation. AFTER EDITING any gocog sections (between gocog open and close
+// square brackets), you MUST run:
+//
+//     rm -f request.go_cog; $GOPATH/bin/gocog request.go; go fmt ./...
+
+import (
+	"github.com/petar/GoLLRB/llrb"
+)
+
+// Operation is the kind of SNMP request made by a Request:
+//
+//     OP_GET       GET of Oids, split into several requests if needed (see GetMany)
+//     OP_GETNEXT   GETNEXT of Oids
+//     OP_WALK      walk of Oids using GETNEXT
+//     OP_BULKWALK  walk of Oids using GETBULK (GETNEXT for v1)
+//     OP_SET       SET of Varbinds
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"OP_GET", "OP_GETNEXT", "OP_WALK", "OP_BULKWALK", "OP_SET"}
+	enumconv.WriteGo("Operation", "Operation", vals, 0)
+}
+gocog]]]*/
+
+// type and values for Operation
+type Operation int
+
+const (
+	OP_GET Operation = iota
+	OP_GETNEXT
+	OP_WALK
+	OP_BULKWALK
+	OP_SET
+)
+
+// Stringer for Operation
+func (operation Operation) String() string {
+	switch operation {
+	case OP_GET:
+		return "OP_GET"
+	case OP_GETNEXT:
+		return "OP_GETNEXT"
+	case OP_WALK:
+		return "OP_WALK"
+	case OP_BULKWALK:
+		return "OP_BULKWALK"
+	case OP_SET:
+		return "OP_SET"
+	}
+	return "UNKNOWN Operation"
+}
+
+//[[[end]]]
+
+// Request is a query, as an alternative to an RFC 4088 uri. Nothing is
+// parsed, so a community can contain any characters, and SETs and v3 can be
+// expressed too.
+type Request struct {
+	Host      string
+	Port      int // defaults to 161
+	Community string
+	Version   SnmpVersion
+	Usm       *UsmParams // the v3 credentials, see QueryParams
+	Timeout   int        // timeout in milliseconds
+	Retries   int        // number of retries
+	Nonrep    int        // used by OP_BULKWALK, see QueryParams
+	Maxrep    int        // used by OP_BULKWALK, see QueryParams
+	Resolver  *Resolver  // resolves Host; DefaultResolver is used if it is nil
+	Transport Transport  // TRANSPORT_UDP by default; for TRANSPORT_UNIX, Host is the socket's path
+
+	Operation Operation
+	Oids      []string      // oids, or names if Mib is set; not used by OP_SET
+	Varbinds  []QueryResult // the values to set, for OP_SET
+}
+
+// NewRequest returns a Request with the same defaults as NewDefaultParams.
+func NewRequest(host, community string, version SnmpVersion, op Operation, oids ...string) *Request {
+	defaults := NewDefaultParams("")
+	return &Request{
+		Host:      host,
+		Port:      161,
+		Community: community,
+		Version:   version,
+		Timeout:   defaults.Timeout,
+		Retries:   defaults.Retries,
+		Nonrep:    defaults.Nonrep,
+		Maxrep:    defaults.Maxrep,
+		Operation: op,
+		Oids:      oids,
+	}
+}
+
+// ParseRequest converts an RFC 4088 uri (as given to Query) to a Request,
+// with the defaults of NewDefaultParams. Walks are OP_BULKWALK, as they are
+// with Query. Names can be used in the uri if Mib is set.
+//
+// A uri can't give the version, so the Request is SNMP v2c, unless the uri
+// has a context (eg snmp://user@host/ctx;engine/1.3.6.1.2.1.1.1.0), which
+// only exists in SNMP v3. The Request is then v3, with a Usm of the uri's
+// user, context name and context engine id; set the security level and
+// passwords in it before querying.
+//
+// Percent-encoded characters in the community (eg %40 for '@') and context
+// are decoded. Errors are a *UriError.
+func ParseRequest(uri string) (req *Request, err error) {
+	if Mib != nil {
+		if uri, err = Mib.translateUri(uri); err != nil {
+			return nil, err
+		}
+	}
+	parsed, err := parseSnmpUri(uri)
+	if err != nil {
+		return nil, err
+	}
+	req = NewRequest(parsed.host, parsed.community, GNET_SNMP_V2C, OP_GET)
+	req.Port = parsed.port
+	req.Transport = parsed.transport
+	if parsed.context != "" || parsed.engineID != "" {
+		req.Version = GNET_SNMP_V3
+		req.Usm = &UsmParams{
+			UserName:        parsed.community,
+			ContextName:     parsed.context,
+			ContextEngineID: parsed.engineID,
+		}
+	}
+	switch parsed.uritype {
+	case GNET_SNMP_URI_NEXT:
+		req.Operation = OP_GETNEXT
+	case GNET_SNMP_URI_WALK:
+		req.Operation = OP_BULKWALK
+	}
+	for _, oid := range parsed.oids {
+		req.Oids = append(req.Oids, oidString(oid))
+	}
+	return req, nil
+}
+
+// QueryRequest does the query described by req, and returns the results.
+// Errors are the same as those of Query and Set; a Request without Oids (or
+// Varbinds, for OP_SET) or with an unknown Operation is a *SessionError.
+func QueryRequest(req *Request) (results *llrb.Tree, err error) {
+	if req.Operation == OP_SET {
+		if len(req.Varbinds) == 0 {
+			return nil, &SessionError{Msg: "QueryRequest(): no Varbinds to set"}
+		}
+	} else if len(req.Oids) == 0 {
+		return nil, &SessionError{Msg: "QueryRequest(): no Oids"}
+	}
+
+	s := &Session{
+		Host:      req.Host,
+		Port:      req.Port,
+		Community: req.Community,
+		Version:   req.Version,
+		Timeout:   req.Timeout,
+		Retries:   req.Retries,
+		Nonrep:    req.Nonrep,
+		Maxrep:    req.Maxrep,
+		Usm:       req.Usm,
+		Resolver:  req.Resolver,
+		Transport: req.Transport,
+	}
+	if err = s.Open(); err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	switch req.Operation {
+	case OP_GET:
+		return s.GetMany(req.Oids)
+	case OP_GETNEXT:
+		return s.GetNext(req.Oids)
+	case OP_WALK:
+		return s.Walk(req.Oids)
+	case OP_BULKWALK:
+		return s.BulkWalk(req.Oids)
+	case OP_SET:
+		return s.Set(req.Varbinds)
+	}
+	return nil, &SessionError{Msg: "QueryRequest(): unknown operation " + req.Operation.String()}
+}
+
+// Uri converts req to an RFC 4088 uri, eg
+// snmp://public@192.168.1.10//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.3.0), or
+// snmp+tcp:// or snmp+unix:// for the other transports. The community and
+// the context of a v3 Usm are percent-encoded if necessary; names in Oids
+// are converted to oids using Mib.
+//
+// A SET can't be expressed as a uri, and neither can the Version, Timeout,
+// Retries, Nonrep, Maxrep and Resolver fields nor the Usm's credentials;
+// use them to make QueryParams for the uri. Errors are a *UriError.
+func (req *Request) Uri() (uri string, err error) {
+	u := &snmpUri{
+		community: req.Community,
+		host:      req.Host,
+		port:      req.Port,
+		transport: req.Transport,
+	}
+	if req.Version == GNET_SNMP_V3 && req.Usm != nil {
+		u.community, u.context, u.engineID = req.Usm.UserName, req.Usm.ContextName, req.Usm.ContextEngineID
+	}
+	switch req.Operation {
+	case OP_GET:
+		u.uritype = GNET_SNMP_URI_GET
+	case OP_GETNEXT:
+		u.uritype = GNET_SNMP_URI_NEXT
+	case OP_WALK, OP_BULKWALK:
+		u.uritype = GNET_SNMP_URI_WALK
+	default:
+		return "", &UriError{Uri: u.String(), Pos: -1, Msg: "Uri(): " + req.Operation.String() + " can't be expressed as a uri"}
+	}
+	if len(req.Oids) == 0 {
+		return "", &UriError{Uri: u.String(), Pos: -1, Msg: "Uri(): no Oids"}
+	}
+	for _, name := range req.Oids {
+		oid, err := parseOidName(name)
+		if err != nil {
+			return "", &UriError{Uri: u.String(), Pos: -1, Msg: "Uri(): invalid oid " + name}
+		}
+		u.oids = append(u.oids, oid)
+	}
+	return u.String(), nil
+}
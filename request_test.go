@@ -0,0 +1,150 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var requestUriTests = []struct {
+	req *Request
+	uri string
+}{
+	{NewRequest("192.168.1.10", "public", GNET_SNMP_V2C, OP_GET, "1.3.6.1.2.1.1.1.0"),
+		"snmp://public@192.168.1.10//1.3.6.1.2.1.1.1.0"},
+	{NewRequest("192.168.1.10", "public", GNET_SNMP_V2C, OP_GET, "1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.3.0"),
+		"snmp://public@192.168.1.10//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.3.0)"},
+	{NewRequest("192.168.1.10", "public", GNET_SNMP_V2C, OP_GETNEXT, "1.3.6.1.2.1"),
+		"snmp://public@192.168.1.10//1.3.6.1.2.1+"},
+	{NewRequest("192.168.1.10", "public", GNET_SNMP_V2C, OP_BULKWALK, "1.3.6.1.2.1.2.2"),
+		"snmp://public@192.168.1.10//1.3.6.1.2.1.2.2.*"},
+	{NewRequest("192.168.1.10", "public", GNET_SNMP_V2C, OP_BULKWALK, "1.3.6.1.2.1.2.2.1.2", "1.3.6.1.2.1.2.2.1.3"),
+		"snmp://public@192.168.1.10//(1.3.6.1.2.1.2.2.1.2,1.3.6.1.2.1.2.2.1.3).*"},
+	{NewRequest("::1", "p@ss/w rd", GNET_SNMP_V2C, OP_GET, "1.3.6.1.2.1.1.1.0"),
+		"snmp://p%40ss%2Fw%20rd@[::1]//1.3.6.1.2.1.1.1.0"},
+	{&Request{Host: "::1", Port: 1161, Community: "public", Oids: []string{"1.3.6.1.2.1.1.1.0"}},
+		"snmp://public@[::1]:1161//1.3.6.1.2.1.1.1.0"},
+}
+
+func TestRequestUri(t *testing.T) {
+	for i, test := range requestUriTests {
+		uri, err := test.req.Uri()
+		if err != nil {
+			t.Errorf("#%d: Uri() error: %s", i, err)
+			continue
+		}
+		if uri != test.uri {
+			t.Errorf("#%d: Uri() expected %s got %s", i, test.uri, uri)
+		}
+
+		// and back again; OP_WALK becomes OP_BULKWALK, like Query() walks
+		req, err := ParseRequest(uri)
+		if err != nil {
+			t.Errorf("#%d: ParseRequest(%s) error: %s", i, uri, err)
+			continue
+		}
+		if req.Host != test.req.Host || req.Community != test.req.Community || req.Operation != test.req.Operation ||
+			!reflect.DeepEqual(req.Oids, test.req.Oids) {
+			t.Errorf("#%d: ParseRequest(%s) expected %+v got %+v", i, uri, test.req, req)
+		}
+	}
+
+	set := NewRequest("192.168.1.10", "private", GNET_SNMP_V2C, OP_SET)
+	if _, err := set.Uri(); err == nil {
+		t.Errorf("Uri() of a SET expected error")
+	}
+	var uri_err *UriError
+	if _, err := NewRequest("192.168.1.10", "public", GNET_SNMP_V2C, OP_GET).Uri(); !errors.As(err, &uri_err) {
+		t.Errorf("Uri() without oids expected a *UriError, got %v", err)
+	}
+	if _, err := NewRequest("192.168.1.10", "public", GNET_SNMP_V2C, OP_GET, "1.3.x").Uri(); !errors.As(err, &uri_err) {
+		t.Errorf("Uri() with an invalid oid expected a *UriError, got %v", err)
+	}
+}
+
+func TestParseRequestContext(t *testing.T) {
+	uri := "snmp://admin@192.168.1.10/vlan%2F2;%80%00%1F%88/1.3.6.1.2.1.1.1.0"
+	req, err := ParseRequest(uri)
+	if err != nil {
+		t.Fatalf("ParseRequest(%s) error: %s", uri, err)
+	}
+	expected := &UsmParams{UserName: "admin", ContextName: "vlan/2", ContextEngineID: "\x80\x00\x1f\x88"}
+	if req.Version != GNET_SNMP_V3 || !reflect.DeepEqual(req.Usm, expected) {
+		t.Errorf("ParseRequest(%s) expected v3 with %+v, got %s with %+v", uri, expected, req.Version, req.Usm)
+	}
+	back, err := req.Uri()
+	if err != nil || back != "snmp://admin@192.168.1.10/vlan%2F2;%80%00%1F%88/1.3.6.1.2.1.1.1.0" {
+		t.Errorf("Uri() of ParseRequest(%s) expected the same uri, got %s, %v", uri, back, err)
+	}
+
+	// without a context the uri's user is a community
+	req, err = ParseRequest("snmp://admin@192.168.1.10//1.3.6.1.2.1.1.1.0")
+	if err != nil || req.Version != GNET_SNMP_V2C || req.Usm != nil || req.Community != "admin" {
+		t.Errorf("ParseRequest() without a context expected v2c, got %+v, %v", req, err)
+	}
+}
+
+func TestQueryRequest(t *testing.T) {
+	// a community that can't be put in a uri unescaped
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0], func(a *Agent) {
+		a.Community = "p@ss/word"
+		a.Writable = true
+	})
+	defer agent.Close()
+
+	requests := []*Request{
+		NewRequest("127.0.0.1", "p@ss/word", GNET_SNMP_V2C, OP_GET, "1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.3.0"),
+		NewRequest("127.0.0.1", "p@ss/word", GNET_SNMP_V2C, OP_GETNEXT, "1.3.6.1.2.1.1.1.0"),
+		NewRequest("127.0.0.1", "p@ss/word", GNET_SNMP_V1, OP_WALK, "1.3.6.1.2.1.2.2.1.2"),
+		NewRequest("127.0.0.1", "p@ss/word", GNET_SNMP_V2C, OP_BULKWALK, "1.3.6.1.2.1.2.2.1.2"),
+	}
+	expected := []int{2, 1, 3, 3}
+	for i, req := range requests {
+		req.Port = port
+		results, err := QueryRequest(req)
+		if err != nil {
+			t.Errorf("#%d: QueryRequest(%s) error: %s", i, req.Operation, err)
+			continue
+		}
+		if results.Len() != expected[i] {
+			t.Errorf("#%d: QueryRequest(%s) expected %d results got %d", i, req.Operation, expected[i], results.Len())
+		}
+		CompareVerax(t, results, vresults)
+	}
+
+	set := NewRequest("127.0.0.1", "p@ss/word", GNET_SNMP_V2C, OP_SET)
+	set.Port = port
+	set.Varbinds = []QueryResult{{Oid: MustParseOID("1.3.6.1.2.1.1.5.0"), Value: VBT_OctetString("renamed")}}
+	if _, err := QueryRequest(set); err != nil {
+		t.Errorf("QueryRequest(%s) error: %s", set.Operation, err)
+	}
+
+	for i, bad := range []*Request{
+		NewRequest("127.0.0.1", "public", GNET_SNMP_V2C, OP_GET),
+		NewRequest("127.0.0.1", "public", GNET_SNMP_V2C, OP_SET),
+		NewRequest("127.0.0.1", "public", GNET_SNMP_V2C, Operation(99), "1.3.6.1.2.1.1.1.0"),
+	} {
+		bad.Port = port
+		var session_err *SessionError
+		if _, err := QueryRequest(bad); !errors.As(err, &session_err) {
+			t.Errorf("#%d: QueryRequest(%s) expected a *SessionError, got %v", i, bad.Operation, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// resolve.go contains the Resolver of host names. github.com/natefinch/gocog
+// is used to generate the boilerplate for AddressPreference. AFTER EDITING
+// any gocog sections (between gocog open and close square brackets), you
+// MUST run:
+//
+//     rm -f resolve.go_cog; $GOPATH/bin/gocog resolve.go; go fmt ./...
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// AddressPreference decides which of the addresses of a host name a Resolver
+// uses:
+//
+//     PREFER_SYSTEM  the first address, in the order of the system resolver
+//     PREFER_IPV4    an ipv4 address if there is one, otherwise ipv6
+//     PREFER_IPV6    an ipv6 address if there is one, otherwise ipv4
+//     ONLY_IPV4      ipv4 addresses only
+//     ONLY_IPV6      ipv6 addresses only
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"PREFER_SYSTEM", "PREFER_IPV4", "PREFER_IPV6", "ONLY_IPV4", "ONLY_IPV6"}
+	enumconv.WriteGo("AddressPreference", "AddressPreference", vals, 0)
+}
+gocog]]]*/
+
+// type and values for AddressPreference
+type AddressPreference int
+
+const (
+	PREFER_SYSTEM AddressPreference = iota
+	PREFER_IPV4
+	PREFER_IPV6
+	ONLY_IPV4
+	ONLY_IPV6
+)
+
+// Stringer for AddressPreference
+func (addresspreference AddressPreference) String() string {
+	switch addresspreference {
+	case PREFER_SYSTEM:
+		return "PREFER_SYSTEM"
+	case PREFER_IPV4:
+		return "PREFER_IPV4"
+	case PREFER_IPV6:
+		return "PREFER_IPV6"
+	case ONLY_IPV4:
+		return "ONLY_IPV4"
+	case ONLY_IPV6:
+		return "ONLY_IPV6"
+	}
+	return "UNKNOWN AddressPreference"
+}
+
+//[[[end]]]
+
+// Resolver turns the host of a query (a host name, or an ipv4 or ipv6
+// literal) into the address the query is sent to. Names are looked up with
+// LookupIP, and the addresses cached for TTL.
+//
+// Set the fields before first use. A Resolver is safe for concurrent use,
+// and can be shared by any number of queries, Sessions and Pollers.
+type Resolver struct {
+	Prefer AddressPreference
+	TTL    time.Duration // how long to cache the addresses of a name; 0 doesn't cache
+	// LookupIP looks up the addresses of host; network is "ip", "ip4" or
+	// "ip6". It defaults to net.DefaultResolver.LookupIP.
+	LookupIP func(ctx context.Context, network, host string) ([]net.IP, error)
+
+	mu    sync.Mutex
+	cache map[string]resolvedHost
+}
+
+// DefaultResolver is used when a query, Session or Target has no Resolver.
+// It uses the order of the system resolver, and doesn't cache.
+var DefaultResolver = &Resolver{}
+
+// Resolve returns the address to use for host. An ip literal is returned
+// as is, if Prefer allows it. Errors are a *SessionError.
+func (r *Resolver) Resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if !r.allowed(ip) {
+			return nil, &SessionError{Msg: "Resolve(): " + host + " isn't allowed by " + r.Prefer.String()}
+		}
+		return ip, nil
+	}
+	ips, err := r.lookup(ctx, host)
+	if err != nil {
+		return nil, &SessionError{Msg: "Resolve(): unable to resolve " + host, Err: err}
+	}
+	if ip := r.pick(ips); ip != nil {
+		return ip, nil
+	}
+	return nil, &SessionError{Msg: "Resolve(): no " + r.Prefer.String() + " address for " + host}
+}
+
+// Flush empties the cache, so names are looked up again.
+func (r *Resolver) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = nil
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// allowed returns false if ip is of a family excluded by r.Prefer.
+func (r *Resolver) allowed(ip net.IP) bool {
+	switch r.Prefer {
+	case ONLY_IPV4:
+		return ip.To4() != nil
+	case ONLY_IPV6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// lookup returns the addresses of host, from the cache if they haven't
+// expired.
+func (r *Resolver) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	network := "ip"
+	switch r.Prefer {
+	case ONLY_IPV4:
+		network = "ip4"
+	case ONLY_IPV6:
+		network = "ip6"
+	}
+	key := network + "/" + host
+	if r.TTL > 0 {
+		r.mu.Lock()
+		cached, ok := r.cache[key]
+		r.mu.Unlock()
+		if ok && time.Now().Before(cached.expires) {
+			return cached.ips, nil
+		}
+	}
+
+	lookup_ip := r.LookupIP
+	if lookup_ip == nil {
+		lookup_ip = net.DefaultResolver.LookupIP
+	}
+	ips, err := lookup_ip(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	if r.TTL > 0 {
+		r.mu.Lock()
+		if r.cache == nil {
+			r.cache = make(map[string]resolvedHost)
+		}
+		r.cache[key] = resolvedHost{ips: ips, expires: time.Now().Add(r.TTL)}
+		r.mu.Unlock()
+	}
+	return ips, nil
+}
+
+// pick returns the address from ips that r.Prefer chooses, or nil.
+func (r *Resolver) pick(ips []net.IP) net.IP {
+	var first, first_v4, first_v6 net.IP
+	for _, ip := range ips {
+		if first == nil {
+			first = ip
+		}
+		if ip.To4() != nil && first_v4 == nil {
+			first_v4 = ip
+		} else if ip.To4() == nil && first_v6 == nil {
+			first_v6 = ip
+		}
+	}
+	switch r.Prefer {
+	case PREFER_IPV4:
+		if first_v4 != nil {
+			return first_v4
+		}
+	case PREFER_IPV6:
+		if first_v6 != nil {
+			return first_v6
+		}
+	case ONLY_IPV4:
+		return first_v4
+	case ONLY_IPV6:
+		return first_v6
+	}
+	return first
+}
+
+// resolveAddr returns the udp address of host and port, using resolver or
+// DefaultResolver.
+func resolveAddr(ctx context.Context, resolver *Resolver, host string, port int) (*net.UDPAddr, error) {
+	if resolver == nil {
+		resolver = DefaultResolver
+	}
+	ip, err := resolver.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return &net.UDPAddr{IP: ip, Port: port}, nil
+}
+
+// resolvedHost is a cached lookup of a Resolver.
+type resolvedHost struct {
+	ips     []net.IP
+	expires time.Time
+}
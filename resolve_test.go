@@ -0,0 +1,213 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// lookupTest is a LookupIP that knows a few names, and counts its lookups.
+type lookupTest struct {
+	lookups int
+}
+
+func (l *lookupTest) LookupIP(ctx context.Context, network, host string) (ips []net.IP, err error) {
+	l.lookups++
+	names := map[string][]string{
+		"dual.example": {"2001:db8::1", "192.0.2.1", "2001:db8::2"},
+		"v4.example":   {"192.0.2.4"},
+		"v6.example":   {"2001:db8::6"},
+		"agent.test":   {"::1", "127.0.0.1"},
+	}
+	for _, addr := range names[host] {
+		ip := net.ParseIP(addr)
+		if network == "ip4" && ip.To4() == nil || network == "ip6" && ip.To4() != nil {
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	if len(ips) == 0 {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+var resolverTests = []struct {
+	prefer   AddressPreference
+	host     string
+	expected string // "" for an error
+}{
+	{PREFER_SYSTEM, "dual.example", "2001:db8::1"},
+	{PREFER_IPV4, "dual.example", "192.0.2.1"},
+	{PREFER_IPV6, "dual.example", "2001:db8::1"},
+	{ONLY_IPV4, "dual.example", "192.0.2.1"},
+	{ONLY_IPV6, "dual.example", "2001:db8::1"},
+	{PREFER_IPV6, "v4.example", "192.0.2.4"},
+	{PREFER_IPV4, "v6.example", "2001:db8::6"},
+	{ONLY_IPV6, "v4.example", ""},
+	{ONLY_IPV4, "v6.example", ""},
+	{PREFER_SYSTEM, "nonexistent.example", ""},
+	{PREFER_SYSTEM, "192.168.1.10", "192.168.1.10"},
+	{PREFER_IPV4, "::1", "::1"},
+	{ONLY_IPV4, "::1", ""},
+	{ONLY_IPV6, "192.168.1.10", ""},
+}
+
+func TestResolver(t *testing.T) {
+	lookup := &lookupTest{}
+	for i, test := range resolverTests {
+		r := &Resolver{Prefer: test.prefer, LookupIP: lookup.LookupIP}
+		ip, err := r.Resolve(context.Background(), test.host)
+		switch {
+		case test.expected == "":
+			var session_err *SessionError
+			if !errors.As(err, &session_err) {
+				t.Errorf("#%d: Resolve(%s) with %s expected SessionError, got %v %v", i, test.host, test.prefer, ip, err)
+			}
+		case err != nil:
+			t.Errorf("#%d: Resolve(%s) with %s error: %s", i, test.host, test.prefer, err)
+		case !ip.Equal(net.ParseIP(test.expected)):
+			t.Errorf("#%d: Resolve(%s) with %s expected %s got %s", i, test.host, test.prefer, test.expected, ip)
+		}
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	lookup := &lookupTest{}
+	r := &Resolver{TTL: 50 * time.Millisecond, LookupIP: lookup.LookupIP}
+	for i := 0; i < 3; i++ {
+		if _, err := r.Resolve(context.Background(), "dual.example"); err != nil {
+			t.Fatalf("Resolve error: %s", err)
+		}
+	}
+	if lookup.lookups != 1 {
+		t.Errorf("expected 1 lookup while cached, got %d", lookup.lookups)
+	}
+	r.Flush()
+	r.Resolve(context.Background(), "dual.example")
+	if lookup.lookups != 2 {
+		t.Errorf("expected a lookup after Flush, got %d lookups", lookup.lookups)
+	}
+	time.Sleep(60 * time.Millisecond)
+	r.Resolve(context.Background(), "dual.example")
+	if lookup.lookups != 3 {
+		t.Errorf("expected a lookup after the TTL, got %d lookups", lookup.lookups)
+	}
+
+	// no TTL, no caching
+	r = &Resolver{LookupIP: lookup.LookupIP}
+	r.Resolve(context.Background(), "dual.example")
+	r.Resolve(context.Background(), "dual.example")
+	if lookup.lookups != 5 {
+		t.Errorf("expected every Resolve to look up without a TTL, got %d lookups", lookup.lookups-3)
+	}
+}
+
+// ipv6Agent returns an agent listening on [::1], or ok false if ipv6 isn't
+// available.
+func ipv6Agent(t *testing.T) (agent *Agent, port int, ok bool) {
+	vresults, err := ReadVeraxResults(veraxDevices[0])
+	if err != nil {
+		t.Fatalf("ReadVeraxResults error: %s", err)
+	}
+	agent = NewAgent(vresults)
+	if err = agent.Listen("[::1]:0"); err != nil {
+		t.Logf("skipping, no ipv6: %s", err)
+		return nil, 0, false
+	}
+	return agent, agent.Addr().(*net.UDPAddr).Port, true
+}
+
+func TestQueryIPv6(t *testing.T) {
+	agent, port, ok := ipv6Agent(t)
+	if !ok {
+		return
+	}
+	defer agent.Close()
+
+	uris := []string{
+		fmt.Sprintf("snmp://public@[::1]:%d//1.3.6.1.2.1.1.1.0", port),
+		fmt.Sprintf("snmp://public@agent.test:%d//1.3.6.1.2.1.1.1.0", port),
+	}
+	resolver := &Resolver{Prefer: PREFER_IPV6, TTL: time.Minute, LookupIP: (&lookupTest{}).LookupIP}
+	for i, uri := range uris {
+		params := NewDefaultParams(uri)
+		params.Resolver = resolver
+		results, status, err := QueryWithStatus(params)
+		if err != nil {
+			t.Errorf("#%d: Query(%s) error: %s", i, uri, err)
+			continue
+		}
+		if results.Len() != 1 {
+			t.Errorf("#%d: Query(%s) expected 1 result got %d", i, uri, results.Len())
+		}
+		if status.Addr == nil || status.Addr.String() != fmt.Sprintf("[::1]:%d", port) {
+			t.Errorf("#%d: Query(%s) expected Addr [::1]:%d got %v", i, uri, port, status.Addr)
+		}
+	}
+
+	// the agent isn't listening on ipv4
+	params := NewDefaultParams(uris[1])
+	params.Resolver = &Resolver{Prefer: ONLY_IPV4, LookupIP: (&lookupTest{}).LookupIP}
+	params.Timeout, params.Retries = 50, 0
+	_, status, err := QueryWithStatus(params)
+	if addr, _ := status.Addr.(*net.UDPAddr); !errors.Is(err, ErrTimeout) || addr == nil || addr.IP.To4() == nil {
+		t.Errorf("Query(%s) with ONLY_IPV4 expected timeout from 127.0.0.1, got %v", uris[1], err)
+	}
+}
+
+func TestSessionIPv6(t *testing.T) {
+	agent, port, ok := ipv6Agent(t)
+	if !ok {
+		return
+	}
+	defer agent.Close()
+
+	s := NewSession("agent.test", "public", GNET_SNMP_V2C)
+	s.Port = port
+	s.Resolver = &Resolver{Prefer: PREFER_IPV6, LookupIP: (&lookupTest{}).LookupIP}
+	if s.Addr() != nil {
+		t.Errorf("expected no Addr before Open, got %s", s.Addr())
+	}
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer s.Close()
+	if addr := s.Addr(); addr == nil || addr.String() != fmt.Sprintf("[::1]:%d", port) {
+		t.Errorf("expected Addr [::1]:%d got %s", port, addr)
+	}
+	results, err := s.BulkWalk([]string{"1.3.6.1.2.1.2.2.1.2"})
+	if err != nil {
+		t.Fatalf("BulkWalk error: %s", err)
+	}
+	if results.Len() != 3 {
+		t.Errorf("expected 3 results got %d", results.Len())
+	}
+
+	s = NewSession("nonexistent.example", "public", GNET_SNMP_V2C)
+	s.Resolver = &Resolver{LookupIP: (&lookupTest{}).LookupIP}
+	var session_err *SessionError
+	if err := s.Open(); !errors.As(err, &session_err) {
+		t.Errorf("Open of an unresolvable host expected SessionError, got %v", err)
+	}
+}
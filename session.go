@@ -0,0 +1,178 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"context"
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+	"sync"
+)
+
+// Session is an SNMP session with a single agent, for doing many requests
+// without parsing a uri and setting up a gsnmp session each time.
+//
+// Fill in the fields (or use NewSession), call Open(), and Close() when
+// finished. Changing the fields of an open Session has no effect. The
+// methods are safe to call concurrently, but requests on a single Session
+// are done one at a time; use several Sessions for parallel requests.
+type Session struct {
+	Host      string
+	Port      int // defaults to 161
+	Community string
+	Version   SnmpVersion
+	Timeout   int // timeout in milliseconds
+	Retries   int // number of retries
+	Nonrep    int // used by BulkWalk, see QueryParams
+	Maxrep    int // used by BulkWalk, see QueryParams
+	Usm       *UsmParams
+	Resolver  *Resolver // resolves Host; DefaultResolver is used if it is nil
+	Transport Transport // TRANSPORT_UDP by default; for TRANSPORT_UNIX, Host is the socket's path
+
+	mu   sync.Mutex
+	addr net.Addr     // Host, as resolved by Open()
+	conn *sessionConn // the backend's session, see session_gsnmp.go and session_purego.go
+}
+
+// NewSession returns a Session with the same defaults as NewDefaultParams.
+func NewSession(host, community string, version SnmpVersion) *Session {
+	defaults := NewDefaultParams("")
+	return &Session{
+		Host:      host,
+		Port:      161,
+		Community: community,
+		Version:   version,
+		Timeout:   defaults.Timeout,
+		Retries:   defaults.Retries,
+		Nonrep:    defaults.Nonrep,
+		Maxrep:    defaults.Maxrep,
+	}
+}
+
+// Open creates the session. Host is resolved once, here, by Resolver.
+func (s *Session) Open() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return &SessionError{Msg: "Open(): session is already open"}
+	}
+	if s.Host == "" {
+		return &SessionError{Msg: "Open(): no Host"}
+	}
+	port := s.Port
+	if port == 0 {
+		port = 161
+	}
+	params := &QueryParams{
+		Version: s.Version,
+		Timeout: s.Timeout,
+		Retries: s.Retries,
+		Nonrep:  s.Nonrep,
+		Maxrep:  s.Maxrep,
+		Usm:     s.Usm,
+	}
+	addr, err := transportAddr(context.Background(), s.Transport, s.Resolver, s.Host, port)
+	if err != nil {
+		return err
+	}
+	if s.conn, err = openSessionConn(addr, s.Community, params); err != nil {
+		return err
+	}
+	s.addr = addr
+	return nil
+}
+
+// Addr returns the transport address Host was resolved to by Open(), or nil
+// if the Session isn't open.
+func (s *Session) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.addr
+}
+
+// Close frees the session. It is safe to call Close more than once.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.close()
+		s.conn, s.addr = nil, nil
+	}
+	return nil
+}
+
+// Get does an SNMP GET of oids.
+func (s *Session) Get(oids []string) (results *llrb.Tree, err error) {
+	return s.query(oids, GNET_SNMP_URI_GET, false)
+}
+
+// GetMany does an SNMP GET of any number of oids, splitting them into
+// requests that fit; see the GetMany() function.
+func (s *Session) GetMany(oids []string) (results *llrb.Tree, err error) {
+	results = llrb.New(LessOID)
+	err = getMany(oids, results, s.Get)
+	return results, err
+}
+
+// GetNext does an SNMP GETNEXT of oids.
+func (s *Session) GetNext(oids []string) (results *llrb.Tree, err error) {
+	return s.query(oids, GNET_SNMP_URI_NEXT, false)
+}
+
+// Walk walks the subtree of each of oids using GETNEXT requests.
+func (s *Session) Walk(oids []string) (results *llrb.Tree, err error) {
+	return s.query(oids, GNET_SNMP_URI_WALK, false)
+}
+
+// BulkWalk walks the subtree of each of oids using GETBULK requests, with
+// the Session's Nonrep and Maxrep. SNMP v1 sessions fall back to GETNEXT.
+func (s *Session) BulkWalk(oids []string) (results *llrb.Tree, err error) {
+	return s.query(oids, GNET_SNMP_URI_WALK, true)
+}
+
+// Set does an SNMP SET of varbinds; see the Set() function.
+func (s *Session) Set(varbinds []QueryResult) (results *llrb.Tree, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil, &SessionError{Msg: "Set(): session is not open"}
+	}
+	return s.conn.set(varbinds)
+}
+
+// query parses oids (names too, if Mib is set) and does a GET, GETNEXT or walk on the backend's session.
+func (s *Session) query(oids []string, uritype UriType, bulk bool) (results *llrb.Tree, err error) {
+	var request [][]uint32
+	for _, oid := range oids {
+		parsed, err := parseOidName(oid)
+		if err != nil {
+			return nil, err
+		}
+		request = append(request, parsed)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil, &SessionError{Msg: "session is not open"}
+	}
+	return s.conn.query(request, uritype, bulk)
+}
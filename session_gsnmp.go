@@ -0,0 +1,126 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+/*
+#cgo pkg-config: glib-2.0 gsnmp
+#include "c_bridge.h"
+*/
+import "C"
+
+import (
+	"context"
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+)
+
+// sessionConn is a Session's gsnmp session. All calls into gsnmp are run on
+// the dispatcher worker. SNMP v3 sessions use the Go client instead (see
+// gsnmp.go).
+type sessionConn struct {
+	params  *QueryParams
+	session *_Ctype_GNetSnmp
+	client  *goClient // for SNMP v3
+}
+
+// openSessionConn creates and configures a gsnmp session for the agent at
+// addr, or a Go client for SNMP v3.
+func openSessionConn(addr net.Addr, community string, params *QueryParams) (conn *sessionConn, err error) {
+	if params.Version == GNET_SNMP_V3 {
+		client, err := dialClient(addr, community, params)
+		if err != nil {
+			return nil, err
+		}
+		return &sessionConn{params: params, client: client}, nil
+	}
+	dispatch(func() {
+		var session *_Ctype_GNetSnmp
+		session, err = newSession(context.Background(), params, addr, community)
+		if err != nil {
+			sessionDelete(session)
+			return
+		}
+		conn = &sessionConn{params: params, session: session}
+	})
+	return conn, err
+}
+
+// close frees the gsnmp session, or closes the Go client's socket.
+func (c *sessionConn) close() {
+	if c.client != nil {
+		c.client.close()
+		return
+	}
+	dispatch(func() {
+		sessionDelete(c.session)
+	})
+}
+
+// query does a GET, GETNEXT or walk (with GETBULK if bulk is true) of oids.
+func (c *sessionConn) query(oids [][]uint32, uritype UriType, bulk bool) (results *llrb.Tree, err error) {
+	if c.client != nil {
+		varbinds, _, err := c.client.query(context.Background(), oids, uritype, bulk)
+		if err != nil {
+			return nil, err
+		}
+		return resultsTree(c.params, varbinds), nil
+	}
+	dispatch(func() {
+		var varbinds []QueryResult
+		if uritype == GNET_SNMP_URI_WALK {
+			bulk = bulk && c.params.Version != GNET_SNMP_V1
+			varbinds, _, err = walk(context.Background(), c.session, oids, bulk, c.params)
+		} else {
+			vbl := vblFromOids(oids)
+			defer vblDelete(vbl)
+			varbinds, _, err = querySync(context.Background(), c.session, vbl, C.GNetSnmpUriType(uritype), c.params)
+		}
+		if err != nil {
+			return
+		}
+		results = resultsTree(c.params, varbinds)
+	})
+	return results, err
+}
+
+// set does an SNMP SET of varbinds.
+func (c *sessionConn) set(varbinds []QueryResult) (results *llrb.Tree, err error) {
+	if c.client != nil {
+		return c.client.set(varbinds)
+	}
+	dispatch(func() {
+		vbl, vbl_err := vblFromResults(varbinds)
+		defer vblDelete(vbl)
+		if vbl_err != nil {
+			err = vbl_err
+			return
+		}
+		var gerror *C.GError
+		out := C.gnet_snmp_sync_set(c.session, vbl, &gerror)
+		defer vblDelete(out)
+		status := newQueryStatus(c.session, &gerror)
+		if out != nil {
+			results = convertResults(c.params, out)
+		}
+		err = status.err(c.params)
+	})
+	return results, err
+}
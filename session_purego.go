@@ -0,0 +1,61 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"context"
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+)
+
+// sessionConn is a Session's connection to its agent, using the pure Go
+// backend.
+type sessionConn struct {
+	client *goClient
+}
+
+// openSessionConn creates a client for the agent at addr.
+func openSessionConn(addr net.Addr, community string, params *QueryParams) (conn *sessionConn, err error) {
+	client, err := dialClient(addr, community, params)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionConn{client: client}, nil
+}
+
+// close closes the client's socket.
+func (c *sessionConn) close() {
+	c.client.close()
+}
+
+// query does a GET, GETNEXT or walk (with GETBULK if bulk is true) of oids.
+func (c *sessionConn) query(oids [][]uint32, uritype UriType, bulk bool) (results *llrb.Tree, err error) {
+	varbinds, _, err := c.client.query(context.Background(), oids, uritype, bulk)
+	if err != nil {
+		return nil, err
+	}
+	return resultsTree(c.client.params, varbinds), nil
+}
+
+// set does an SNMP SET of varbinds.
+func (c *sessionConn) set(varbinds []QueryResult) (results *llrb.Tree, err error) {
+	return c.client.set(varbinds)
+}
@@ -0,0 +1,164 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSessionNotOpen(t *testing.T) {
+	s := NewSession("127.0.0.1", "public", GNET_SNMP_V2C)
+	oids := []string{"1.3.6.1.2.1.1.1.0"}
+	requests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"Get", func() error { _, err := s.Get(oids); return err }},
+		{"GetNext", func() error { _, err := s.GetNext(oids); return err }},
+		{"Walk", func() error { _, err := s.Walk(oids); return err }},
+		{"BulkWalk", func() error { _, err := s.BulkWalk(oids); return err }},
+		{"Set", func() error {
+			_, err := s.Set([]QueryResult{{Oid: MustParseOID(oids[0]), Value: VBT_Integer32(1)}})
+			return err
+		}},
+	}
+	for i, test := range requests {
+		var session_err *SessionError
+		if err := test.fn(); !errors.As(err, &session_err) {
+			t.Errorf("#%d, %s: expected SessionError on unopened session, got %v", i, test.name, err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close of unopened session: %s", err)
+	}
+
+	var session_err *SessionError
+	if err := (&Session{}).Open(); !errors.As(err, &session_err) {
+		t.Errorf("expected SessionError opening session without Host, got %v", err)
+	}
+}
+
+func TestSessionGet(t *testing.T) {
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0])
+	defer agent.Close()
+
+	s := NewSession("127.0.0.1", "public", GNET_SNMP_V2C)
+	s.Port = port
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer s.Close()
+
+	// several requests on the one session
+	for i, oid := range []string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.2.0", "1.3.6.1.2.1.1.5.0"} {
+		results, err := s.Get([]string{oid})
+		if err != nil {
+			t.Errorf("#%d: Get(%s) error: %s", i, oid, err)
+			continue
+		}
+		if results.Get(QueryResult{Oid: MustParseOID(oid)}) == nil {
+			t.Errorf("#%d: Get(%s) returned no result", i, oid)
+		}
+		CompareVerax(t, results, vresults)
+	}
+}
+
+func TestSessionTable(t *testing.T) {
+	_, agent, port, _ := veraxAgent(t, veraxDevices[0])
+	defer agent.Close()
+
+	s := NewSession("127.0.0.1", "public", GNET_SNMP_V2C)
+	s.Port = port
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		oid     string
+		columns []uint32
+		entry   string
+		rows    int
+		column  uint32 // a column of the last row
+		value   string
+	}{
+		{"1.3.6.1.2.1.2.2", nil, "1.3.6.1.2.1.2.2.1", 3, 2, "eth1"},
+		{"1.3.6.1.2.1.2.2", []uint32{2, 8}, "1.3.6.1.2.1.2.2.1", 3, 8, "2"},
+		{"1.3.6.1.2.1.4.20", []uint32{2}, "1.3.6.1.2.1.4.20.1", 2, 2, "1"},
+		{"1.3.6.1.2.1.31.1.1", nil, "1.3.6.1.2.1.31.1.1.1", 2, 6, "3062744"},
+		// entries, without MIBs
+		{"1.3.6.1.2.1.2.2.1", nil, "1.3.6.1.2.1.2.2.1", 3, 2, "eth1"},
+		{"1.3.6.1.2.1.2.2.1", []uint32{2, 8}, "1.3.6.1.2.1.2.2.1", 3, 8, "2"},
+		{"1.3.6.1.2.1.31.1.1.1", nil, "1.3.6.1.2.1.31.1.1.1", 2, 6, "3062744"},
+	}
+	for i, test := range tests {
+		table, err := s.Table(test.oid, test.columns...)
+		if err != nil {
+			t.Errorf("#%d: Table(%s) error: %s", i, test.oid, err)
+			continue
+		}
+		if table.Entry.String() != test.entry || len(table.Rows) != test.rows {
+			t.Errorf("#%d: Table(%s) expected entry %s with %d rows, got %s with %d rows",
+				i, test.oid, test.entry, test.rows, table.Entry, len(table.Rows))
+			continue
+		}
+		if test.columns != nil && !reflect.DeepEqual(table.Columns, test.columns) {
+			t.Errorf("#%d: Table(%s) expected columns %v got %v", i, test.oid, test.columns, table.Columns)
+		}
+		value := table.Rows[len(table.Rows)-1].Values[test.column]
+		if value == nil || value.String() != test.value {
+			t.Errorf("#%d: Table(%s) column %d expected %s got %v", i, test.oid, test.column, test.value, value)
+		}
+	}
+
+	if table, err := s.Table("1.3.6.1.2.1.99"); err == nil {
+		t.Errorf("Table(1.3.6.1.2.1.99): expected an error, got %d rows", len(table.Rows))
+	}
+}
+
+func TestSessionGetMany(t *testing.T) {
+	vresults, agent, port, _ := veraxAgent(t, veraxDevices[0], func(a *Agent) { a.MaxMessage = 1000 })
+	defer agent.Close()
+
+	s := NewSession("127.0.0.1", "public", GNET_SNMP_V2C)
+	s.Port = port
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer s.Close()
+
+	var oids []string
+	ch := vresults.IterAscend()
+	for {
+		r := <-ch
+		if r == nil {
+			break
+		}
+		oids = append(oids, r.(QueryResult).Oid.String())
+	}
+	results, err := s.GetMany(oids)
+	if err != nil {
+		t.Fatalf("GetMany error: %s", err)
+	}
+	if results.Len() != vresults.Len() {
+		t.Errorf("GetMany expected %d results got %d", vresults.Len(), results.Len())
+	}
+	CompareVerax(t, results, vresults)
+}
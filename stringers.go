@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 package gsnmpgo
 
 // gsnmpgo is a go/cgo wrapper around gsnmp.
@@ -17,10 +20,11 @@ package gsnmpgo
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// stringers.go contains stringers for C enums and other types. To help with
-// the generation of the boilerplate code for the C enums,
-// github.com/natefinch/gocog is used. AFTER EDITING any gocog sections
-// (between gocog open and close square brackets), you MUST run:
+// stringers.go contains stringers for C enums and other C types; the Go types
+// for the C enums are in enums.go. To help with the generation of the
+// boilerplate code for the C enums, github.com/natefinch/gocog is used. AFTER
+// EDITING any gocog sections (between gocog open and close square brackets),
+// you MUST run:
 //
 //     rm -f stringers.go_cog; $GOPATH/bin/gocog stringers.go; go fmt ./...
 
@@ -35,7 +39,6 @@ import (
 	"encoding/binary"
 	"fmt"
 	"strconv"
-	"strings"
 	"unsafe"
 )
 
@@ -44,24 +47,12 @@ var _ = binary.Size("1")                        // dummy
 var _ = unsafe.Sizeof("1")                      // dummy
 
 // gIntArrayOidString converts an oid from C array of guint32's to a Go string
-func gIntArrayOidString(oid *_Ctype_guint32, oid_len _Ctype_gsize) (result string) {
-	size := int(unsafe.Sizeof(*oid))
-	length := int(oid_len)
-	gbytes := C.GoBytes(unsafe.Pointer(oid), (_Ctype_int)(size*length))
-	buf := bytes.NewBuffer(gbytes)
-
-	for i := 0; i < length; i++ {
-		var out uint32
-		if err := binary.Read(buf, binary.LittleEndian, &out); err == nil {
-			result = result + fmt.Sprintf(".%d", out)
-		} else {
-			return "<error converting oid>"
-		}
-	}
-	if len(result) > 1 {
-		return result[1:] // strip leading dot
+func gIntArrayOidString(oid *_Ctype_guint32, oid_len _Ctype_gsize) string {
+	result := gIntArrayOid(oid, oid_len)
+	if len(result) == 0 {
+		return "<error converting oid>"
 	}
-	return "<error converting oid>"
+	return OID(result).String()
 }
 
 // gListOidsString returns the string represention of the OIDs in a GList
@@ -78,16 +69,6 @@ func gListOidsString(vbl *_Ctype_GList) (result string) {
 	panic(fmt.Sprintf("%s: gListOidsString(): fell out of for loop", libname()))
 }
 
-// Converts an OID in a slice of int format (eg []int{1, 3, 6, 1} to a string.
-func OidAsString(o []int) string {
-	if len(o) == 0 {
-		return ""
-	}
-	result := fmt.Sprintf("%v", o)
-	result = result[1 : len(result)-1] // strip [ ] of Array representation
-	return "." + strings.Join(strings.Split(result, " "), ".")
-}
-
 // Stringer for *_Ctype_GURI
 //
 // Example:
@@ -214,63 +195,10 @@ package main
 import ("github.com/soniah/gsnmpgo/enumconv")
 func main() {
 	ccode := "gsnmp-0.3.0/src/pdu.h"
-	vals := []string{"GNET_SNMP_VARBIND_TYPE_NULL", "GNET_SNMP_VARBIND_TYPE_OCTETSTRING", "GNET_SNMP_VARBIND_TYPE_OBJECTID", "GNET_SNMP_VARBIND_TYPE_IPADDRESS", "GNET_SNMP_VARBIND_TYPE_INTEGER32", "GNET_SNMP_VARBIND_TYPE_UNSIGNED32", "GNET_SNMP_VARBIND_TYPE_COUNTER32", "GNET_SNMP_VARBIND_TYPE_TIMETICKS", "GNET_SNMP_VARBIND_TYPE_OPAQUE", "GNET_SNMP_VARBIND_TYPE_COUNTER64", "GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT", "GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE", "GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW"}
-	enumconv.Write("VarBindType", "_Ctype_GNetSnmpVarBindType", vals, ccode, 0)
+	enumconv.WriteC("VarBindType", "_Ctype_GNetSnmpVarBindType", ccode)
 }
 gocog]]]*/
 
-// type and values for _Ctype_GNetSnmpVarBindType
-type VarBindType int
-
-const (
-	GNET_SNMP_VARBIND_TYPE_NULL VarBindType = iota
-	GNET_SNMP_VARBIND_TYPE_OCTETSTRING
-	GNET_SNMP_VARBIND_TYPE_OBJECTID
-	GNET_SNMP_VARBIND_TYPE_IPADDRESS
-	GNET_SNMP_VARBIND_TYPE_INTEGER32
-	GNET_SNMP_VARBIND_TYPE_UNSIGNED32
-	GNET_SNMP_VARBIND_TYPE_COUNTER32
-	GNET_SNMP_VARBIND_TYPE_TIMETICKS
-	GNET_SNMP_VARBIND_TYPE_OPAQUE
-	GNET_SNMP_VARBIND_TYPE_COUNTER64
-	GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT
-	GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE
-	GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW
-)
-
-// Stringer for VarBindType
-func (varbindtype VarBindType) String() string {
-	switch varbindtype {
-	case GNET_SNMP_VARBIND_TYPE_NULL:
-		return "GNET_SNMP_VARBIND_TYPE_NULL"
-	case GNET_SNMP_VARBIND_TYPE_OCTETSTRING:
-		return "GNET_SNMP_VARBIND_TYPE_OCTETSTRING"
-	case GNET_SNMP_VARBIND_TYPE_OBJECTID:
-		return "GNET_SNMP_VARBIND_TYPE_OBJECTID"
-	case GNET_SNMP_VARBIND_TYPE_IPADDRESS:
-		return "GNET_SNMP_VARBIND_TYPE_IPADDRESS"
-	case GNET_SNMP_VARBIND_TYPE_INTEGER32:
-		return "GNET_SNMP_VARBIND_TYPE_INTEGER32"
-	case GNET_SNMP_VARBIND_TYPE_UNSIGNED32:
-		return "GNET_SNMP_VARBIND_TYPE_UNSIGNED32"
-	case GNET_SNMP_VARBIND_TYPE_COUNTER32:
-		return "GNET_SNMP_VARBIND_TYPE_COUNTER32"
-	case GNET_SNMP_VARBIND_TYPE_TIMETICKS:
-		return "GNET_SNMP_VARBIND_TYPE_TIMETICKS"
-	case GNET_SNMP_VARBIND_TYPE_OPAQUE:
-		return "GNET_SNMP_VARBIND_TYPE_OPAQUE"
-	case GNET_SNMP_VARBIND_TYPE_COUNTER64:
-		return "GNET_SNMP_VARBIND_TYPE_COUNTER64"
-	case GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT:
-		return "GNET_SNMP_VARBIND_TYPE_NOSUCHOBJECT"
-	case GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE:
-		return "GNET_SNMP_VARBIND_TYPE_NOSUCHINSTANCE"
-	case GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW:
-		return "GNET_SNMP_VARBIND_TYPE_ENDOFMIBVIEW"
-	}
-	return "UNKNOWN VarBindType"
-}
-
 // Stringer for _Ctype_GNetSnmpVarBindType
 //
 // C:
@@ -286,33 +214,10 @@ package main
 import ("github.com/soniah/gsnmpgo/enumconv")
 func main() {
 	ccode := "/usr/include/gsnmp/utils.h"
-	vals := []string{"GNET_SNMP_URI_GET", "GNET_SNMP_URI_NEXT", "GNET_SNMP_URI_WALK"}
-	enumconv.Write("UriType", "_Ctype_GNetSnmpUriType", vals, ccode, 0)
+	enumconv.WriteC("UriType", "_Ctype_GNetSnmpUriType", ccode)
 }
 gocog]]]*/
 
-// type and values for _Ctype_GNetSnmpUriType
-type UriType int
-
-const (
-	GNET_SNMP_URI_GET UriType = iota
-	GNET_SNMP_URI_NEXT
-	GNET_SNMP_URI_WALK
-)
-
-// Stringer for UriType
-func (uritype UriType) String() string {
-	switch uritype {
-	case GNET_SNMP_URI_GET:
-		return "GNET_SNMP_URI_GET"
-	case GNET_SNMP_URI_NEXT:
-		return "GNET_SNMP_URI_NEXT"
-	case GNET_SNMP_URI_WALK:
-		return "GNET_SNMP_URI_WALK"
-	}
-	return "UNKNOWN UriType"
-}
-
 // Stringer for _Ctype_GNetSnmpUriType
 //
 // C:
@@ -328,36 +233,10 @@ package main
 import ("github.com/soniah/gsnmpgo/enumconv")
 func main() {
 	ccode := "gsnmp-0.3.0/src/security.h"
-	vals := []string{"GNET_SNMP_SECMODEL_ANY", "GNET_SNMP_SECMODEL_SNMPV1", "GNET_SNMP_SECMODEL_SNMPV2C", "GNET_SNMP_SECMODEL_SNMPV3"}
-	enumconv.Write("SecModel", "_Ctype_GNetSnmpSecModel", vals, ccode, 0)
+	enumconv.WriteC("SecModel", "_Ctype_GNetSnmpSecModel", ccode)
 }
 gocog]]]*/
 
-// type and values for _Ctype_GNetSnmpSecModel
-type SecModel int
-
-const (
-	GNET_SNMP_SECMODEL_ANY SecModel = iota
-	GNET_SNMP_SECMODEL_SNMPV1
-	GNET_SNMP_SECMODEL_SNMPV2C
-	GNET_SNMP_SECMODEL_SNMPV3
-)
-
-// Stringer for SecModel
-func (secmodel SecModel) String() string {
-	switch secmodel {
-	case GNET_SNMP_SECMODEL_ANY:
-		return "GNET_SNMP_SECMODEL_ANY"
-	case GNET_SNMP_SECMODEL_SNMPV1:
-		return "GNET_SNMP_SECMODEL_SNMPV1"
-	case GNET_SNMP_SECMODEL_SNMPV2C:
-		return "GNET_SNMP_SECMODEL_SNMPV2C"
-	case GNET_SNMP_SECMODEL_SNMPV3:
-		return "GNET_SNMP_SECMODEL_SNMPV3"
-	}
-	return "UNKNOWN SecModel"
-}
-
 // Stringer for _Ctype_GNetSnmpSecModel
 //
 // C:
@@ -373,33 +252,10 @@ package main
 import ("github.com/soniah/gsnmpgo/enumconv")
 func main() {
 	ccode := "gsnmp-0.3.0/src/security.h"
-	vals := []string{"GNET_SNMP_SECLEVEL_NANP", "GNET_SNMP_SECLEVEL_ANP", "GNET_SNMP_SECLEVEL_AP"}
-	enumconv.Write("SecLevel", "_Ctype_GNetSnmpSecLevel", vals, ccode, 0)
+	enumconv.WriteC("SecLevel", "_Ctype_GNetSnmpSecLevel", ccode)
 }
 gocog]]]*/
 
-// type and values for _Ctype_GNetSnmpSecLevel
-type SecLevel int
-
-const (
-	GNET_SNMP_SECLEVEL_NANP SecLevel = iota
-	GNET_SNMP_SECLEVEL_ANP
-	GNET_SNMP_SECLEVEL_AP
-)
-
-// Stringer for SecLevel
-func (seclevel SecLevel) String() string {
-	switch seclevel {
-	case GNET_SNMP_SECLEVEL_NANP:
-		return "GNET_SNMP_SECLEVEL_NANP"
-	case GNET_SNMP_SECLEVEL_ANP:
-		return "GNET_SNMP_SECLEVEL_ANP"
-	case GNET_SNMP_SECLEVEL_AP:
-		return "GNET_SNMP_SECLEVEL_AP"
-	}
-	return "UNKNOWN SecLevel"
-}
-
 // Stringer for _Ctype_GNetSnmpSecLevel
 //
 // C:
@@ -419,93 +275,10 @@ package main
 import ("github.com/soniah/gsnmpgo/enumconv")
 func main() {
 	ccode := "gsnmp-0.3.0/src/pdu.h"
-	vals := []string{"GNET_SNMP_PDU_ERR_DONE", "GNET_SNMP_PDU_ERR_PROCEDURE", "GNET_SNMP_PDU_ERR_INTERNAL", "GNET_SNMP_PDU_ERR_NORESPONSE", "GNET_SNMP_PDU_ERR_NOERROR", "GNET_SNMP_PDU_ERR_TOOBIG", "GNET_SNMP_PDU_ERR_NOSUCHNAME", "GNET_SNMP_PDU_ERR_BADVALUE", "GNET_SNMP_PDU_ERR_READONLY", "GNET_SNMP_PDU_ERR_GENERROR", "GNET_SNMP_PDU_ERR_NOACCESS", "GNET_SNMP_PDU_ERR_WRONGTYPE", "GNET_SNMP_PDU_ERR_WRONGLENGTH", "GNET_SNMP_PDU_ERR_WRONGENCODING", "GNET_SNMP_PDU_ERR_WRONGVALUE", "GNET_SNMP_PDU_ERR_NOCREATION", "GNET_SNMP_PDU_ERR_INCONSISTENTVALUE", "GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE", "GNET_SNMP_PDU_ERR_COMMITFAILED", "GNET_SNMP_PDU_ERR_UNDOFAILED", "GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR", "GNET_SNMP_PDU_ERR_NOTWRITABLE", "GNET_SNMP_PDU_ERR_INCONSISTENTNAME"}
-	enumconv.Write("PduError", "_Ctype_gint32", vals, ccode, -4)
+	enumconv.WriteC("PduError", "_Ctype_gint32", ccode)
 }
 gocog]]]*/
 
-// type and values for _Ctype_gint32
-type PduError int
-
-const (
-	GNET_SNMP_PDU_ERR_DONE PduError = iota - 4
-	GNET_SNMP_PDU_ERR_PROCEDURE
-	GNET_SNMP_PDU_ERR_INTERNAL
-	GNET_SNMP_PDU_ERR_NORESPONSE
-	GNET_SNMP_PDU_ERR_NOERROR
-	GNET_SNMP_PDU_ERR_TOOBIG
-	GNET_SNMP_PDU_ERR_NOSUCHNAME
-	GNET_SNMP_PDU_ERR_BADVALUE
-	GNET_SNMP_PDU_ERR_READONLY
-	GNET_SNMP_PDU_ERR_GENERROR
-	GNET_SNMP_PDU_ERR_NOACCESS
-	GNET_SNMP_PDU_ERR_WRONGTYPE
-	GNET_SNMP_PDU_ERR_WRONGLENGTH
-	GNET_SNMP_PDU_ERR_WRONGENCODING
-	GNET_SNMP_PDU_ERR_WRONGVALUE
-	GNET_SNMP_PDU_ERR_NOCREATION
-	GNET_SNMP_PDU_ERR_INCONSISTENTVALUE
-	GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE
-	GNET_SNMP_PDU_ERR_COMMITFAILED
-	GNET_SNMP_PDU_ERR_UNDOFAILED
-	GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR
-	GNET_SNMP_PDU_ERR_NOTWRITABLE
-	GNET_SNMP_PDU_ERR_INCONSISTENTNAME
-)
-
-// Stringer for PduError
-func (pduerror PduError) String() string {
-	switch pduerror {
-	case GNET_SNMP_PDU_ERR_DONE:
-		return "GNET_SNMP_PDU_ERR_DONE"
-	case GNET_SNMP_PDU_ERR_PROCEDURE:
-		return "GNET_SNMP_PDU_ERR_PROCEDURE"
-	case GNET_SNMP_PDU_ERR_INTERNAL:
-		return "GNET_SNMP_PDU_ERR_INTERNAL"
-	case GNET_SNMP_PDU_ERR_NORESPONSE:
-		return "GNET_SNMP_PDU_ERR_NORESPONSE"
-	case GNET_SNMP_PDU_ERR_NOERROR:
-		return "GNET_SNMP_PDU_ERR_NOERROR"
-	case GNET_SNMP_PDU_ERR_TOOBIG:
-		return "GNET_SNMP_PDU_ERR_TOOBIG"
-	case GNET_SNMP_PDU_ERR_NOSUCHNAME:
-		return "GNET_SNMP_PDU_ERR_NOSUCHNAME"
-	case GNET_SNMP_PDU_ERR_BADVALUE:
-		return "GNET_SNMP_PDU_ERR_BADVALUE"
-	case GNET_SNMP_PDU_ERR_READONLY:
-		return "GNET_SNMP_PDU_ERR_READONLY"
-	case GNET_SNMP_PDU_ERR_GENERROR:
-		return "GNET_SNMP_PDU_ERR_GENERROR"
-	case GNET_SNMP_PDU_ERR_NOACCESS:
-		return "GNET_SNMP_PDU_ERR_NOACCESS"
-	case GNET_SNMP_PDU_ERR_WRONGTYPE:
-		return "GNET_SNMP_PDU_ERR_WRONGTYPE"
-	case GNET_SNMP_PDU_ERR_WRONGLENGTH:
-		return "GNET_SNMP_PDU_ERR_WRONGLENGTH"
-	case GNET_SNMP_PDU_ERR_WRONGENCODING:
-		return "GNET_SNMP_PDU_ERR_WRONGENCODING"
-	case GNET_SNMP_PDU_ERR_WRONGVALUE:
-		return "GNET_SNMP_PDU_ERR_WRONGVALUE"
-	case GNET_SNMP_PDU_ERR_NOCREATION:
-		return "GNET_SNMP_PDU_ERR_NOCREATION"
-	case GNET_SNMP_PDU_ERR_INCONSISTENTVALUE:
-		return "GNET_SNMP_PDU_ERR_INCONSISTENTVALUE"
-	case GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE:
-		return "GNET_SNMP_PDU_ERR_RESOURCEUNAVAILABLE"
-	case GNET_SNMP_PDU_ERR_COMMITFAILED:
-		return "GNET_SNMP_PDU_ERR_COMMITFAILED"
-	case GNET_SNMP_PDU_ERR_UNDOFAILED:
-		return "GNET_SNMP_PDU_ERR_UNDOFAILED"
-	case GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR:
-		return "GNET_SNMP_PDU_ERR_AUTHORIZATIONERROR"
-	case GNET_SNMP_PDU_ERR_NOTWRITABLE:
-		return "GNET_SNMP_PDU_ERR_NOTWRITABLE"
-	case GNET_SNMP_PDU_ERR_INCONSISTENTNAME:
-		return "GNET_SNMP_PDU_ERR_INCONSISTENTNAME"
-	}
-	return "UNKNOWN PduError"
-}
-
 // Stringer for _Ctype_gint32
 //
 // C:
@@ -521,36 +294,10 @@ package main
 import ("github.com/soniah/gsnmpgo/enumconv")
 func main() {
 	ccode := "gsnmp-0.3.0/src/message.h"
-	vals := []string{"GNET_SNMP_V1", "GNET_SNMP_V2C", "GNET_SNMP_V2P", "GNET_SNMP_V3"}
-	enumconv.Write("SnmpVersion", "_Ctype_GNetSnmpVersion", vals, ccode, 0)
+	enumconv.WriteC("SnmpVersion", "_Ctype_GNetSnmpVersion", ccode)
 }
 gocog]]]*/
 
-// type and values for _Ctype_GNetSnmpVersion
-type SnmpVersion int
-
-const (
-	GNET_SNMP_V1 SnmpVersion = iota
-	GNET_SNMP_V2C
-	GNET_SNMP_V2P
-	GNET_SNMP_V3
-)
-
-// Stringer for SnmpVersion
-func (snmpversion SnmpVersion) String() string {
-	switch snmpversion {
-	case GNET_SNMP_V1:
-		return "GNET_SNMP_V1"
-	case GNET_SNMP_V2C:
-		return "GNET_SNMP_V2C"
-	case GNET_SNMP_V2P:
-		return "GNET_SNMP_V2P"
-	case GNET_SNMP_V3:
-		return "GNET_SNMP_V3"
-	}
-	return "UNKNOWN SnmpVersion"
-}
-
 // Stringer for _Ctype_GNetSnmpVersion
 //
 // C:
@@ -0,0 +1,214 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"fmt"
+	"github.com/petar/GoLLRB/llrb"
+	"sort"
+)
+
+// Table is an SNMP table (eg ifTable), pivoted from the column.index oids
+// returned by a walk into rows keyed by index.
+type Table struct {
+	Entry   OID        // the table's entry, eg ifEntry 1.3.6.1.2.1.2.2.1
+	Columns []uint32   // the column numbers found, in ascending order
+	Rows    []TableRow // the rows, in index order
+
+	rows map[string]int // index string to position in Rows
+}
+
+// TableRow is a row of a Table. In a sparse table, a row only has values
+// for some of the columns.
+type TableRow struct {
+	Index  OID                  // eg 3 for ifIndex 3, or 10.0.0.1 in ipAddrTable
+	Values map[uint32]Varbinder // the values, keyed by column number
+}
+
+// NewTable pivots the results of walking a table (eg from Query() or
+// Session.BulkWalk) into rows. oid is the table (eg ifTable
+// 1.3.6.1.2.1.2.2), or its entry (eg ifEntry 1.3.6.1.2.1.2.2.1); results
+// outside it are ignored. Without MIBs defining the entry, oid is taken to
+// be the entry if there are no rows below oid.1 but there are below oid.
+func NewTable(oid OID, results *llrb.Tree) *Table {
+	entry, known := tableEntry(oid)
+	table := newTable(entry, results)
+	if len(table.Rows) == 0 && !known {
+		if below := newTable(oid, results); len(below.Rows) > 0 {
+			return below
+		}
+	}
+	return table
+}
+
+// Table walks a table and returns its rows. oid is the table (eg ifTable
+// "1.3.6.1.2.1.2.2"), or its entry (eg ifEntry "1.3.6.1.2.1.2.2.1" or
+// "IF-MIB::ifEntry"). Without MIBs defining the entry, oid is taken to be
+// the table, and if the walk below oid.1 finds no rows oid is walked as the
+// entry instead. It's an error if neither walk finds any rows.
+//
+// A walk can't always tell an entry from a table: if oid is an entry whose
+// column 1 has a multi-part index (eg ipNetToMediaEntry), column 1 is
+// pivoted as if it were a table. Load MIBs for such tables, or give the
+// table rather than the entry.
+//
+// If columns are given only those columns are walked, otherwise the whole
+// table is. GETBULK is used, except for SNMP v1 sessions.
+func (s *Session) Table(oid string, columns ...uint32) (table *Table, err error) {
+	parsed, err := parseOidName(oid)
+	if err != nil {
+		return nil, err
+	}
+	entry, known := tableEntry(parsed)
+	if table, err = s.walkTable(entry, columns); err != nil || len(table.Rows) > 0 || known {
+		return table, err
+	}
+	if table, err = s.walkTable(parsed, columns); err != nil || len(table.Rows) > 0 {
+		return table, err
+	}
+	return nil, &SessionError{Msg: fmt.Sprintf("Table(): no rows found below %s", parsed)}
+}
+
+// Row returns the row with the given index.
+func (t *Table) Row(index OID) (row TableRow, ok bool) {
+	position, ok := t.rows[index.String()]
+	if !ok {
+		return TableRow{}, false
+	}
+	return t.Rows[position], true
+}
+
+// SplitIndex splits a multi-part row index into its parts. Each of lengths
+// is the number of sub-identifiers in a part, eg 1 for an INTEGER (such as
+// ifIndex) and 4 for an IpAddress. A length of 0 is a variable length part
+// (eg an OCTET STRING), whose first sub-identifier is its length; the length
+// isn't included in the part.
+//
+// For example the index of ipNetToMediaTable is ifIndex then IpAddress:
+//
+//    parts, err := gsnmpgo.SplitIndex(row.Index, 1, 4)
+//    // parts[0] is the ifIndex, parts[1] the ip address
+func SplitIndex(index OID, lengths ...int) (parts []OID, err error) {
+	rest := index
+	for i, length := range lengths {
+		if length == 0 {
+			if len(rest) == 0 {
+				return nil, fmt.Errorf("%s: SplitIndex(): index %s has no length for part %d", libname(), index, i)
+			}
+			length, rest = int(rest[0]), rest[1:]
+		}
+		if length < 0 || length > len(rest) {
+			return nil, fmt.Errorf("%s: SplitIndex(): index %s is too short for part %d", libname(), index, i)
+		}
+		parts = append(parts, rest[:length].Append())
+		rest = rest[length:]
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%s: SplitIndex(): index %s is longer than its parts", libname(), index)
+	}
+	return parts, nil
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// newTable pivots the results below entry into a Table.
+func newTable(entry OID, results *llrb.Tree) *Table {
+	table := &Table{Entry: entry, rows: make(map[string]int)}
+	columns := make(map[uint32]bool)
+	ch := results.IterAscend()
+	for {
+		r := <-ch
+		if r == nil {
+			break
+		}
+		result := r.(QueryResult)
+		cell, ok := result.Oid.Index(entry)
+		if !ok || len(cell) < 2 {
+			continue // not a column.index of this table
+		}
+		column, index := cell[0], cell[1:]
+		key := index.String()
+		position, ok := table.rows[key]
+		if !ok {
+			position = len(table.Rows)
+			table.rows[key] = position
+			table.Rows = append(table.Rows, TableRow{Index: index, Values: make(map[uint32]Varbinder)})
+		}
+		table.Rows[position].Values[column] = result.Value
+		if !columns[column] {
+			columns[column] = true
+			table.Columns = append(table.Columns, column)
+		}
+	}
+
+	// rows are found column by column, so a sparse first column leaves them
+	// out of order
+	sort.Sort(tableRows(table.Rows))
+	for position, row := range table.Rows {
+		table.rows[row.Index.String()] = position
+	}
+	sort.Sort(tableColumns(table.Columns))
+	return table
+}
+
+// tableColumns sorts column numbers.
+type tableColumns []uint32
+
+func (c tableColumns) Len() int           { return len(c) }
+func (c tableColumns) Less(i, j int) bool { return c[i] < c[j] }
+func (c tableColumns) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// tableEntry returns the entry of oid, and whether Mib says what oid is.
+// oid is the table, whose entry is its only child numbered 1 (RFC 2578
+// 7.1.12), unless Mib defines oid as an entry, ie an object with an INDEX or
+// AUGMENTS clause.
+func tableEntry(oid OID) (entry OID, known bool) {
+	if Mib != nil {
+		if node, index := Mib.Node(oid); node != nil && len(index) == 0 {
+			if len(node.Index) > 0 || node.Augments != "" {
+				return oid, true
+			}
+			return oid.Append(1), true
+		}
+	}
+	return oid.Append(1), false
+}
+
+// tableRows sorts rows by index.
+type tableRows []TableRow
+
+func (r tableRows) Len() int           { return len(r) }
+func (r tableRows) Less(i, j int) bool { return r[i].Index.Compare(r[j].Index) < 0 }
+func (r tableRows) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// walkTable walks the given columns of entry, or all of it if there are
+// none, and pivots the results into a Table.
+func (s *Session) walkTable(entry OID, columns []uint32) (table *Table, err error) {
+	request := []string{entry.String()}
+	if len(columns) > 0 {
+		request = request[:0]
+		for _, column := range columns {
+			request = append(request, entry.Append(column).String())
+		}
+	}
+	results, err := s.BulkWalk(request)
+	if err != nil {
+		return nil, err
+	}
+	return newTable(entry, results), nil
+}
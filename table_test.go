@@ -0,0 +1,215 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"github.com/petar/GoLLRB/llrb"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// tableResults is part of ipNetToMediaTable (1.3.6.1.2.1.4.22), indexed by
+// ifIndex and IpAddress, with a sparse column 4, and some results from
+// outside the table.
+var tableResults = []QueryResult{
+	{Oid: MustParseOID("1.3.6.1.2.1.4.21.1.1.0.0.0.0"), Value: VBT_IPAddress("0.0.0.0")},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.22.1.1.2.10.0.0.1"), Value: VBT_Integer32(2)},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.22.1.1.2.10.0.0.254"), Value: VBT_Integer32(2)},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.22.1.1.10.192.168.1.1"), Value: VBT_Integer32(10)},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.22.1.3.2.10.0.0.1"), Value: VBT_IPAddress("10.0.0.1")},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.22.1.3.2.10.0.0.254"), Value: VBT_IPAddress("10.0.0.254")},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.22.1.3.10.192.168.1.1"), Value: VBT_IPAddress("192.168.1.1")},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.22.1.4.10.192.168.1.1"), Value: VBT_Integer32(3)},
+	{Oid: MustParseOID("1.3.6.1.2.1.4.23.0"), Value: VBT_Counter32(0)},
+}
+
+var newTableTests = []struct {
+	oid     string
+	entry   string
+	columns []uint32
+	rows    []string // indexes
+	mib     bool     // whether MIBs are loaded
+}{
+	{"1.3.6.1.2.1.4.22", "1.3.6.1.2.1.4.22.1", []uint32{1, 3, 4}, []string{"2.10.0.0.1", "2.10.0.0.254", "10.192.168.1.1"}, false},
+	{"1.3.6.1.2.1.4.24", "1.3.6.1.2.1.4.24.1", nil, nil, false},
+	{"1.3.6.1.2.1.4.22", "1.3.6.1.2.1.4.22.1", []uint32{1, 3, 4}, []string{"2.10.0.0.1", "2.10.0.0.254", "10.192.168.1.1"}, true},
+	{"1.3.6.1.2.1.4.22.1", "1.3.6.1.2.1.4.22.1", []uint32{1, 3, 4}, []string{"2.10.0.0.1", "2.10.0.0.254", "10.192.168.1.1"}, true},
+}
+
+func TestNewTable(t *testing.T) {
+	results := llrb.New(LessOID)
+	for _, result := range tableResults {
+		results.ReplaceOrInsert(result)
+	}
+	mib, err := LoadMIBs("testing/mibs")
+	if err != nil {
+		t.Fatalf("LoadMIBs error: %s", err)
+	}
+	defer func() { Mib = nil }()
+	for i, test := range newTableTests {
+		Mib = nil
+		if test.mib {
+			Mib = mib
+		}
+		table := NewTable(MustParseOID(test.oid), results)
+		if entry := table.Entry.String(); entry != test.entry {
+			t.Errorf("#%d: %s: expected entry %s got %s", i, test.oid, test.entry, entry)
+		}
+		if !reflect.DeepEqual(table.Columns, test.columns) {
+			t.Errorf("#%d: %s: expected columns %v got %v", i, test.oid, test.columns, table.Columns)
+		}
+		var rows []string
+		for _, row := range table.Rows {
+			rows = append(rows, row.Index.String())
+		}
+		if !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("#%d: %s: expected rows %v got %v", i, test.oid, test.rows, rows)
+		}
+	}
+
+	// only column 1 looks the same below the table as below the entry
+	column1 := llrb.New(LessOID)
+	for _, result := range tableResults[1:4] {
+		column1.ReplaceOrInsert(result)
+	}
+	table := NewTable(MustParseOID("1.3.6.1.2.1.4.22.1"), column1)
+	if len(table.Columns) != 1 || len(table.Rows) != 3 {
+		t.Errorf("NewTable(ipNetToMediaEntry) of column 1: expected 1 column and 3 rows, got %v and %d rows",
+			table.Columns, len(table.Rows))
+	}
+
+	// without MIBs, an entry whose column 1 isn't in the results
+	Mib = nil
+	sparse := llrb.New(LessOID)
+	for _, result := range tableResults[4:] {
+		sparse.ReplaceOrInsert(result)
+	}
+	table = NewTable(MustParseOID("1.3.6.1.2.1.4.22.1"), sparse)
+	if entry := table.Entry.String(); entry != "1.3.6.1.2.1.4.22.1" || len(table.Rows) != 3 {
+		t.Errorf("NewTable(ipNetToMediaEntry) without MIBs: expected entry 1.3.6.1.2.1.4.22.1 and 3 rows, got %s and %d rows",
+			entry, len(table.Rows))
+	}
+
+	table = NewTable(MustParseOID("1.3.6.1.2.1.4.22"), results)
+	row, ok := table.Row(MustParseOID("2.10.0.0.254"))
+	if !ok {
+		t.Fatalf("Row(2.10.0.0.254) not found")
+	}
+	if value, ok := row.Values[3].(VBT_IPAddress); !ok || value != "10.0.0.254" {
+		t.Errorf("Row(2.10.0.0.254) column 3: expected 10.0.0.254 got %v", row.Values[3])
+	}
+	if _, ok := row.Values[4]; ok {
+		t.Errorf("Row(2.10.0.0.254): expected no value for sparse column 4")
+	}
+	if _, ok := table.Row(MustParseOID("2.10.0.0.2")); ok {
+		t.Errorf("Row(2.10.0.0.2): expected no row")
+	}
+}
+
+func TestSessionTableEntry(t *testing.T) {
+	mib, err := LoadMIBs("testing/mibs")
+	if err != nil {
+		t.Fatalf("LoadMIBs error: %s", err)
+	}
+	Mib = mib
+	defer func() { Mib = nil }()
+
+	results := llrb.New(LessOID)
+	for _, result := range tableResults {
+		results.ReplaceOrInsert(result)
+	}
+	agent := NewAgent(results)
+	if err := agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen error: %s", err)
+	}
+	defer agent.Close()
+	s := NewSession("127.0.0.1", "public", GNET_SNMP_V2C)
+	s.Port = agent.Addr().(*net.UDPAddr).Port
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		oid     string
+		columns []uint32
+		rows    []string
+	}{
+		{"1.3.6.1.2.1.4.22.1", []uint32{1, 3}, []string{"2.10.0.0.1", "2.10.0.0.254", "10.192.168.1.1"}},
+		{"1.3.6.1.2.1.4.22.1", []uint32{1}, []string{"2.10.0.0.1", "2.10.0.0.254", "10.192.168.1.1"}},
+		{"RFC1213-MIB::ipNetToMediaEntry", []uint32{4}, []string{"10.192.168.1.1"}},
+		{"ipNetToMediaTable", []uint32{1, 3}, []string{"2.10.0.0.1", "2.10.0.0.254", "10.192.168.1.1"}},
+	}
+	for i, test := range tests {
+		table, err := s.Table(test.oid, test.columns...)
+		if err != nil {
+			t.Errorf("#%d: Table(%s) error: %s", i, test.oid, err)
+			continue
+		}
+		if entry := table.Entry.String(); entry != "1.3.6.1.2.1.4.22.1" {
+			t.Errorf("#%d: Table(%s) expected entry 1.3.6.1.2.1.4.22.1 got %s", i, test.oid, entry)
+		}
+		if !reflect.DeepEqual(table.Columns, test.columns) {
+			t.Errorf("#%d: Table(%s) expected columns %v got %v", i, test.oid, test.columns, table.Columns)
+		}
+		var rows []string
+		for _, row := range table.Rows {
+			rows = append(rows, row.Index.String())
+		}
+		if !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("#%d: Table(%s) expected rows %v got %v", i, test.oid, test.rows, rows)
+		}
+	}
+}
+
+var splitIndexTests = []struct {
+	index   string
+	lengths []int
+	parts   []string // nil for an error
+}{
+	{"2.10.0.0.1", []int{1, 4}, []string{"2", "10.0.0.1"}},
+	{"10.0.0.1", []int{4}, []string{"10.0.0.1"}},
+	{"4.112.117.98.108.3", []int{0, 1}, []string{"112.117.98.108", "3"}},
+	{"2.10.0.0.1", []int{1, 3}, nil},
+	{"2.10.0.0", []int{1, 4}, nil},
+	{"9.1", []int{0}, nil},
+}
+
+func TestSplitIndex(t *testing.T) {
+	for i, test := range splitIndexTests {
+		parts, err := SplitIndex(MustParseOID(test.index), test.lengths...)
+		if test.parts == nil {
+			if err == nil {
+				t.Errorf("#%d: SplitIndex(%s, %v) expected error", i, test.index, test.lengths)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: SplitIndex(%s, %v) error: %s", i, test.index, test.lengths, err)
+			continue
+		}
+		var got []string
+		for _, part := range parts {
+			got = append(got, part.String())
+		}
+		if !reflect.DeepEqual(got, test.parts) {
+			t.Errorf("#%d: SplitIndex(%s, %v) expected %v got %v", i, test.index, test.lengths, test.parts, got)
+		}
+	}
+}
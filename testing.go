@@ -40,7 +40,6 @@ func ReadVeraxResults(filename string) (results *llrb.Tree, err error) {
 
 	// some lines have newlines in them, therefore can't just split on newline
 	lines_split := re_split(regexp.MustCompile(`\n\.`), string(lines), -1)
-LINE:
 	for _, line := range lines_split {
 		splits_a := strings.SplitN(line, " = ", 2)
 		oid := splits_a[0]
@@ -56,10 +55,17 @@ LINE:
 		var value Varbinder
 		switch oidtype {
 
-		case "STRING", "String", "Hex-STRING":
+		case "STRING", "String":
 			oidval = strings.Trim(oidval, `"`)
 			value = VBT_OctetString(oidval)
 
+		case "Hex-STRING":
+			data, err := parseHexString(oidval)
+			if err != nil {
+				return nil, fmt.Errorf("%s in file %s", err, filename)
+			}
+			value = VBT_OctetString(data)
+
 		case "OID":
 			value = VBT_ObjectID(oidval)
 
@@ -98,16 +104,25 @@ LINE:
 			}
 
 		case "BITS":
-			continue LINE
-			// TODO is BITS Verax specific, or doesn't gsnmp handle?
+			// BITS are sent as an OCTET STRING; Verax shows the bytes in
+			// hex, then the bits that are set, eg
 			// .1.3.6.1.2.1.88.1.4.2.1.3.6.95.115.110.109.112.100.95.109.116.101.
 			// 84.114.105.103.103.101.114.70.105.114.101.100
 			// = BITS: 38 30 20 30 2 3 4 10 11 18 26 27
+			data, err := veraxBits(oidval)
+			if err != nil {
+				return nil, fmt.Errorf("%s in file %s", err, filename)
+			}
+			value = VBT_OctetString(data)
 
 		default:
 			panic(fmt.Sprintf("Unhandled type: %s, %s\n", oidtype, oidval))
 		}
-		result := QueryResult{Oid: oid, Value: value}
+		parsed_oid, err := ParseOID(oid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid %s in file %s", oid, filename)
+		}
+		result := QueryResult{Oid: parsed_oid, Value: value}
 		results.ReplaceOrInsert(result)
 	}
 	return results, nil
@@ -189,3 +204,31 @@ func re_split(re *regexp.Regexp, s string, n int) []string {
 	}
 	return strings
 }
+
+// veraxBits parses a Verax BITS value: the bytes in hex, followed by the
+// numbers of the bits that are set (possibly named, eg "boolean(1)"). As
+// both are numbers, the split between them is where the bits set in the
+// bytes match the numbers that follow.
+func veraxBits(oidval string) (data []byte, err error) {
+	fields := strings.Fields(oidval)
+	for i, field := range fields {
+		if open := strings.Index(field, "("); open >= 0 && strings.HasSuffix(field, ")") {
+			fields[i] = field[open+1 : len(field)-1]
+		}
+	}
+	for n := 1; n <= len(fields); n++ {
+		if data, err = parseHexString(strings.Join(fields[:n], " ")); err != nil {
+			break
+		}
+		var bits []string
+		for i := 0; i < n*8; i++ {
+			if data[i/8]&(0x80>>uint(i%8)) != 0 {
+				bits = append(bits, strconv.Itoa(i))
+			}
+		}
+		if strings.Join(bits, " ") == strings.Join(fields[n:], " ") {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid BITS value %s", oidval)
+}
@@ -0,0 +1,150 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// transport.go contains the transports SNMP messages are sent over.
+// github.com/natefinch/gocog is used to generate the boilerplate for
+// Transport. AFTER EDITING any gocog sections (between gocog open and close
+// square brackets), you MUST run:
+//
+//     rm -f transport.go_cog; $GOPATH/bin/gocog transport.go; go fmt ./...
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+)
+
+// the largest message read from a stream transport; SNMP engines must
+// accept messages of at least 484 bytes, and agents rarely send more than
+// 64k (RFC 3430 2.1)
+const maxStreamMessage = 1 << 20
+
+// Transport is the transport that SNMP messages are sent over:
+//
+//     TRANSPORT_UDP   UDP over ipv4 or ipv6 (RFC 3417), the default
+//     TRANSPORT_TCP   TCP over ipv4 or ipv6 (RFC 3430)
+//     TRANSPORT_UNIX  a Unix-domain stream socket, eg net-snmp's unix:/var/agentx/master
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"TRANSPORT_UDP", "TRANSPORT_TCP", "TRANSPORT_UNIX"}
+	enumconv.WriteGo("Transport", "Transport", vals, 0)
+}
+gocog]]]*/
+
+// type and values for Transport
+type Transport int
+
+const (
+	TRANSPORT_UDP Transport = iota
+	TRANSPORT_TCP
+	TRANSPORT_UNIX
+)
+
+// Stringer for Transport
+func (transport Transport) String() string {
+	switch transport {
+	case TRANSPORT_UDP:
+		return "TRANSPORT_UDP"
+	case TRANSPORT_TCP:
+		return "TRANSPORT_TCP"
+	case TRANSPORT_UNIX:
+		return "TRANSPORT_UNIX"
+	}
+	return "UNKNOWN Transport"
+}
+
+//[[[end]]]
+
+// ------------------- other functions in alphabetical order --------------------
+
+// queryAddr returns the address of the agent in a query's uri. The
+// transport is that of the uri's scheme, or params.Transport for a snmp://
+// uri.
+func queryAddr(ctx context.Context, params *QueryParams, uri *snmpUri) (net.Addr, error) {
+	transport := uri.transport
+	if transport == TRANSPORT_UDP {
+		transport = params.Transport
+	}
+	if transport == TRANSPORT_UNIX && uri.transport != TRANSPORT_UNIX {
+		return nil, &UriError{Uri: params.Uri, Pos: 0, Msg: "TRANSPORT_UNIX needs a snmp+unix:// uri"}
+	}
+	return transportAddr(ctx, transport, params.Resolver, uri.host, uri.port)
+}
+
+// readMessage reads one message from a stream transport. Messages are sent
+// one after another without any framing, as each is a BER SEQUENCE that
+// holds its own length (RFC 3430 2.1).
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2, 6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != berSequence {
+		return nil, fmt.Errorf("%s: readMessage(): expected a message, got tag 0x%02x", libname(), header[0])
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("%s: readMessage(): invalid length", libname())
+		}
+		header = header[:2+n]
+		if _, err := io.ReadFull(r, header[2:]); err != nil {
+			return nil, err
+		}
+		length = 0
+		for _, c := range header[2:] {
+			length = length<<8 | int(c)
+		}
+	}
+	if length < 0 || length > maxStreamMessage {
+		return nil, fmt.Errorf("%s: readMessage(): message of %d bytes is too long", libname(), length)
+	}
+	msg := make([]byte, len(header)+length)
+	copy(msg, header)
+	if _, err := io.ReadFull(r, msg[len(header):]); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// transportAddr returns the address of an agent: host and port resolved by
+// resolver (or DefaultResolver) for UDP and TCP, or host as the path of a
+// Unix-domain socket.
+func transportAddr(ctx context.Context, transport Transport, resolver *Resolver,
+	host string, port int) (net.Addr, error) {
+	switch transport {
+	case TRANSPORT_UDP:
+		return resolveAddr(ctx, resolver, host, port)
+	case TRANSPORT_TCP:
+		addr, err := resolveAddr(ctx, resolver, host, port)
+		if err != nil {
+			return nil, err
+		}
+		return &net.TCPAddr{IP: addr.IP, Port: addr.Port}, nil
+	case TRANSPORT_UNIX:
+		return &net.UnixAddr{Name: host, Net: "unix"}, nil
+	}
+	return nil, &SessionError{Msg: "unsupported transport " + transport.String()}
+}
@@ -0,0 +1,246 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var readMessageTests = []struct {
+	stream   []byte
+	expected [][]byte // the messages read, before an error or EOF
+	ok       bool     // whether the stream ends cleanly
+}{
+	{[]byte{0x30, 0x00}, [][]byte{{0x30, 0x00}}, true},
+	{[]byte{0x30, 0x02, 0x05, 0x00, 0x30, 0x01, 0x07},
+		[][]byte{{0x30, 0x02, 0x05, 0x00}, {0x30, 0x01, 0x07}}, true},
+	{append([]byte{0x30, 0x81, 0x80}, make([]byte, 0x80)...),
+		[][]byte{append([]byte{0x30, 0x81, 0x80}, make([]byte, 0x80)...)}, true},
+	{append([]byte{0x30, 0x82, 0x01, 0x00}, make([]byte, 0x100)...),
+		[][]byte{append([]byte{0x30, 0x82, 0x01, 0x00}, make([]byte, 0x100)...)}, true},
+	{[]byte{0x30, 0x02, 0x05, 0x00, 0x02, 0x01, 0x00}, [][]byte{{0x30, 0x02, 0x05, 0x00}}, false}, // not a SEQUENCE
+	{[]byte{0x30, 0x03, 0x05, 0x00}, nil, false},                                                  // truncated
+	{[]byte{0x30, 0x82, 0x01}, nil, false},                                                        // truncated length
+	{[]byte{0x30, 0x80, 0x05, 0x00, 0x00, 0x00}, nil, false},                                      // indefinite length
+	{[]byte{0x30, 0x85, 0x01, 0x00, 0x00, 0x00, 0x00}, nil, false},                                // 5 length octets
+	{[]byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff}, nil, false},                                      // too long
+}
+
+func TestReadMessage(t *testing.T) {
+	for i, test := range readMessageTests {
+		r := bufio.NewReader(bytes.NewReader(test.stream))
+		var got [][]byte
+		var err error
+		for {
+			var msg []byte
+			if msg, err = readMessage(r); err != nil {
+				break
+			}
+			got = append(got, msg)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("#%d: readMessage expected %x got %x", i, test.expected, got)
+		}
+		if ok := err == io.EOF; ok != test.ok {
+			t.Errorf("#%d: readMessage expected clean EOF %t, got %s", i, test.ok, err)
+		}
+	}
+}
+
+// streamAgent returns an agent serving the first Verax device over network
+// ("tcp" or "unix"), and the uri of the agent without any oids.
+func streamAgent(t *testing.T, network string) (agent *Agent, uri string, ok bool) {
+	path := veraxDevices[0]
+	if _, err := os.Stat(path); err != nil {
+		t.Logf("%s: skipping, %s", path, err)
+		return nil, "", false
+	}
+	vresults, err := ReadVeraxResults(path)
+	if err != nil {
+		t.Fatalf("%s: ReadVeraxResults error: %s", path, err)
+	}
+	agent = NewAgent(vresults)
+	switch network {
+	case "tcp":
+		if err = agent.ListenStream("tcp", "127.0.0.1:0"); err != nil {
+			t.Fatalf("ListenStream error: %s", err)
+		}
+		uri = "snmp+tcp://public@" + agent.Addr().String()
+	case "unix":
+		socket := filepath.Join(t.TempDir(), "agent.sock")
+		if err = agent.ListenStream("unix", socket); err != nil {
+			t.Fatalf("ListenStream error: %s", err)
+		}
+		uri = "snmp+unix://public@" + uriEscape(socket)
+	}
+	return agent, uri, true
+}
+
+func TestQueryTransports(t *testing.T) {
+	for i, network := range []string{"tcp", "unix"} {
+		agent, uri, ok := streamAgent(t, network)
+		if !ok {
+			return
+		}
+		defer agent.Close()
+
+		uri += "//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.2.2.1.2.1)"
+		results, status, err := QueryWithStatus(NewDefaultParams(uri))
+		if err != nil {
+			t.Errorf("#%d: Query(%s) error: %s", i, uri, err)
+			continue
+		}
+		if results.Len() != 2 {
+			t.Errorf("#%d: Query(%s) expected 2 results got %d", i, uri, results.Len())
+		}
+		if status.Addr == nil || status.Addr.Network() != network {
+			t.Errorf("#%d: Query(%s) expected a %s Addr got %v", i, uri, network, status.Addr)
+		}
+
+		uri = uri[:len(uri)-len("(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.2.2.1.2.1)")] + "1.3.6.1.2.1.2.2.1.2.*"
+		if results, err = Query(NewDefaultParams(uri)); err != nil {
+			t.Errorf("#%d: Query(%s) error: %s", i, uri, err)
+		} else if results.Len() != 3 {
+			t.Errorf("#%d: Query(%s) expected 3 results got %d", i, uri, results.Len())
+		}
+	}
+
+	// a snmp:// uri, with the transport in the params
+	agent, uri, ok := streamAgent(t, "tcp")
+	if !ok {
+		return
+	}
+	defer agent.Close()
+	params := NewDefaultParams("snmp" + uri[len("snmp+tcp"):] + "//1.3.6.1.2.1.1.1.0")
+	params.Transport = TRANSPORT_TCP
+	if results, err := Query(params); err != nil || results.Len() != 1 {
+		t.Errorf("Query(%s) over TRANSPORT_TCP expected 1 result, got %v", params.Uri, err)
+	}
+
+	params.Transport = TRANSPORT_UNIX
+	var uri_err *UriError
+	if _, err := Query(params); !errors.As(err, &uri_err) {
+		t.Errorf("Query(%s) over TRANSPORT_UNIX expected UriError, got %v", params.Uri, err)
+	}
+}
+
+func TestSessionTransports(t *testing.T) {
+	for i, network := range []string{"tcp", "unix"} {
+		agent, _, ok := streamAgent(t, network)
+		if !ok {
+			return
+		}
+		defer agent.Close()
+
+		s := NewSession("", "public", GNET_SNMP_V2C)
+		switch addr := agent.Addr().(type) {
+		case *net.TCPAddr:
+			s.Host, s.Port, s.Transport = addr.IP.String(), addr.Port, TRANSPORT_TCP
+		case *net.UnixAddr:
+			s.Host, s.Transport = addr.Name, TRANSPORT_UNIX
+		}
+		if err := s.Open(); err != nil {
+			t.Fatalf("#%d: Open error: %s", i, err)
+		}
+		defer s.Close()
+		if addr := s.Addr(); addr == nil || addr.String() != agent.Addr().String() {
+			t.Errorf("#%d: expected Addr %s got %v", i, agent.Addr(), addr)
+		}
+
+		// several requests over the one connection
+		for j := 0; j < 3; j++ {
+			results, err := s.BulkWalk([]string{"1.3.6.1.2.1.2.2.1.2"})
+			if err != nil {
+				t.Fatalf("#%d: BulkWalk error: %s", i, err)
+			}
+			if results.Len() != 3 {
+				t.Errorf("#%d: BulkWalk expected 3 results got %d", i, results.Len())
+			}
+		}
+		if results, err := s.Get([]string{"1.3.6.1.2.1.1.1.0"}); err != nil || results.Len() != 1 {
+			t.Errorf("#%d: Get expected 1 result, got %v", i, err)
+		}
+	}
+}
+
+func TestRequestTransports(t *testing.T) {
+	agent, uri, ok := streamAgent(t, "unix")
+	if !ok {
+		return
+	}
+	defer agent.Close()
+
+	uri += "//1.3.6.1.2.1.2.2.1.2.*"
+	req, err := ParseRequest(uri)
+	if err != nil {
+		t.Fatalf("ParseRequest(%s) error: %s", uri, err)
+	}
+	if req.Transport != TRANSPORT_UNIX || req.Host != agent.Addr().String() {
+		t.Errorf("ParseRequest(%s) expected TRANSPORT_UNIX to %s, got %s to %s", uri, agent.Addr(), req.Transport, req.Host)
+	}
+	if got, err := req.Uri(); err != nil || got != uri {
+		t.Errorf("Uri() expected %s got %s (%v)", uri, got, err)
+	}
+	results, err := QueryRequest(req)
+	if err != nil {
+		t.Fatalf("QueryRequest error: %s", err)
+	}
+	if results.Len() != 3 {
+		t.Errorf("QueryRequest expected 3 results got %d", results.Len())
+	}
+
+	req = NewRequest(agent.Addr().String()+".missing", "public", GNET_SNMP_V2C, OP_GET, "1.3.6.1.2.1.1.1.0")
+	req.Transport, req.Timeout, req.Retries = TRANSPORT_UNIX, 100, 0
+	if _, err := QueryRequest(req); err == nil {
+		t.Errorf("QueryRequest to a missing socket expected an error")
+	}
+}
+
+func TestAgentListenStream(t *testing.T) {
+	agent, _, ok := streamAgent(t, "tcp")
+	if !ok {
+		return
+	}
+	if err := agent.ListenStream("tcp", "127.0.0.1:0"); err == nil {
+		t.Errorf("expected an error listening twice")
+	}
+	conn, err := net.Dial("tcp", agent.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial error: %s", err)
+	}
+	defer conn.Close()
+
+	// Close must not wait for the client to hang up
+	if err := agent.Close(); err != nil {
+		t.Errorf("Close error: %s", err)
+	}
+	if _, err := readMessage(bufio.NewReader(conn)); err == nil {
+		t.Errorf("expected the connection to be closed by Close")
+	}
+	if err := agent.Close(); err != nil {
+		t.Errorf("second Close error: %s", err)
+	}
+}
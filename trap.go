@@ -0,0 +1,435 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"code.google.com/p/tcgl/applog"
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// sysUpTime.0 and snmpTrapOID.0, the first two varbinds of a v2c
+// notification (RFC 3416 4.2.6)
+var (
+	sysUpTimeOid   = MustParseOID("1.3.6.1.2.1.1.3.0")
+	snmpTrapOidOid = MustParseOID("1.3.6.1.6.3.1.1.4.1.0")
+)
+
+// snmpTraps, the prefix of the generic trap oids (RFC 3584 3.1)
+var snmpTrapsOid = MustParseOID("1.3.6.1.6.3.1.1.5")
+
+// Notification is an SNMP trap or inform received by a Listener.
+type Notification struct {
+	Source    *net.UDPAddr // address the notification was sent from
+	Version   SnmpVersion  // GNET_SNMP_V1 or GNET_SNMP_V2C
+	Community string
+	Inform    bool // an InformRequest; the Listener has already acknowledged it
+
+	// TrapOid is snmpTrapOID.0; for v1 traps it is made from the
+	// enterprise, generic-trap and specific-trap fields as in RFC 3584.
+	TrapOid OID
+
+	// Uptime is sysUpTime.0, or the time-stamp field of a v1 trap.
+	Uptime VBT_Timeticks
+
+	// v1 traps only
+	Enterprise   OID
+	AgentAddress string
+	GenericTrap  int
+	SpecificTrap int
+
+	// Varbinds are in the order they were sent; for v2c, sysUpTime.0 and
+	// snmpTrapOID.0 are left out (see Uptime and TrapOid).
+	Varbinds []QueryResult
+}
+
+// Listener receives v1 traps, v2c traps and v2c informs on a UDP port, and
+// delivers them on C. Informs are acknowledged before being delivered.
+// Messages that can't be decoded, and v3 messages, are dropped, and aren't
+// acknowledged if they are informs.
+type Listener struct {
+	C <-chan *Notification
+
+	conn          *net.UDPConn
+	notifications chan *Notification
+	done          chan struct{}
+	closeOnce     sync.Once
+	wg            sync.WaitGroup
+}
+
+// NewListener listens for notifications on the UDP address addr, eg ":162"
+// (the standard port usually needs root), or "127.0.0.1:0" for any free
+// port on loopback (see Addr()).
+func NewListener(addr string) (*Listener, error) {
+	udp_addr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, &SessionError{Msg: "NewListener(): invalid address " + addr, Err: err}
+	}
+	conn, err := net.ListenUDP("udp", udp_addr)
+	if err != nil {
+		return nil, &SessionError{Msg: "NewListener(): unable to listen on " + addr, Err: err}
+	}
+
+	l := &Listener{
+		conn:          conn,
+		notifications: make(chan *Notification, 100),
+		done:          make(chan struct{}),
+	}
+	l.C = l.notifications
+	l.wg.Add(1)
+	go l.receive()
+	return l, nil
+}
+
+// Addr returns the address the Listener is listening on.
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Close stops the Listener and closes C; notifications already queued on C
+// can still be read. It is safe to call Close more than once.
+func (l *Listener) Close() (err error) {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.conn.Close()
+		l.wg.Wait()
+	})
+	return err
+}
+
+// receive reads messages until the Listener is closed.
+func (l *Listener) receive() {
+	defer l.wg.Done()
+	defer close(l.notifications)
+
+	packet := make([]byte, 65535)
+	for {
+		n, source, err := l.conn.ReadFromUDP(packet)
+		if err != nil {
+			select {
+			case <-l.done:
+				return
+			default:
+			}
+			if Debug {
+				applog.Warningf("Listener: read error: %s", err)
+			}
+			continue
+		}
+
+		notification, err := l.handle(packet[:n], source)
+		if err != nil {
+			if Debug {
+				applog.Warningf("Listener: dropping message from %s: %s", source, err)
+			}
+			continue
+		}
+		select {
+		case l.notifications <- notification:
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// handle decodes a message, and acknowledges it if it's an inform. An
+// inform is only acknowledged once it's known to be a valid notification,
+// so that the sender retries one that is dropped.
+func (l *Listener) handle(packet []byte, source *net.UDPAddr) (*Notification, error) {
+	msg, err := decodeMessage(packet)
+	if err != nil {
+		return nil, err
+	}
+	notification, err := newNotification(msg)
+	if err != nil {
+		return nil, err
+	}
+	if msg.pduType == pduInform {
+		response, err := informResponse(packet)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = l.conn.WriteToUDP(response, source); err != nil {
+			return nil, err
+		}
+	}
+	notification.Source = source
+	return notification, nil
+}
+
+// SendTrap sends n as a trap to the manager in params.Uri, eg
+// snmp://public@nms.example.com (the port defaults to 162). A v1 trap is sent
+// if params.Version is GNET_SNMP_V1, otherwise a v2c trap.
+//
+// For v2c, n.Uptime, n.TrapOid and n.Varbinds are sent. For v1, the
+// Enterprise, GenericTrap and SpecificTrap fields are sent if Enterprise is
+// set, otherwise they are made from TrapOid as in RFC 3584. AgentAddress
+// defaults to the local address used to reach the manager.
+//
+// Notifications are only sent over UDP; a params.Transport other than
+// TRANSPORT_UDP is an error.
+func SendTrap(params *QueryParams, n *Notification) error {
+	return sendNotification(params, n, false)
+}
+
+// SendInform sends n as a v2c inform to the manager in params.Uri, and waits
+// for it to be acknowledged. Each attempt waits params.Timeout milliseconds,
+// and is retried params.Retries times; a *TimeoutError is returned if the
+// inform isn't acknowledged.
+func SendInform(params *QueryParams, n *Notification) error {
+	return sendNotification(params, n, true)
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// informResponse returns the Response to an InformRequest message: the
+// same message with the pdu type changed, since the request-id, a zero
+// error-status and error-index, and the variable-bindings are all kept
+// (RFC 3416 4.2.7).
+func informResponse(packet []byte) ([]byte, error) {
+	body, _, err := berExpect(packet, berSequence, "message")
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		tag  byte
+		name string
+	}{{berInteger, "version"}, {berOctetString, "community"}} {
+		if _, body, err = berExpect(body, field.tag, field.name); err != nil {
+			return nil, err
+		}
+	}
+	if len(body) == 0 || body[0] != pduInform {
+		return nil, fmt.Errorf("%s: informResponse(): not an inform", libname())
+	}
+
+	// body shares packet's backing array, so the difference in capacity is
+	// the offset of the pdu tag
+	response := make([]byte, len(packet))
+	copy(response, packet)
+	response[cap(packet)-cap(body)] = pduResponse
+	return response, nil
+}
+
+// newNotification converts a decoded trap or inform to a Notification.
+func newNotification(msg *snmpMessage) (*Notification, error) {
+	if msg.version == GNET_SNMP_V3 {
+		return nil, fmt.Errorf("%s: newNotification(): v3 notifications aren't supported", libname())
+	}
+	notification := &Notification{
+		Version:   msg.version,
+		Community: msg.community,
+		Inform:    msg.pduType == pduInform,
+	}
+
+	switch msg.pduType {
+	case pduTrapV1:
+		notification.Enterprise = msg.enterprise
+		notification.AgentAddress = msg.agentAddress
+		notification.GenericTrap = msg.genericTrap
+		notification.SpecificTrap = msg.specificTrap
+		notification.Uptime = VBT_Timeticks(msg.timestamp)
+		notification.Varbinds = msg.varbinds
+		if msg.genericTrap == 6 { // enterpriseSpecific
+			notification.TrapOid = msg.enterprise.Append(0, uint32(msg.specificTrap))
+		} else {
+			notification.TrapOid = snmpTrapsOid.Append(uint32(msg.genericTrap + 1))
+		}
+
+	case pduTrapV2, pduInform:
+		for _, varbind := range msg.varbinds {
+			switch {
+			case varbind.Oid.Compare(sysUpTimeOid) == 0:
+				if uptime, ok := varbind.Value.(VBT_Timeticks); ok {
+					notification.Uptime = uptime
+					continue
+				}
+			case varbind.Oid.Compare(snmpTrapOidOid) == 0:
+				if trap_oid, ok := varbind.Value.(VBT_ObjectID); ok {
+					if oid, err := ParseOID(string(trap_oid)); err == nil {
+						notification.TrapOid = oid
+						continue
+					}
+				}
+			}
+			notification.Varbinds = append(notification.Varbinds, varbind)
+		}
+		if len(notification.TrapOid) == 0 {
+			return nil, fmt.Errorf("%s: newNotification(): no snmpTrapOID.0", libname())
+		}
+
+	default:
+		return nil, fmt.Errorf("%s: newNotification(): pdu type 0x%02x isn't a notification",
+			libname(), msg.pduType)
+	}
+	return notification, nil
+}
+
+// notificationMessage returns the message for sending n.
+func notificationMessage(version SnmpVersion, community string, n *Notification,
+	inform bool, local_addr net.Addr) (msg *snmpMessage, err error) {
+
+	msg = &snmpMessage{
+		version:   version,
+		community: community,
+		requestID: rand.Int31(),
+	}
+
+	switch {
+	case version == GNET_SNMP_V1 && inform:
+		return nil, &SessionError{Msg: "sendNotification(): informs need snmp v2c"}
+
+	case version == GNET_SNMP_V1:
+		msg.pduType = pduTrapV1
+		msg.enterprise = n.Enterprise
+		msg.genericTrap, msg.specificTrap = n.GenericTrap, n.SpecificTrap
+		if len(msg.enterprise) == 0 {
+			if msg.enterprise, msg.genericTrap, msg.specificTrap, err = trapV1Fields(n.TrapOid); err != nil {
+				return nil, err
+			}
+		}
+		msg.agentAddress = n.AgentAddress
+		if msg.agentAddress == "" {
+			msg.agentAddress = "0.0.0.0"
+			if udp_addr, ok := local_addr.(*net.UDPAddr); ok && udp_addr.IP.To4() != nil {
+				msg.agentAddress = udp_addr.IP.String()
+			}
+		}
+		msg.timestamp = uint32(n.Uptime)
+		msg.varbinds = n.Varbinds
+
+	case version == GNET_SNMP_V2C:
+		if len(n.TrapOid) < 2 {
+			return nil, fmt.Errorf("%s: sendNotification(): invalid trap oid %s", libname(), n.TrapOid)
+		}
+		msg.pduType = pduTrapV2
+		if inform {
+			msg.pduType = pduInform
+		}
+		msg.varbinds = append([]QueryResult{
+			{Oid: sysUpTimeOid, Value: n.Uptime},
+			{Oid: snmpTrapOidOid, Value: VBT_ObjectID("." + n.TrapOid.String())},
+		}, n.Varbinds...)
+
+	default:
+		return nil, &SessionError{Msg: fmt.Sprintf("sendNotification(): unsupported version %s", version)}
+	}
+	return msg, nil
+}
+
+// notificationTarget returns the host, port and community of the manager in
+// an snmp uri. The port defaults to 162 and the community to "public"; the
+// community may be percent-encoded, as in Query's uris. Notifications are
+// only sent over UDP, so the scheme must be snmp://.
+func notificationTarget(uri string) (host string, port int, community string, err error) {
+	parsed, err := parseSnmpUriPort(uri, 162)
+	if err != nil {
+		return "", 0, "", err
+	}
+	if parsed.transport != TRANSPORT_UDP {
+		return "", 0, "", &UriError{Uri: uri, Pos: 0, Msg: "notifications can only be sent to snmp:// targets"}
+	}
+	return parsed.host, parsed.port, parsed.community, nil
+}
+
+// sendNotification sends n, and if inform is true waits for the
+// acknowledgement.
+func sendNotification(params *QueryParams, n *Notification, inform bool) error {
+	if params.Transport != TRANSPORT_UDP {
+		return &SessionError{Msg: "sendNotification(): notifications can only be sent over UDP, not " + params.Transport.String()}
+	}
+	host, port, community, err := notificationTarget(params.Uri)
+	if err != nil {
+		return err
+	}
+	addr, err := resolveAddr(context.Background(), params.Resolver, host, port)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return &SessionError{Msg: "sendNotification(): unable to reach " + addr.String(), Err: err}
+	}
+	defer conn.Close()
+
+	msg, err := notificationMessage(params.Version, community, n, inform, conn.LocalAddr())
+	if err != nil {
+		return err
+	}
+	packet, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	if Debug {
+		applog.Debugf("sendNotification(): %s to %s, request-id %d", n.TrapOid, addr, msg.requestID)
+	}
+
+	if !inform {
+		if _, err = conn.Write(packet); err != nil {
+			return &SessionError{Msg: "sendNotification(): unable to send to " + addr.String(), Err: err}
+		}
+		return nil
+	}
+
+	response := make([]byte, 65535)
+	for attempt := 0; attempt <= params.Retries; attempt++ {
+		if _, err = conn.Write(packet); err != nil {
+			return &SessionError{Msg: "sendNotification(): unable to send to " + addr.String(), Err: err}
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Duration(params.Timeout) * time.Millisecond))
+		for {
+			length, err := conn.Read(response)
+			if err != nil {
+				break // timed out, or eg icmp port unreachable; retry
+			}
+			ack, err := decodeMessage(response[:length])
+			if err != nil || ack.pduType != pduResponse || ack.requestID != msg.requestID {
+				continue // not our ack
+			}
+			if status := PduError(ack.errorStatus); status != GNET_SNMP_PDU_ERR_NOERROR {
+				return &AgentError{Status: status, Index: ack.errorIndex}
+			}
+			return nil
+		}
+	}
+	return &TimeoutError{Timeout: params.Timeout, Retries: params.Retries}
+}
+
+// trapV1Fields returns the v1 trap enterprise, generic-trap and
+// specific-trap fields for a v2c snmpTrapOID (RFC 3584 3.2).
+func trapV1Fields(trap_oid OID) (enterprise OID, generic, specific int, err error) {
+	if len(trap_oid) < 3 {
+		return nil, 0, 0, fmt.Errorf("%s: trapV1Fields(): invalid trap oid %s", libname(), trap_oid)
+	}
+	last := int(trap_oid[len(trap_oid)-1])
+	if trap_oid.IsChildOf(snmpTrapsOid) && len(trap_oid) == len(snmpTrapsOid)+1 && last >= 1 && last <= 6 {
+		// coldStart(1) .. egpNeighborLoss(6) are generic-trap 0 .. 5
+		return snmpTrapsOid, last - 1, 0, nil
+	}
+
+	// enterpriseSpecific: enterprise.0.specific, or enterprise.specific
+	enterprise = trap_oid.Parent()
+	if enterprise[len(enterprise)-1] == 0 {
+		enterprise = enterprise.Parent()
+	}
+	return enterprise, 6, last, nil
+}
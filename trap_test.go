@@ -0,0 +1,282 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var linkDownVarbinds = []QueryResult{
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.1.3"), Value: VBT_Integer32(3)},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.2.3"), Value: VBT_OctetString("eth2")},
+}
+
+var listenerTests = []struct {
+	msg      *snmpMessage
+	expected Notification
+}{
+	// v1 linkDown
+	{&snmpMessage{
+		version: GNET_SNMP_V1, community: "public", pduType: pduTrapV1,
+		enterprise: MustParseOID("1.3.6.1.4.1.8072.3.2.10"), agentAddress: "10.0.0.1",
+		genericTrap: 2, timestamp: 4381200, varbinds: linkDownVarbinds},
+		Notification{
+			Version: GNET_SNMP_V1, Community: "public",
+			TrapOid: MustParseOID("1.3.6.1.6.3.1.1.5.3"), Uptime: 4381200,
+			Enterprise: MustParseOID("1.3.6.1.4.1.8072.3.2.10"), AgentAddress: "10.0.0.1", GenericTrap: 2,
+			Varbinds: linkDownVarbinds}},
+
+	// v1 enterpriseSpecific
+	{&snmpMessage{
+		version: GNET_SNMP_V1, community: "traps", pduType: pduTrapV1,
+		enterprise: MustParseOID("1.3.6.1.4.1.9"), agentAddress: "10.0.0.2",
+		genericTrap: 6, specificTrap: 17, timestamp: 100},
+		Notification{
+			Version: GNET_SNMP_V1, Community: "traps",
+			TrapOid: MustParseOID("1.3.6.1.4.1.9.0.17"), Uptime: 100,
+			Enterprise: MustParseOID("1.3.6.1.4.1.9"), AgentAddress: "10.0.0.2", GenericTrap: 6, SpecificTrap: 17}},
+
+	// v2c linkDown
+	{&snmpMessage{
+		version: GNET_SNMP_V2C, community: "public", pduType: pduTrapV2, requestID: 42,
+		varbinds: append([]QueryResult{
+			{Oid: sysUpTimeOid, Value: VBT_Timeticks(4381200)},
+			{Oid: snmpTrapOidOid, Value: VBT_ObjectID(".1.3.6.1.6.3.1.1.5.3")}},
+			linkDownVarbinds...)},
+		Notification{
+			Version: GNET_SNMP_V2C, Community: "public",
+			TrapOid: MustParseOID("1.3.6.1.6.3.1.1.5.3"), Uptime: 4381200,
+			Varbinds: linkDownVarbinds}},
+
+	// v2c inform
+	{&snmpMessage{
+		version: GNET_SNMP_V2C, community: "public", pduType: pduInform, requestID: 43,
+		varbinds: append([]QueryResult{
+			{Oid: sysUpTimeOid, Value: VBT_Timeticks(99)},
+			{Oid: snmpTrapOidOid, Value: VBT_ObjectID(".1.3.6.1.6.3.1.1.5.4")}},
+			linkDownVarbinds...)},
+		Notification{
+			Version: GNET_SNMP_V2C, Community: "public", Inform: true,
+			TrapOid: MustParseOID("1.3.6.1.6.3.1.1.5.4"), Uptime: 99,
+			Varbinds: linkDownVarbinds}},
+}
+
+func TestListener(t *testing.T) {
+	l, err := NewListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewListener error: %s", err)
+	}
+	defer l.Close()
+
+	conn, err := net.DialUDP("udp", nil, l.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP error: %s", err)
+	}
+	defer conn.Close()
+
+	// garbage is dropped, and so is an inform without snmpTrapOID.0, which
+	// isn't acknowledged (the ack read below must be for the valid inform)
+	conn.Write([]byte{0x30, 0x03, 0x02, 0x01})
+	malformed, err := encodeMessage(&snmpMessage{
+		version: GNET_SNMP_V2C, community: "public", pduType: pduInform, requestID: 41,
+		varbinds: []QueryResult{{Oid: sysUpTimeOid, Value: VBT_Timeticks(99)}}})
+	if err != nil {
+		t.Fatalf("encodeMessage error: %s", err)
+	}
+	conn.Write(malformed)
+
+	for i, test := range listenerTests {
+		packet, err := encodeMessage(test.msg)
+		if err != nil {
+			t.Fatalf("#%d: encodeMessage error: %s", i, err)
+		}
+		if _, err = conn.Write(packet); err != nil {
+			t.Fatalf("#%d: Write error: %s", i, err)
+		}
+
+		var notification *Notification
+		select {
+		case notification = <-l.C:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("#%d: timed out waiting for notification", i)
+		}
+		if notification.Source.String() != conn.LocalAddr().String() {
+			t.Errorf("#%d: expected Source %s got %s", i, conn.LocalAddr(), notification.Source)
+		}
+		notification.Source = nil
+		if !reflect.DeepEqual(*notification, test.expected) {
+			t.Errorf("#%d: expected %+v got %+v", i, test.expected, *notification)
+		}
+
+		if test.msg.pduType == pduInform {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			ack := make([]byte, 1500)
+			n, err := conn.Read(ack)
+			if err != nil {
+				t.Fatalf("#%d: no inform ack: %s", i, err)
+			}
+			response, err := decodeMessage(ack[:n])
+			if err != nil {
+				t.Fatalf("#%d: decodeMessage(ack) error: %s", i, err)
+			}
+			if response.pduType != pduResponse || response.requestID != test.msg.requestID ||
+				!reflect.DeepEqual(response.varbinds, test.msg.varbinds) {
+				t.Errorf("#%d: bad inform ack %+v", i, response)
+			}
+		}
+	}
+
+	l.Close()
+	if _, ok := <-l.C; ok {
+		t.Errorf("expected C to be closed")
+	}
+}
+
+func TestSendNotification(t *testing.T) {
+	l, err := NewListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewListener error: %s", err)
+	}
+	defer l.Close()
+	uri := "snmp://traps@" + l.Addr().String()
+
+	tests := []struct {
+		version SnmpVersion
+		inform  bool
+	}{
+		{GNET_SNMP_V1, false},
+		{GNET_SNMP_V2C, false},
+		{GNET_SNMP_V2C, true},
+	}
+	for i, test := range tests {
+		params := NewDefaultParams(uri)
+		params.Version = test.version
+		sent := &Notification{
+			TrapOid:  MustParseOID("1.3.6.1.6.3.1.1.5.3"),
+			Uptime:   4381200,
+			Varbinds: linkDownVarbinds,
+		}
+		if test.inform {
+			err = SendInform(params, sent)
+		} else {
+			err = SendTrap(params, sent)
+		}
+		if err != nil {
+			t.Fatalf("#%d: send error: %s", i, err)
+		}
+
+		var received *Notification
+		select {
+		case received = <-l.C:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("#%d: timed out waiting for notification", i)
+		}
+		if received.Version != test.version || received.Inform != test.inform ||
+			received.Community != "traps" || received.TrapOid.Compare(sent.TrapOid) != 0 ||
+			received.Uptime != sent.Uptime || !reflect.DeepEqual(received.Varbinds, sent.Varbinds) {
+			t.Errorf("#%d: sent %+v received %+v", i, sent, received)
+		}
+		if test.version == GNET_SNMP_V1 && (received.GenericTrap != 2 || received.AgentAddress != "127.0.0.1") {
+			t.Errorf("#%d: expected v1 linkDown from 127.0.0.1, got %+v", i, received)
+		}
+	}
+
+	// nothing acknowledges informs sent to a plain udp socket
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP error: %s", err)
+	}
+	defer conn.Close()
+	params := NewDefaultParams("snmp://public@" + conn.LocalAddr().String())
+	params.Timeout, params.Retries = 20, 1
+	err = SendInform(params, &Notification{TrapOid: MustParseOID("1.3.6.1.6.3.1.1.5.1")})
+	if _, ok := err.(*TimeoutError); !ok {
+		t.Errorf("expected TimeoutError for unacknowledged inform, got %v", err)
+	}
+
+	params.Version = GNET_SNMP_V1
+	if err = SendInform(params, &Notification{TrapOid: MustParseOID("1.3.6.1.6.3.1.1.5.1")}); err == nil {
+		t.Errorf("expected error sending a v1 inform")
+	}
+
+	params = NewDefaultParams(uri)
+	params.Transport = TRANSPORT_TCP
+	err = SendTrap(params, &Notification{TrapOid: MustParseOID("1.3.6.1.6.3.1.1.5.1")})
+	if _, ok := err.(*SessionError); !ok {
+		t.Errorf("expected a *SessionError sending a trap over TCP, got %v", err)
+	}
+}
+
+var notificationTargetTests = []struct {
+	uri       string
+	host      string // empty for an error
+	port      int
+	community string
+}{
+	{"snmp://nms.example.com", "nms.example.com", 162, "public"},
+	{"snmp://traps@nms.example.com:1162", "nms.example.com", 1162, "traps"},
+	{"snmp://p%40ss%2Fword@[::1]", "::1", 162, "p@ss/word"},
+	{"snmp://p@ss@127.0.0.1:161/", "127.0.0.1", 161, "p@ss"},
+	{"snmp+tcp://public@nms.example.com", "", 0, ""},
+	{"snmp+unix://public@%2Fvar%2Fsnmp", "", 0, ""},
+	{"http://nms.example.com", "", 0, ""},
+	{"snmp://nms.example.com:99999", "", 0, ""},
+}
+
+func TestNotificationTarget(t *testing.T) {
+	for i, test := range notificationTargetTests {
+		host, port, community, err := notificationTarget(test.uri)
+		if test.host == "" {
+			if _, ok := err.(*UriError); !ok {
+				t.Errorf("#%d: notificationTarget(%s) expected a *UriError, got %v", i, test.uri, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: notificationTarget(%s) error: %s", i, test.uri, err)
+		} else if host != test.host || port != test.port || community != test.community {
+			t.Errorf("#%d: notificationTarget(%s) expected %s %d %s got %s %d %s",
+				i, test.uri, test.host, test.port, test.community, host, port, community)
+		}
+	}
+}
+
+var trapV1FieldsTests = []struct {
+	trap_oid   string
+	enterprise string
+	generic    int
+	specific   int
+}{
+	{"1.3.6.1.6.3.1.1.5.1", "1.3.6.1.6.3.1.1.5", 0, 0},
+	{".1.3.6.1.6.3.1.1.5.6", "1.3.6.1.6.3.1.1.5", 5, 0},
+	{"1.3.6.1.4.1.9.0.17", "1.3.6.1.4.1.9", 6, 17},
+	{"1.3.6.1.4.1.8072.4.1", "1.3.6.1.4.1.8072.4", 6, 1},
+}
+
+func TestTrapV1Fields(t *testing.T) {
+	for i, test := range trapV1FieldsTests {
+		enterprise, generic, specific, err := trapV1Fields(MustParseOID(test.trap_oid))
+		if err != nil || enterprise.String() != test.enterprise || generic != test.generic || specific != test.specific {
+			t.Errorf("#%d: trapV1Fields(%s) expected %s %d %d got %s %d %d (%v)", i, test.trap_oid,
+				test.enterprise, test.generic, test.specific, enterprise, generic, specific, err)
+		}
+	}
+}
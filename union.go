@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 package gsnmpgo
 
 // gsnmpgo is a go/cgo wrapper around gsnmp.
@@ -45,8 +48,7 @@ import "C"
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
-	"strconv"
+	"net"
 	"unsafe"
 )
 
@@ -79,51 +81,23 @@ func union_ui64(cbytes [8]byte) (result uint64) {
 	return 0
 }
 
-// return ui8v field as an ip address
+// return ui8v field as an ip address; 4 bytes, or 16 for the ipv6 addresses
+// some agents send
 func union_ui8v_ipaddress(cbytes [8]byte, value_len _Ctype_gsize) (result string) {
-	if int(value_len) != 4 { // an ip4 address must have 4 bytes
+	if length := int(value_len); length != net.IPv4len && length != net.IPv6len {
 		return
 	}
 	buf := bytes.NewBuffer(cbytes[:])
 	var ptr uint64
 	if err := binary.Read(buf, binary.LittleEndian, &ptr); err == nil { // read bytes as uint64
 		up := (unsafe.Pointer(uintptr(ptr))) // convert the uint64 into a pointer
-		gobytes := C.GoBytes(up, 4)
-		for i := 0; i < 4; i++ {
-			result += fmt.Sprintf(".%d", gobytes[i])
-		}
-		return result[1:] // strip leading dot
+		return net.IP(C.GoBytes(up, C.int(value_len))).String()
 	}
 	return
 }
 
-// return ui8v field as a string
-func union_ui8v_string(cbytes [8]byte, value_len _Ctype_gsize) (result string) {
-	var ptr uint64
-	var err error
-	buf := bytes.NewBuffer(cbytes[:])
-	if err = binary.Read(buf, binary.LittleEndian, &ptr); err != nil { // read bytes as uint64
-		return
-	}
-	up := (unsafe.Pointer(uintptr(ptr))) // convert the uint64 into a pointer
-
-	length := (_Ctype_int)(value_len)
-	gobytes := C.GoBytes(up, length)
-	for i := 0; i < int(length); i++ {
-		if !strconv.IsPrint(rune(gobytes[i])) {
-			// can't pass gobytes & length to union_ui8v_hexstring() -
-			// it's also used for TYPE_OPAQUE
-			return union_ui8v_hexstring(cbytes, value_len)
-		}
-	}
-
-	return string(gobytes)
-}
-
-// the ui8v field contains unprintable characters - convert to "hex string"
-//
-// eg 00 25 89 27 56 1B
-func union_ui8v_hexstring(cbytes [8]byte, value_len _Ctype_gsize) (result string) {
+// return ui8v field as bytes, eg an octet string or opaque
+func union_ui8v_bytes(cbytes [8]byte, value_len _Ctype_gsize) (result []byte) {
 	var ptr uint64
 	var err error
 	buf := bytes.NewBuffer(cbytes[:])
@@ -133,11 +107,7 @@ func union_ui8v_hexstring(cbytes [8]byte, value_len _Ctype_gsize) (result string
 	up := (unsafe.Pointer(uintptr(ptr))) // convert the uint64 into a pointer
 
 	length := (_Ctype_int)(value_len)
-	gobytes := C.GoBytes(up, length)
-	for i := 0; i < int(length); i++ {
-		result += fmt.Sprintf(" %02X", gobytes[i])
-	}
-	return result[1:] // strip leading space
+	return C.GoBytes(up, length)
 }
 
 // return ui32v field
@@ -0,0 +1,379 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// snmpUri is an snmp uri (RFC 4088) parsed in Go. It is used to check uris
+// before they are given to gsnmp, and by the pure Go backend.
+type snmpUri struct {
+	community string
+	host      string
+	port      int
+	context   string
+	engineID  string // the context engine id, after the ';' of the context
+	oids      [][]uint32
+	uritype   UriType
+	transport Transport // from the scheme, eg snmp+tcp://
+}
+
+// uriSchemes are the schemes of snmp uris, and the transports they select.
+// Only snmp:// is in RFC 4088; the others follow the convention of eg
+// http+unix://.
+var uriSchemes = []struct {
+	scheme    string
+	transport Transport
+}{
+	{"snmp://", TRANSPORT_UDP},
+	{"snmp+tcp://", TRANSPORT_TCP},
+	{"snmp+unix://", TRANSPORT_UNIX},
+}
+
+// parseSnmpUri parses an snmp uri, eg
+//
+//    snmp://public@192.168.1.10:161/ctx;engine/(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.2.0)
+//
+// The community defaults to "public" and the port to 161; an ipv6 host must
+// be in brackets, eg [::1]. The community and context may be percent-encoded.
+// As with gsnmp, an oid list ending in '+' is a GETNEXT, one ending in '*' (or
+// ".*") is a walk, and anything else is a GET. A uri without a path has no
+// oids (eg for Set()).
+//
+// The scheme snmp+tcp:// selects TCP, and snmp+unix:// a Unix-domain socket
+// whose percent-encoded path is the host, eg
+// snmp+unix://public@%2Fvar%2Fagentx%2Fmaster//1.3.6.1.2.1.1.1.0
+//
+// Errors are a *UriError, with Pos the byte offset of the problem.
+func parseSnmpUri(uri string) (parsed *snmpUri, err error) {
+	return parseSnmpUriPort(uri, 161)
+}
+
+// parseSnmpUriPort is parseSnmpUri with port as the default port, eg 162
+// for a notification target.
+func parseSnmpUriPort(uri string, port int) (parsed *snmpUri, err error) {
+	parsed = &snmpUri{community: "public", port: port, uritype: GNET_SNMP_URI_GET}
+	pos := -1
+	for _, scheme := range uriSchemes {
+		if len(uri) >= len(scheme.scheme) && strings.EqualFold(uri[:len(scheme.scheme)], scheme.scheme) {
+			parsed.transport, pos = scheme.transport, len(scheme.scheme)
+			break
+		}
+	}
+	if pos < 0 {
+		return nil, &UriError{Uri: uri, Pos: 0, Msg: "uri must start with snmp://, snmp+tcp:// or snmp+unix://"}
+	}
+
+	// authority: [community@]host[:port]
+	end := uriIndex(uri, pos, '/')
+	if i := strings.LastIndex(uri[pos:end], "@"); i >= 0 {
+		// an unescaped '@' in the community is allowed, as gsnmp allows it
+		if parsed.community, err = uriUnescape(uri, pos, pos+i, "@:"); err != nil {
+			return nil, err
+		}
+		pos += i + 1
+	}
+	if parsed.transport == TRANSPORT_UNIX {
+		if parsed.host, err = uriUnescape(uri, pos, end, ""); err != nil {
+			return nil, err
+		}
+		if parsed.host == "" {
+			return nil, &UriError{Uri: uri, Pos: pos, Msg: "no socket path"}
+		}
+	} else if err = parsed.parseHost(uri, pos, end); err != nil {
+		return nil, err
+	}
+
+	// path: /[context[;engineid]][/oids]
+	if end >= len(uri)-1 {
+		return parsed, nil
+	}
+	pos = end + 1
+	end = uriIndex(uri, pos, '/')
+	context_end := uriIndex(uri[:end], pos, ';')
+	if parsed.context, err = uriUnescape(uri, pos, context_end, ":@"); err != nil {
+		return nil, err
+	}
+	if context_end < end {
+		if context_end+1 == end {
+			return nil, &UriError{Uri: uri, Pos: end, Msg: "empty context engine id"}
+		}
+		if parsed.engineID, err = uriUnescape(uri, context_end+1, end, ":@"); err != nil {
+			return nil, err
+		}
+	}
+	if end >= len(uri)-1 {
+		return parsed, nil
+	}
+	if err = parsed.parseOids(uri, end+1); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// String formats u as a uri that parseSnmpUri parses back to u. The
+// community and context are percent-encoded where necessary.
+func (u *snmpUri) String() string {
+	uri := uriBase(u.transport, u.community, u.host, u.port)
+	if u.context == "" && u.engineID == "" && len(u.oids) == 0 {
+		return uri
+	}
+	uri += "/" + uriEscape(u.context)
+	if u.engineID != "" {
+		uri += ";" + uriEscape(u.engineID)
+	}
+	if len(u.oids) == 0 {
+		return uri
+	}
+	oids := make([]string, len(u.oids))
+	for i, oid := range u.oids {
+		oids[i] = oidString(oid)
+	}
+	list := strings.Join(oids, ",")
+	if len(oids) > 1 {
+		list = "(" + list + ")"
+	}
+	switch u.uritype {
+	case GNET_SNMP_URI_NEXT:
+		list += "+"
+	case GNET_SNMP_URI_WALK:
+		list += ".*"
+	}
+	return uri + "/" + list
+}
+
+// validateUri parses uri, and checks it has no more than max oids. It is
+// used before a uri is given to gsnmp, whose parser doesn't say what is
+// wrong with a uri.
+func validateUri(uri string, max int) (parsed *snmpUri, err error) {
+	if parsed, err = parseSnmpUri(uri); err != nil {
+		return nil, err
+	}
+	if len(parsed.oids) > max {
+		return nil, &UriError{Uri: uri, Pos: -1,
+			Msg: fmt.Sprintf("number of uris is greater than max (%d/%d), use GetMany()", len(parsed.oids), max)}
+	}
+	return parsed, nil
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// isUriSubDelim returns true if c is a sub-delim in a uri (RFC 3986 2.2).
+func isUriSubDelim(c byte) bool {
+	return strings.IndexByte("!$&'()*+,;=", c) >= 0
+}
+
+// isUriUnreserved returns true if c is unreserved in a uri (RFC 3986 2.3).
+func isUriUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// parseHost parses the host[:port] in uri[pos:end] into u.
+func (u *snmpUri) parseHost(uri string, pos, end int) error {
+	host, port_pos := uri[pos:end], end
+	if strings.HasPrefix(host, "[") {
+		// ipv6 literal, eg [::1]:161
+		close := strings.Index(host, "]")
+		if close < 0 {
+			return &UriError{Uri: uri, Pos: pos, Msg: "unterminated ipv6 address"}
+		}
+		if ip := net.ParseIP(host[1:close]); ip == nil || !strings.Contains(host[1:close], ":") {
+			return &UriError{Uri: uri, Pos: pos + 1, Msg: "invalid ipv6 address"}
+		}
+		u.host, port_pos = host[1:close], pos+close+1
+		if port_pos < end && uri[port_pos] != ':' {
+			return &UriError{Uri: uri, Pos: port_pos, Msg: "expected ':' after ipv6 address"}
+		}
+	} else {
+		if i := strings.Index(host, ":"); i >= 0 {
+			if strings.Count(host, ":") > 1 {
+				return &UriError{Uri: uri, Pos: pos, Msg: "ipv6 address must be in brackets"}
+			}
+			host, port_pos = host[:i], pos+i
+		}
+		if host == "" {
+			return &UriError{Uri: uri, Pos: pos, Msg: "no host"}
+		}
+		for i := 0; i < len(host); i++ {
+			if c := host[i]; !isUriUnreserved(c) {
+				return &UriError{Uri: uri, Pos: pos + i, Msg: fmt.Sprintf("invalid character %q in host", c)}
+			}
+		}
+		u.host = host
+	}
+
+	// an empty port is the default (RFC 3986 3.2.3)
+	if port_pos+1 < end {
+		n, err := strconv.Atoi(uri[port_pos+1 : end])
+		if err != nil || n < 1 || n > 65535 || uri[port_pos+1] == '+' || uri[port_pos+1] == '-' {
+			return &UriError{Uri: uri, Pos: port_pos + 1, Msg: "invalid port"}
+		}
+		u.port = n
+	}
+	return nil
+}
+
+// parseOids parses the oids from pos to the end of uri into u, eg
+// (1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.3.0) or 1.3.6.1.2.1.2.2.*
+func (u *snmpUri) parseOids(uri string, pos int) error {
+	end := len(uri)
+	switch uri[end-1] {
+	case '+':
+		u.uritype = GNET_SNMP_URI_NEXT
+		end--
+	case '*':
+		u.uritype = GNET_SNMP_URI_WALK
+		end--
+		if end > pos && uri[end-1] == '.' {
+			end--
+		}
+	}
+	if end == pos {
+		return &UriError{Uri: uri, Pos: pos, Msg: "no oids"}
+	}
+	if uri[pos] == '(' {
+		close := uriIndex(uri[:end], pos, ')')
+		if close == end {
+			return &UriError{Uri: uri, Pos: end, Msg: "expected ')'"}
+		}
+		if close != end-1 {
+			return &UriError{Uri: uri, Pos: close + 1, Msg: "unexpected character after ')'"}
+		}
+		pos, end = pos+1, end-1
+	}
+	for {
+		oid_end := uriIndex(uri[:end], pos, ',')
+		oid, err := parseUriOid(uri, pos, oid_end)
+		if err != nil {
+			return err
+		}
+		u.oids = append(u.oids, oid)
+		if oid_end == end {
+			return nil
+		}
+		pos = oid_end + 1
+	}
+}
+
+// parseUriOid parses the numeric oid in uri[pos:end], eg .1.3.6.1.2.1.1.1.0
+func parseUriOid(uri string, pos, end int) (oid []uint32, err error) {
+	if pos < end && uri[pos] == '.' {
+		pos++
+	}
+	if pos == end {
+		return nil, &UriError{Uri: uri, Pos: pos, Msg: "empty oid"}
+	}
+	for {
+		sub_end := uriIndex(uri[:end], pos, '.')
+		if sub_end == pos {
+			return nil, &UriError{Uri: uri, Pos: pos, Msg: "empty sub-identifier in oid"}
+		}
+		for i := pos; i < sub_end; i++ {
+			if c := uri[i]; c < '0' || c > '9' {
+				return nil, &UriError{Uri: uri, Pos: i, Msg: fmt.Sprintf("invalid character %q in oid", c)}
+			}
+		}
+		n, err := strconv.ParseUint(uri[pos:sub_end], 10, 32)
+		if err != nil {
+			return nil, &UriError{Uri: uri, Pos: pos, Msg: "sub-identifier out of range in oid"}
+		}
+		if len(oid) == MAX_OID_LEN {
+			return nil, &UriError{Uri: uri, Pos: pos, Msg: fmt.Sprintf("oid has more than %d sub-identifiers", MAX_OID_LEN)}
+		}
+		oid = append(oid, uint32(n))
+		if sub_end == end {
+			return oid, nil
+		}
+		pos = sub_end + 1
+	}
+}
+
+// uriBase returns the scheme and authority of a uri, eg
+// snmp+tcp://community@host:port; the port is left out if it is the
+// default. For TRANSPORT_UNIX host is the path of the socket.
+func uriBase(transport Transport, community, host string, port int) string {
+	scheme := uriSchemes[0].scheme
+	for _, s := range uriSchemes {
+		if s.transport == transport {
+			scheme = s.scheme
+		}
+	}
+	if transport == TRANSPORT_UNIX {
+		return scheme + uriEscape(community) + "@" + uriEscape(host)
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // ipv6 literal
+	}
+	if port != 0 && port != 161 {
+		host += ":" + strconv.Itoa(port)
+	}
+	return scheme + uriEscape(community) + "@" + host
+}
+
+// uriEscape percent-encodes the characters of s that aren't unreserved in
+// a uri (RFC 3986 2.3).
+func uriEscape(s string) string {
+	var escaped []byte
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; isUriUnreserved(c) {
+			escaped = append(escaped, c)
+		} else {
+			escaped = append(escaped, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+	return string(escaped)
+}
+
+// uriIndex returns the index in s of the first c at or after pos, or len(s).
+func uriIndex(s string, pos int, c byte) int {
+	if i := strings.IndexByte(s[pos:], c); i >= 0 {
+		return pos + i
+	}
+	return len(s)
+}
+
+// uriUnescape decodes the percent-encoded uri[pos:end], which may contain
+// unreserved characters, sub-delims and the characters in extra.
+func uriUnescape(uri string, pos, end int, extra string) (string, error) {
+	var unescaped []byte
+	for i := pos; i < end; i++ {
+		c := uri[i]
+		switch {
+		case c == '%':
+			if i+2 >= end {
+				return "", &UriError{Uri: uri, Pos: i, Msg: "invalid percent-encoding"}
+			}
+			n, err := strconv.ParseUint(uri[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", &UriError{Uri: uri, Pos: i, Msg: "invalid percent-encoding"}
+			}
+			unescaped = append(unescaped, byte(n))
+			i += 2
+		case isUriUnreserved(c) || isUriSubDelim(c) || strings.IndexByte(extra, c) >= 0:
+			unescaped = append(unescaped, c)
+		default:
+			return "", &UriError{Uri: uri, Pos: i, Msg: fmt.Sprintf("invalid character %q", c)}
+		}
+	}
+	return string(unescaped), nil
+}
@@ -0,0 +1,186 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"reflect"
+	"testing"
+)
+
+var parseSnmpUriTests = []struct {
+	uri      string
+	expected *snmpUri
+}{
+	{"snmp://192.168.1.10",
+		&snmpUri{community: "public", host: "192.168.1.10", port: 161, uritype: GNET_SNMP_URI_GET}},
+	{"snmp://private@192.168.1.10:1161/",
+		&snmpUri{community: "private", host: "192.168.1.10", port: 1161, uritype: GNET_SNMP_URI_GET}},
+	{"snmp://public@127.0.0.1:161//(1.3.6.1.2.1.1.1.0,.1.3.6.1.2.1.1.3.0)",
+		&snmpUri{community: "public", host: "127.0.0.1", port: 161, uritype: GNET_SNMP_URI_GET,
+			oids: [][]uint32{{1, 3, 6, 1, 2, 1, 1, 1, 0}, {1, 3, 6, 1, 2, 1, 1, 3, 0}}}},
+	{"snmp://public@host.example.com//1.3.6.1.2.1.1.1+",
+		&snmpUri{community: "public", host: "host.example.com", port: 161, uritype: GNET_SNMP_URI_NEXT,
+			oids: [][]uint32{{1, 3, 6, 1, 2, 1, 1, 1}}}},
+	{"snmp://public@[::1]:1161/ctx;engine/(1.3.6.1.2.1.2.2.1.2,1.3.6.1.2.1.2.2.1.3).*",
+		&snmpUri{community: "public", host: "::1", port: 1161, context: "ctx", engineID: "engine", uritype: GNET_SNMP_URI_WALK,
+			oids: [][]uint32{{1, 3, 6, 1, 2, 1, 2, 2, 1, 2}, {1, 3, 6, 1, 2, 1, 2, 2, 1, 3}}}},
+	{"snmp://a@b@10.0.0.1//1.3.6.1.2.1.1*",
+		&snmpUri{community: "a@b", host: "10.0.0.1", port: 161, uritype: GNET_SNMP_URI_WALK,
+			oids: [][]uint32{{1, 3, 6, 1, 2, 1, 1}}}},
+	{"snmp://a%2Fb%40c@10.0.0.1//1.3.6.1.2.1.1.1.0",
+		&snmpUri{community: "a/b@c", host: "10.0.0.1", port: 161, uritype: GNET_SNMP_URI_GET,
+			oids: [][]uint32{{1, 3, 6, 1, 2, 1, 1, 1, 0}}}},
+	{"snmp+tcp://public@[::1]:1161//1.3.6.1.2.1.1*",
+		&snmpUri{community: "public", host: "::1", port: 1161, uritype: GNET_SNMP_URI_WALK, transport: TRANSPORT_TCP,
+			oids: [][]uint32{{1, 3, 6, 1, 2, 1, 1}}}},
+	{"SNMP+TCP://host.example.com",
+		&snmpUri{community: "public", host: "host.example.com", port: 161, uritype: GNET_SNMP_URI_GET, transport: TRANSPORT_TCP}},
+	{"snmp+unix://public@%2Fvar%2Fagentx%2Fmaster//1.3.6.1.2.1.1.1.0",
+		&snmpUri{community: "public", host: "/var/agentx/master", port: 161, uritype: GNET_SNMP_URI_GET, transport: TRANSPORT_UNIX,
+			oids: [][]uint32{{1, 3, 6, 1, 2, 1, 1, 1, 0}}}},
+	{"snmp+unix://agent.sock",
+		&snmpUri{community: "public", host: "agent.sock", port: 161, uritype: GNET_SNMP_URI_GET, transport: TRANSPORT_UNIX}},
+}
+
+var parseSnmpUriErrorTests = []struct {
+	uri string
+	pos int
+}{
+	{"http://192.168.1.10", 0},
+	{"snmp://public@", 14},
+	{"snmp://public@host:0", 19},
+	{"snmp://public@host:x//1.3", 19},
+	{"snmp://[::1//1.3", 7},
+	{"snmp://host//(1.3.6,1.3.x)", 24},
+	{"snmp://host//(1.3.6", 19},
+	{"snmp://host//(1.3.6,,1.3.7)", 20},
+	{"snmp://host//(1.3..6)", 18},
+	{"snmp://host//()", 14},
+	{"snmp://host//(1.3.6)x", 20},
+	{"snmp://host//(1.3.6)x+", 20},
+	{"snmp://host//1.3.6)", 18},
+	{"snmp://host//+", 13},
+	{"snmp://host//1.3.4294967296", 17},
+	{"snmp://host//1.3.-1", 17},
+	{"snmp://pub lic@host//1.3", 10},
+	{"snmp://pub%4@host//1.3", 10},
+	{"snmp://pub%zz@host//1.3", 10},
+	{"snmp://ho st//1.3", 9},
+	{"snmp://::1//1.3", 7},
+	{"snmp://[::1]x//1.3", 12},
+	{"snmp://[1.2.3.4]//1.3", 8},
+	{"snmp://[fe80::zz]//1.3", 8},
+	{"snmp://host:+161//1.3", 12},
+	{"snmp://host:65536//1.3", 12},
+	{"snmp://host/ctx;/1.3", 16},
+	{"snmp://host/c<t>x/1.3", 13},
+	{"snmp+udp://host//1.3", 0},
+	{"snmp+tcp://host:x//1.3", 16},
+	{"snmp+unix://public@//1.3", 19},
+	{"snmp+unix://%2Fvar%2//1.3", 18},
+}
+
+var snmpUriStringTests = []struct {
+	uri      string
+	expected string // if different from uri
+}{
+	{"snmp://public@192.168.1.10", ""},
+	{"snmp://192.168.1.10:161/", "snmp://public@192.168.1.10"},
+	{"snmp://public@192.168.1.10//1.3.6.1.2.1.1.1.0", ""},
+	{"snmp://public@192.168.1.10//(.1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.3.0)",
+		"snmp://public@192.168.1.10//(1.3.6.1.2.1.1.1.0,1.3.6.1.2.1.1.3.0)"},
+	{"snmp://public@192.168.1.10//1.3.6.1.2.1+", ""},
+	{"snmp://public@192.168.1.10//1.3.6.1.2.1*", "snmp://public@192.168.1.10//1.3.6.1.2.1.*"},
+	{"snmp://a@b@[::1]:1161/vlan1;8000/(1.3.6.1.2.1.2.2.1.2,1.3.6.1.2.1.2.2.1.3).*",
+		"snmp://a%40b@[::1]:1161/vlan1;8000/(1.3.6.1.2.1.2.2.1.2,1.3.6.1.2.1.2.2.1.3).*"},
+	{"snmp://p%40ss%2Fw%20rd@host.example.com:1161/my%20ctx", ""},
+	{"snmp://public@host:/ctx", "snmp://public@host/ctx"},
+	{"snmp+tcp://public@[::1]:1161//1.3.6.1.2.1.1.1.0", ""},
+	{"snmp+unix://public@%2Ftmp%2Fagent%20one.sock//1.3.6.1.2.1.1.1.0", ""},
+	{"snmp+unix://%2Ftmp%2Fagent.sock", "snmp+unix://public@%2Ftmp%2Fagent.sock"},
+}
+
+func TestParseSnmpUri(t *testing.T) {
+	for i, test := range parseSnmpUriTests {
+		parsed, err := parseSnmpUri(test.uri)
+		if err != nil {
+			t.Errorf("#%d: parseSnmpUri(%s) error: %s", i, test.uri, err)
+		} else if !reflect.DeepEqual(parsed, test.expected) {
+			t.Errorf("#%d: parseSnmpUri(%s) expected %+v got %+v", i, test.uri, test.expected, parsed)
+		}
+	}
+	for i, test := range parseSnmpUriErrorTests {
+		_, err := parseSnmpUri(test.uri)
+		if uri_err, ok := err.(*UriError); !ok || uri_err.Pos != test.pos {
+			t.Errorf("#%d: parseSnmpUri(%s) expected UriError at %d got %v", i, test.uri, test.pos, err)
+		}
+	}
+}
+
+func TestSnmpUriString(t *testing.T) {
+	for i, test := range snmpUriStringTests {
+		expected := test.expected
+		if expected == "" {
+			expected = test.uri
+		}
+		parsed, err := parseSnmpUri(test.uri)
+		if err != nil {
+			t.Errorf("#%d: parseSnmpUri(%s) error: %s", i, test.uri, err)
+			continue
+		}
+		if uri := parsed.String(); uri != expected {
+			t.Errorf("#%d: String() of %s expected %s got %s", i, test.uri, expected, uri)
+		}
+
+		// and back again
+		reparsed, err := parseSnmpUri(parsed.String())
+		if err != nil || !reflect.DeepEqual(reparsed, parsed) {
+			t.Errorf("#%d: parseSnmpUri(%s) expected %+v got %+v (%v)", i, parsed, parsed, reparsed, err)
+		}
+	}
+}
+
+var validateUriTests = []struct {
+	uri string
+	max int
+	ok  bool
+}{
+	{"", 3, false},
+	{"snmp://host//((", 3, false},
+	{"snmp://host//))", 3, false},
+	{"snmp://host//())", 3, false},
+	{"snmp://host//(,,)", 3, false},
+	{"snmp://host//(,,,,)", 3, false},
+	{"snmp://host", 3, true},                       // zero
+	{"snmp://host//(1.2,1.3)", 3, true},            // less than
+	{"snmp://host//(1.2,1.3,1.4)", 3, true},        // equal
+	{"snmp://host//(1.2,1.3,1.4,1.5)", 3, false},   // greater than
+	{"snmp://host//(1.2,1.3,1.4,1.5).*", 3, false}, // greater than
+}
+
+func TestValidateUri(t *testing.T) {
+	for i, test := range validateUriTests {
+		_, err := validateUri(test.uri, test.max)
+		if (err == nil) != test.ok {
+			t.Errorf("#%d: expected %t got |%v| max:%d uri:%s", i, test.ok, err, test.max, test.uri)
+		}
+		if _, ok := err.(*UriError); err != nil && !ok {
+			t.Errorf("#%d: expected a UriError, got %T", i, err)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// usm.go contains the SNMP v3 User-based Security Model (USM) parameters,
+// key generation from passwords, and the authentication and encryption of
+// messages (RFC 3414, RFC 3826). github.com/natefinch/gocog is used to
+// generate the boilerplate for the protocols. AFTER EDITING any gocog
+// sections (between gocog open and close square brackets), you MUST run:
+//
+//     rm -f usm.go_cog; $GOPATH/bin/gocog usm.go; go fmt ./...
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/rand"
+	"sync/atomic"
+)
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"USM_AUTH_NONE", "USM_AUTH_MD5", "USM_AUTH_SHA"}
+	enumconv.WriteGo("AuthProtocol", "AuthProtocol", vals, 0)
+}
+gocog]]]*/
+
+// type and values for AuthProtocol
+type AuthProtocol int
+
+const (
+	USM_AUTH_NONE AuthProtocol = iota
+	USM_AUTH_MD5
+	USM_AUTH_SHA
+)
+
+// Stringer for AuthProtocol
+func (authprotocol AuthProtocol) String() string {
+	switch authprotocol {
+	case USM_AUTH_NONE:
+		return "USM_AUTH_NONE"
+	case USM_AUTH_MD5:
+		return "USM_AUTH_MD5"
+	case USM_AUTH_SHA:
+		return "USM_AUTH_SHA"
+	}
+	return "UNKNOWN AuthProtocol"
+}
+
+//[[[end]]]
+
+/*[[[gocog
+package main
+import ("github.com/soniah/gsnmpgo/enumconv")
+func main() {
+	vals := []string{"USM_PRIV_NONE", "USM_PRIV_DES", "USM_PRIV_AES"}
+	enumconv.WriteGo("PrivProtocol", "PrivProtocol", vals, 0)
+}
+gocog]]]*/
+
+// type and values for PrivProtocol
+type PrivProtocol int
+
+const (
+	USM_PRIV_NONE PrivProtocol = iota
+	USM_PRIV_DES
+	USM_PRIV_AES
+)
+
+// Stringer for PrivProtocol
+func (privprotocol PrivProtocol) String() string {
+	switch privprotocol {
+	case USM_PRIV_NONE:
+		return "USM_PRIV_NONE"
+	case USM_PRIV_DES:
+		return "USM_PRIV_DES"
+	case USM_PRIV_AES:
+		return "USM_PRIV_AES"
+	}
+	return "UNKNOWN PrivProtocol"
+}
+
+//[[[end]]]
+
+// the usmStats counters (RFC 3414 5), reported to v3 requests that fail
+const (
+	usmStatsUnsupportedSecLevels uint32 = iota + 1
+	usmStatsNotInTimeWindows
+	usmStatsUnknownUserNames
+	usmStatsUnknownEngineIDs
+	usmStatsWrongDigests
+	usmStatsDecryptionErrors
+)
+
+var usmStatsOid = MustParseOID("1.3.6.1.6.3.15.1.1")
+
+var usmStatsNames = []string{"", "usmStatsUnsupportedSecLevels", "usmStatsNotInTimeWindows",
+	"usmStatsUnknownUserNames", "usmStatsUnknownEngineIDs", "usmStatsWrongDigests", "usmStatsDecryptionErrors"}
+
+// usmTimeWindow is the number of seconds an authenticated message's
+// engine time may differ from the authoritative engine's, RFC 3414 3.2.7.
+const usmTimeWindow = 150
+
+// usmDigestLength is the length of the HMAC-MD5-96 and HMAC-SHA-96 digests
+// in msgAuthenticationParameters.
+const usmDigestLength = 12
+
+// usmKeys are a user's keys, localized to an authoritative engine ID (RFC
+// 3414 2.6). They authenticate and encrypt v3 messages.
+type usmKeys struct {
+	auth    AuthProtocol
+	authKey []byte
+	priv    PrivProtocol
+	privKey []byte
+	salt    uint64 // incremented for each message encrypted, RFC 3414 8.1.1.1
+}
+
+// UsmParams are the SNMP v3 credentials used when QueryParams.Version is
+// GNET_SNMP_V3.
+//
+// SecLevel is one of:
+//
+// GNET_SNMP_SECLEVEL_NANP - noAuthNoPriv, only UserName is used
+//
+// GNET_SNMP_SECLEVEL_ANP - authNoPriv, AuthProtocol and AuthPassword are required
+//
+// GNET_SNMP_SECLEVEL_AP - authPriv, the Priv fields are also required
+type UsmParams struct {
+	UserName     string
+	SecLevel     SecLevel
+	AuthProtocol AuthProtocol
+	AuthPassword string
+	PrivProtocol PrivProtocol
+	PrivPassword string
+	// ContextName and ContextEngineID select the context (eg a vlan or a
+	// proxied device); leave them empty for the default context.
+	// ContextEngineID is raw bytes, not hex.
+	ContextName     string
+	ContextEngineID string
+}
+
+// validate checks UsmParams are consistent with their security level.
+func (usm *UsmParams) validate() error {
+	if usm == nil {
+		return fmt.Errorf("%s: v3 query without UsmParams", libname())
+	}
+	if usm.UserName == "" {
+		return fmt.Errorf("%s: v3 query without a UserName", libname())
+	}
+	switch usm.SecLevel {
+	case GNET_SNMP_SECLEVEL_NANP:
+		return nil
+	case GNET_SNMP_SECLEVEL_ANP, GNET_SNMP_SECLEVEL_AP:
+	default:
+		return fmt.Errorf("%s: unknown v3 security level %d", libname(), usm.SecLevel)
+	}
+
+	if usm.AuthProtocol != USM_AUTH_MD5 && usm.AuthProtocol != USM_AUTH_SHA {
+		return fmt.Errorf("%s: %s requires an auth protocol, got %s",
+			libname(), usm.SecLevel, usm.AuthProtocol)
+	}
+	// RFC 3414 11.2: passwords must be at least 8 characters
+	if len(usm.AuthPassword) < 8 {
+		return fmt.Errorf("%s: auth password must be at least 8 characters", libname())
+	}
+	if usm.SecLevel == GNET_SNMP_SECLEVEL_ANP {
+		return nil
+	}
+
+	if usm.PrivProtocol != USM_PRIV_DES && usm.PrivProtocol != USM_PRIV_AES {
+		return fmt.Errorf("%s: %s requires a priv protocol, got %s",
+			libname(), usm.SecLevel, usm.PrivProtocol)
+	}
+	if len(usm.PrivPassword) < 8 {
+		return fmt.Errorf("%s: priv password must be at least 8 characters", libname())
+	}
+	return nil
+}
+
+// newUsmKeys returns the keys of usm, localized to engine_id. usm must have
+// been validated.
+func newUsmKeys(usm *UsmParams, engine_id string) *usmKeys {
+	keys := &usmKeys{salt: uint64(rand.Int63())}
+	if usm.SecLevel == GNET_SNMP_SECLEVEL_NANP {
+		return keys
+	}
+	keys.auth = usm.AuthProtocol
+	keys.authKey = localizeKey(usm.AuthProtocol, passwordToKey(usm.AuthProtocol, usm.AuthPassword), engine_id)
+	if usm.SecLevel == GNET_SNMP_SECLEVEL_AP {
+		keys.priv = usm.PrivProtocol
+		keys.privKey = localizeKey(usm.AuthProtocol, passwordToKey(usm.AuthProtocol, usm.PrivPassword), engine_id)
+	}
+	return keys
+}
+
+// openMessage checks the digest of a v3 message msg as decoded by
+// decodeMessage, and decrypts and decodes its scopedPDU, as its flags ask.
+// keys may be nil if msg is neither authenticated nor encrypted.
+func openMessage(msg *snmpMessage, keys *usmKeys) error {
+	if msg.flags&v3Auth == 0 {
+		return nil
+	}
+	if keys == nil || keys.auth == USM_AUTH_NONE {
+		return fmt.Errorf("%s: openMessage(): authenticated message for an unauthenticated user", libname())
+	}
+	if !keys.verify(msg.packet, msg.digestAt) {
+		return fmt.Errorf("%s: openMessage(): wrong digest", libname())
+	}
+	if msg.flags&v3Priv == 0 {
+		return nil
+	}
+	if keys.priv == USM_PRIV_NONE {
+		return fmt.Errorf("%s: openMessage(): encrypted message for a user without privacy", libname())
+	}
+	scoped, err := keys.decrypt(msg.encrypted, msg.salt, msg.engineBoots, msg.engineTime)
+	if err != nil {
+		return err
+	}
+	return msg.decodeScopedPdu(scoped)
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// authHash returns the hash function of proto, or nil.
+func authHash(proto AuthProtocol) func() hash.Hash {
+	switch proto {
+	case USM_AUTH_MD5:
+		return md5.New
+	case USM_AUTH_SHA:
+		return sha1.New
+	}
+	return nil
+}
+
+// decrypt decrypts a scopedPDU, with the salt and the authoritative
+// engine's boots and time from the message's security parameters. For DES
+// the result may have padding after the scopedPDU.
+func (k *usmKeys) decrypt(encrypted, salt []byte, boots, time int) ([]byte, error) {
+	if len(salt) != 8 {
+		return nil, fmt.Errorf("%s: decrypt(): msgPrivacyParameters has %d bytes", libname(), len(salt))
+	}
+	block, iv, err := k.privCipher(salt, boots, time)
+	if err != nil {
+		return nil, err
+	}
+	scoped := make([]byte, len(encrypted))
+	switch k.priv {
+	case USM_PRIV_DES:
+		if len(encrypted)%des.BlockSize != 0 {
+			return nil, fmt.Errorf("%s: decrypt(): encryptedPDU isn't a multiple of the DES block size", libname())
+		}
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(scoped, encrypted)
+	case USM_PRIV_AES:
+		cipher.NewCFBDecrypter(block, iv).XORKeyStream(scoped, encrypted)
+	}
+	return scoped, nil
+}
+
+// digest returns the HMAC-MD5-96 or HMAC-SHA-96 digest of packet (RFC 3414
+// 6.3 and 7.3).
+func (k *usmKeys) digest(packet []byte) []byte {
+	mac := hmac.New(authHash(k.auth), k.authKey)
+	mac.Write(packet)
+	return mac.Sum(nil)[:usmDigestLength]
+}
+
+// encrypt encrypts scoped with DES-CBC (RFC 3414 8.1.1) or AES-128-CFB (RFC
+// 3826 3.1.1), returning it and the salt to send as msgPrivacyParameters.
+func (k *usmKeys) encrypt(scoped []byte, boots, time int) (encrypted, salt []byte, err error) {
+	salt = make([]byte, 8)
+	n := atomic.AddUint64(&k.salt, 1)
+	if k.priv == USM_PRIV_DES {
+		binary.BigEndian.PutUint32(salt, uint32(boots))
+		binary.BigEndian.PutUint32(salt[4:], uint32(n))
+	} else {
+		binary.BigEndian.PutUint64(salt, n)
+	}
+	block, iv, err := k.privCipher(salt, boots, time)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch k.priv {
+	case USM_PRIV_DES:
+		padded := make([]byte, (len(scoped)+des.BlockSize-1)/des.BlockSize*des.BlockSize)
+		copy(padded, scoped)
+		encrypted = make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+	case USM_PRIV_AES:
+		encrypted = make([]byte, len(scoped))
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(encrypted, scoped)
+	}
+	return encrypted, salt, nil
+}
+
+// flags returns the msgFlags for the security level of the keys.
+func (k *usmKeys) flags() (flags byte) {
+	if k.auth != USM_AUTH_NONE {
+		flags |= v3Auth
+	}
+	if k.priv != USM_PRIV_NONE {
+		flags |= v3Priv
+	}
+	return flags
+}
+
+// localizeKey localizes key to engine_id, RFC 3414 2.6.
+func localizeKey(proto AuthProtocol, key []byte, engine_id string) []byte {
+	if len(key) == 0 {
+		return nil
+	}
+	h := authHash(proto)()
+	h.Write(key)
+	h.Write([]byte(engine_id))
+	h.Write(key)
+	return h.Sum(nil)
+}
+
+// passwordToKey generates a USM key (Ku) from a password, using the
+// algorithm in RFC 3414 A.2. The key is then localized to an agent's engine
+// ID by localizeKey.
+//
+// The privacy key is generated with the authentication protocol's hash
+// function (RFC 3414 and RFC 3826), hence proto is always an AuthProtocol.
+func passwordToKey(proto AuthProtocol, password string) []byte {
+	new_hash := authHash(proto)
+	if new_hash == nil || password == "" {
+		return nil
+	}
+	h := new_hash()
+
+	// hash 1MB of the password repeated
+	const megabyte = 1048576
+	buf := make([]byte, 64)
+	pwlen := len(password)
+	for count, index := 0, 0; count < megabyte; count += 64 {
+		for i := range buf {
+			buf[i] = password[index%pwlen]
+			index++
+		}
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+// privCipher returns the block cipher and IV for a message with salt, from
+// the privacy key: DES uses the first 8 bytes of the key, and the next 8 as
+// the pre-IV; AES-128 uses the first 16 bytes, and an IV of boots, time and
+// salt.
+func (k *usmKeys) privCipher(salt []byte, boots, time int) (block cipher.Block, iv []byte, err error) {
+	if len(k.privKey) < 16 {
+		return nil, nil, fmt.Errorf("%s: privCipher(): no privacy key", libname())
+	}
+	switch k.priv {
+	case USM_PRIV_DES:
+		if block, err = des.NewCipher(k.privKey[:8]); err != nil {
+			return nil, nil, err
+		}
+		iv = make([]byte, des.BlockSize)
+		for i := range iv {
+			iv[i] = k.privKey[8+i] ^ salt[i]
+		}
+		return block, iv, nil
+	case USM_PRIV_AES:
+		if block, err = aes.NewCipher(k.privKey[:16]); err != nil {
+			return nil, nil, err
+		}
+		iv = make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint32(iv, uint32(boots))
+		binary.BigEndian.PutUint32(iv[4:], uint32(time))
+		copy(iv[8:], salt)
+		return block, iv, nil
+	}
+	return nil, nil, fmt.Errorf("%s: privCipher(): unknown privacy protocol %s", libname(), k.priv)
+}
+
+// sign writes the digest of packet, whose msgAuthenticationParameters at
+// offset are zero, into them.
+func (k *usmKeys) sign(packet []byte, offset int) {
+	copy(packet[offset:], k.digest(packet))
+}
+
+// verify returns true if the digest at offset in packet is correct.
+func (k *usmKeys) verify(packet []byte, offset int) bool {
+	if offset < 0 || offset+usmDigestLength > len(packet) {
+		return false
+	}
+	zeroed := make([]byte, len(packet))
+	copy(zeroed, packet)
+	copy(zeroed[offset:offset+usmDigestLength], make([]byte, usmDigestLength))
+	return hmac.Equal(k.digest(zeroed), packet[offset:offset+usmDigestLength])
+}
@@ -0,0 +1,138 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"fmt"
+	"testing"
+)
+
+// test vectors from RFC 3414 A.3.1 and A.3.2
+var passwordToKeyTests = []struct {
+	proto    AuthProtocol
+	password string
+	key      string
+}{
+	{USM_AUTH_MD5, "maplesyrup", "9faf3283884e92834ebc9847d8edd963"},
+	{USM_AUTH_SHA, "maplesyrup", "9fb5cc0381497b3793528939ff788d5d79145211"},
+	{USM_AUTH_NONE, "maplesyrup", ""},
+	{USM_AUTH_MD5, "", ""},
+}
+
+func TestPasswordToKey(t *testing.T) {
+	for i, test := range passwordToKeyTests {
+		key := fmt.Sprintf("%x", passwordToKey(test.proto, test.password))
+		if key != test.key {
+			t.Errorf("#%d: %s: expected (%s) got (%s)", i, test.proto, test.key, key)
+		}
+	}
+}
+
+var usmValidateTests = []struct {
+	usm *UsmParams
+	ok  bool
+}{
+	{nil, false},
+	{&UsmParams{SecLevel: GNET_SNMP_SECLEVEL_NANP}, false}, // no user
+	{&UsmParams{UserName: "noc", SecLevel: GNET_SNMP_SECLEVEL_NANP}, true},
+	{&UsmParams{UserName: "noc", SecLevel: GNET_SNMP_SECLEVEL_ANP}, false},
+	{&UsmParams{UserName: "noc", SecLevel: GNET_SNMP_SECLEVEL_ANP,
+		AuthProtocol: USM_AUTH_SHA, AuthPassword: "short"}, false},
+	{&UsmParams{UserName: "noc", SecLevel: GNET_SNMP_SECLEVEL_ANP,
+		AuthProtocol: USM_AUTH_SHA, AuthPassword: "maplesyrup"}, true},
+	{&UsmParams{UserName: "noc", SecLevel: GNET_SNMP_SECLEVEL_AP,
+		AuthProtocol: USM_AUTH_SHA, AuthPassword: "maplesyrup"}, false},
+	{&UsmParams{UserName: "noc", SecLevel: GNET_SNMP_SECLEVEL_AP,
+		AuthProtocol: USM_AUTH_MD5, AuthPassword: "maplesyrup",
+		PrivProtocol: USM_PRIV_AES, PrivPassword: "maplesyrup"}, true},
+	{&UsmParams{UserName: "noc", SecLevel: SecLevel(7)}, false},
+}
+
+func TestUsmValidate(t *testing.T) {
+	for i, test := range usmValidateTests {
+		if err := test.usm.validate(); (err == nil) != test.ok {
+			t.Errorf("#%d: expected ok (%t) got (%v)", i, test.ok, err)
+		}
+	}
+}
+
+// test vectors from RFC 3414 A.3.1 and A.3.2
+func TestLocalizeKey(t *testing.T) {
+	engine_id := "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02"
+	for i, test := range []struct {
+		proto AuthProtocol
+		key   string
+	}{
+		{USM_AUTH_MD5, "526f5eed9fcce26f8964c2930787d82b"},
+		{USM_AUTH_SHA, "6695febc9288e36282235fc7151f128497b38f3f"},
+	} {
+		key := fmt.Sprintf("%x", localizeKey(test.proto, passwordToKey(test.proto, "maplesyrup"), engine_id))
+		if key != test.key {
+			t.Errorf("#%d: %s: expected (%s) got (%s)", i, test.proto, test.key, key)
+		}
+	}
+}
+
+func TestUsmMessage(t *testing.T) {
+	for i, usm := range v3TestUsers {
+		keys := newUsmKeys(&usm, agentEngineID)
+		msg := &snmpMessage{version: GNET_SNMP_V3, pduType: pduGet, requestID: 7, msgID: 7,
+			flags: keys.flags() | v3Reportable, engineID: agentEngineID, engineBoots: 1, engineTime: 42,
+			userName: usm.UserName, contextName: "vlan2", keys: keys,
+			varbinds: []QueryResult{{Oid: MustParseOID("1.3.6.1.2.1.1.1.0"), Value: new(VBT_Null)}}}
+		packet, err := encodeMessage(msg)
+		if err != nil {
+			t.Errorf("#%d: encodeMessage error: %s", i, err)
+			continue
+		}
+		decoded, err := decodeMessage(packet)
+		if err == nil {
+			err = openMessage(decoded, keys)
+		}
+		if err != nil {
+			t.Errorf("#%d: decode error: %s", i, err)
+			continue
+		}
+		if decoded.pduType != pduGet || decoded.requestID != 7 || decoded.contextName != "vlan2" ||
+			decoded.userName != usm.UserName || len(decoded.varbinds) != 1 {
+			t.Errorf("#%d: message changed: %+v", i, decoded)
+		}
+
+		// any change to an authenticated message is detected
+		if keys.flags()&v3Auth == 0 {
+			continue
+		}
+		packet[len(packet)-1] ^= 1
+		if decoded, err = decodeMessage(packet); err == nil {
+			err = openMessage(decoded, keys)
+		}
+		if err == nil {
+			t.Errorf("#%d: expected an error for a changed message", i)
+		}
+	}
+}
+
+// v3TestUsers are at each security level, with each protocol
+var v3TestUsers = []UsmParams{
+	{UserName: "nanp", SecLevel: GNET_SNMP_SECLEVEL_NANP},
+	{UserName: "sha", SecLevel: GNET_SNMP_SECLEVEL_ANP, AuthProtocol: USM_AUTH_SHA, AuthPassword: "maplesyrup"},
+	{UserName: "des", SecLevel: GNET_SNMP_SECLEVEL_AP, AuthProtocol: USM_AUTH_MD5, AuthPassword: "maplesyrup",
+		PrivProtocol: USM_PRIV_DES, PrivPassword: "syrupmaple"},
+	{UserName: "aes", SecLevel: GNET_SNMP_SECLEVEL_AP, AuthProtocol: USM_AUTH_SHA, AuthPassword: "maplesyrup",
+		PrivProtocol: USM_PRIV_AES, PrivPassword: "syrupmaple"},
+}
@@ -0,0 +1,139 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// walk.go walks oids with GETBULK or GETNEXT requests. The walk is done in
+// Go by both backends, which each supply a function doing a single request.
+
+import (
+	"code.google.com/p/tcgl/applog"
+	"context"
+)
+
+// walkOids walks each of roots, for both backends. fetch does a single
+// GETBULK (or GETNEXT, if bulk is false) of request, and returns the
+// response varbinds.
+//
+// The first nonrep oids in roots are non-repeaters and are only retrieved
+// once. The remaining oids are columns that are walked maxrep rows at a
+// time (one row at a time for GETNEXT), until each column leaves the subtree
+// of its starting oid or the agent returns an exception (eg endOfMibView).
+// Results are returned in the order they were received.
+//
+// The walk stops at the first error, and between requests if ctx is done.
+func walkOids(ctx context.Context, roots [][]uint32, bulk bool, nonrep, maxrep int,
+	fetch func(request [][]uint32, nonrep, maxrep int) ([]QueryResult, *QueryStatus, error)) (
+	out []QueryResult, status *QueryStatus, err error) {
+	if nonrep < 0 {
+		nonrep = 0
+	} else if nonrep > len(roots) {
+		nonrep = len(roots)
+	}
+	if maxrep < 1 || !bulk {
+		maxrep = 1
+	}
+	if !bulk {
+		nonrep = 0
+	}
+
+	// the last oid retrieved for each column, and the columns still walking
+	cursors := make([][]uint32, len(roots))
+	var active []int
+	for i := nonrep; i < len(roots); i++ {
+		cursors[i] = roots[i]
+		active = append(active, i)
+	}
+
+	request := roots
+	for round := 0; ; round++ {
+		round_nonrep := 0
+		if round == 0 {
+			round_nonrep = nonrep
+		}
+		if ctx.Err() != nil {
+			return out, status, ctx.Err()
+		}
+		var response []QueryResult
+		response, status, err = fetch(request, round_nonrep, maxrep)
+		if err != nil {
+			if Debug {
+				applog.Warningf("walkOids(): %s", err)
+			}
+			return out, status, err
+		}
+
+		var finished = make(map[int]bool)
+		var progress bool
+		for position, varbind := range response {
+			oid := varbind.Oid
+			exception := isException(varbind.Value)
+
+			var keep bool
+			if position < round_nonrep {
+				// a non-repeater - only wanted if it's inside its subtree
+				keep = !exception && oidInSubtree(roots[position], oid)
+			} else if len(active) > 0 {
+				column := active[(position-round_nonrep)%len(active)]
+				if !finished[column] {
+					// a column is finished when it leaves its subtree, or the
+					// agent stops returning increasing oids (a broken agent)
+					if exception || !oidInSubtree(roots[column], oid) ||
+						oidCompare(oid, cursors[column]) <= 0 {
+						finished[column] = true
+					} else {
+						keep = true
+						cursors[column] = oid
+						progress = true
+					}
+				}
+			}
+			if keep {
+				out = append(out, varbind)
+			}
+		}
+
+		var still_active []int
+		for _, column := range active {
+			if !finished[column] {
+				still_active = append(still_active, column)
+			}
+		}
+		active = still_active
+		if len(active) == 0 || !progress {
+			return out, status, nil
+		}
+
+		// next request continues each active column from its last oid
+		request = nil
+		for _, column := range active {
+			request = append(request, cursors[column])
+		}
+	}
+}
+
+// ------------------- other functions in alphabetical order --------------------
+
+// isException returns true if value is one of the v2c exceptions
+// noSuchObject, noSuchInstance or endOfMibView.
+func isException(value Varbinder) bool {
+	switch value.(type) {
+	case *VBT_NoSuchObject, *VBT_NoSuchInstance, *VBT_EndOfMibView:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package gsnmpgo
+
+// gsnmpgo is a go/cgo wrapper around gsnmp.
+//
+// Copyright (C) 2012-2013 Sonia Hamilton sonia@snowfrog.net.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// walkMib is a small agent mib for testing walkOids
+var walkMib = []QueryResult{
+	{Oid: MustParseOID("1.3.6.1.2.1.1.1.0"), Value: VBT_OctetString("Linux")},
+	{Oid: MustParseOID("1.3.6.1.2.1.1.3.0"), Value: VBT_Timeticks(4381200)},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.1.0"), Value: VBT_Integer32(3)},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.1.1"), Value: VBT_Integer32(1)},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.1.2"), Value: VBT_Integer32(2)},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.1.3"), Value: VBT_Integer32(3)},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.2.1"), Value: VBT_OctetString("lo")},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.2.2"), Value: VBT_OctetString("eth0")},
+	{Oid: MustParseOID("1.3.6.1.2.1.2.2.1.2.3"), Value: VBT_OctetString("eth1")},
+}
+
+// walkMibNext returns the varbind following oid in walkMib, or endOfMibView.
+func walkMibNext(oid OID) QueryResult {
+	for _, varbind := range walkMib {
+		if varbind.Oid.Compare(oid) > 0 {
+			return varbind
+		}
+	}
+	return QueryResult{Oid: oid, Value: new(VBT_EndOfMibView)}
+}
+
+// walkMibFetch answers GETBULK (and GETNEXT, with maxrep 1) requests from
+// walkMib, counting the requests made.
+func walkMibFetch(requests *int) func([][]uint32, int, int) ([]QueryResult, *QueryStatus, error) {
+	return func(request [][]uint32, nonrep, maxrep int) ([]QueryResult, *QueryStatus, error) {
+		*requests++
+		var response []QueryResult
+		for _, oid := range request[:nonrep] {
+			response = append(response, walkMibNext(oid))
+		}
+		cursors := append([][]uint32(nil), request[nonrep:]...)
+		for row := 0; row < maxrep; row++ {
+			for i, oid := range cursors {
+				next := walkMibNext(oid)
+				response = append(response, next)
+				cursors[i] = next.Oid
+			}
+		}
+		return response, &QueryStatus{}, nil
+	}
+}
+
+var walkOidsTests = []struct {
+	roots    []string
+	bulk     bool
+	nonrep   int
+	maxrep   int
+	expected []string
+	requests int
+}{
+	// GETNEXT walk of the system group
+	{[]string{"1.3.6.1.2.1.1"}, false, 0, 10,
+		[]string{"1.3.6.1.2.1.1.1.0", "1.3.6.1.2.1.1.3.0"}, 3},
+	// GETBULK walk of two columns
+	{[]string{"1.3.6.1.2.1.2.2.1.1", "1.3.6.1.2.1.2.2.1.2"}, true, 0, 2,
+		[]string{"1.3.6.1.2.1.2.2.1.1.1", "1.3.6.1.2.1.2.2.1.2.1", "1.3.6.1.2.1.2.2.1.1.2",
+			"1.3.6.1.2.1.2.2.1.2.2", "1.3.6.1.2.1.2.2.1.1.3", "1.3.6.1.2.1.2.2.1.2.3"}, 2},
+	// a non-repeater, and a column walked to endOfMibView
+	{[]string{"1.3.6.1.2.1.2.1", "1.3.6.1.2.1.2.2.1.2"}, true, 1, 10,
+		[]string{"1.3.6.1.2.1.2.1.0", "1.3.6.1.2.1.2.2.1.2.1", "1.3.6.1.2.1.2.2.1.2.2",
+			"1.3.6.1.2.1.2.2.1.2.3"}, 1},
+	// nothing in the subtree
+	{[]string{"1.3.6.1.2.1.99"}, true, 0, 10, nil, 1},
+}
+
+func TestWalkOids(t *testing.T) {
+	for i, test := range walkOidsTests {
+		var roots [][]uint32
+		for _, root := range test.roots {
+			oid, _ := parseOid(root)
+			roots = append(roots, oid)
+		}
+		var requests int
+		out, status, err := walkOids(context.Background(), roots, test.bulk, test.nonrep, test.maxrep,
+			walkMibFetch(&requests))
+		if err != nil || status == nil || !status.Ok() {
+			t.Errorf("#%d: walkOids error: %v, status %+v", i, err, status)
+			continue
+		}
+		var oids []string
+		for _, varbind := range out {
+			oids = append(oids, varbind.Oid.String())
+		}
+		if !reflect.DeepEqual(oids, test.expected) {
+			t.Errorf("#%d: walkOids expected %v got %v", i, test.expected, oids)
+		}
+		if requests != test.requests {
+			t.Errorf("#%d: expected %d requests got %d", i, test.requests, requests)
+		}
+	}
+
+	// a broken agent that returns the same oid forever
+	var requests int
+	loop := func(request [][]uint32, nonrep, maxrep int) ([]QueryResult, *QueryStatus, error) {
+		requests++
+		return []QueryResult{{Oid: MustParseOID("1.3.6.1.2.1.1.1.0"), Value: VBT_OctetString("Linux")}}, &QueryStatus{}, nil
+	}
+	root, _ := parseOid("1.3.6.1.2.1.1")
+	out, _, _ := walkOids(context.Background(), [][]uint32{root}, false, 0, 1, loop)
+	if len(out) != 1 || requests != 2 {
+		t.Errorf("expected loop to be detected after 2 requests, got %d results %d requests", len(out), requests)
+	}
+}